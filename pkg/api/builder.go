@@ -69,6 +69,11 @@ type BuildOutput struct {
 	// containing the collapsed transitive upstream dependency set of this
 	// build.
 	Dependencies map[string]string
+
+	// Provenance records how this artifact was produced. Builders may
+	// pre-populate the fields only they know about (e.g. base images, SBOM);
+	// the engine completes the rest.
+	Provenance *BuildProvenance
 }
 
 // DependencyTarget encapsulates the target and version of a dependency.
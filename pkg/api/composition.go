@@ -6,6 +6,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/imdario/mergo"
@@ -85,6 +86,10 @@ type Metadata struct {
 type Resources struct {
 	Memory string `toml:"memory" json:"memory"`
 	CPU    string `toml:"cpu" json:"cpu"`
+
+	// CPUSet pins instances to a set of CPUs, in the Linux cpuset list
+	// format (e.g. "0-3,6"). Only honoured by the local runners.
+	CPUSet string `toml:"cpuset" json:"cpuset,omitempty"`
 }
 
 type Group struct {
@@ -109,6 +114,11 @@ type Group struct {
 	// Run specifies the run configuration for this group.
 	Run RunParams `toml:"run" json:"run"`
 
+	// Coordinates records the cell of the build matrix this group was
+	// expanded from, as a map of module to version. It is set by
+	// ExpandMatrix, and empty for groups that were not expanded.
+	Coordinates map[string]string `toml:"coordinates,omitempty" json:"coordinates,omitempty"`
+
 	// calculatedInstanceCnt caches the actual number of instances in this
 	// group.
 	calculatedInstanceCnt uint
@@ -128,6 +138,25 @@ type Run struct {
 
 	// Instances defines the number of instances that belong to this group.
 	Groups CompositionRunGroups `toml:"groups" json:"groups" validate:"required,gt=0"`
+
+	// Sweep specifies sets of test parameter values to run against. A run
+	// with a sweep is expanded into one run per combination of values (see
+	// ExpandSweeps).
+	Sweep []*SweepDimension `toml:"sweep,omitempty" json:"sweep,omitempty"`
+
+	// Coordinates records the point of the sweep this run was expanded from,
+	// as a map of test parameter to value. It is set by ExpandSweeps, and
+	// empty for runs that were not expanded.
+	Coordinates map[string]string `toml:"coordinates,omitempty" json:"coordinates,omitempty"`
+}
+
+// SweepDimension enumerates the values a test parameter takes across a sweep.
+type SweepDimension struct {
+	// Param is the name of the test parameter.
+	Param string `toml:"param" json:"param"`
+
+	// Values are the values of the test parameter to run against.
+	Values []string `toml:"values" json:"values"`
 }
 
 type CompositionRunGroups []*CompositionRunGroup
@@ -161,6 +190,9 @@ type CompositionRunGroup struct {
 	// CPU profile for the entire duration of the test.
 	Profiles map[string]string `toml:"profiles" json:"profiles"`
 
+	// Start paces the start of the instances of this group.
+	Start *StartPolicy `toml:"start,omitempty" json:"start,omitempty"`
+
 	// calculatedInstanceCnt caches the actual number of instances in this
 	// group.
 	calculatedInstanceCnt uint
@@ -189,6 +221,20 @@ type Build struct {
 	// Dependencies specifies any upstream dependency overrides to apply to this
 	// build.
 	Dependencies Dependencies `toml:"dependencies" json:"dependencies"`
+
+	// Matrix specifies sets of dependency versions to build against. A group
+	// with a matrix is expanded into one group per combination of versions
+	// (see ExpandMatrix).
+	Matrix *BuildMatrix `toml:"matrix,omitempty" json:"matrix,omitempty"`
+}
+
+// BuildMatrix enumerates the values each dimension of a build matrix can take.
+type BuildMatrix struct {
+	// Dependencies maps module paths to the list of versions to build against.
+	// Values can be a version (e.g. v0.20.0), or a target and a version (e.g.
+	// github.com/user/fork@v0.20.1), in which case the module is replaced by
+	// the target.
+	Dependencies map[string][]string `toml:"dependencies" json:"dependencies"`
 }
 
 // BuildKey returns a composite key that identifies this build, suitable for
@@ -297,6 +343,38 @@ type RunParams struct {
 	// profile kind "cpu" is supported; it takes no frequency and it starts a
 	// CPU profile for the entire duration of the test.
 	Profiles map[string]string `toml:"profiles" json:"profiles"`
+
+	// Start paces the start of the instances of this group.
+	Start *StartPolicy `toml:"start,omitempty" json:"start,omitempty"`
+}
+
+// StartPolicy paces the start of the instances of a group, for example to
+// model nodes joining a network over time, or to hold a group back until
+// another one has bootstrapped. Runners start instances as fast as possible
+// when there's no policy.
+type StartPolicy struct {
+	// Delay postpones the start of the group, in time.Duration string
+	// representation (e.g. 30s). It counts from the moment the group may
+	// start: the start of the run, or the signal it waits for.
+	Delay string `toml:"delay" json:"delay"`
+
+	// Rate caps the number of instances of the group started per second.
+	// Zero means no cap.
+	Rate float64 `toml:"rate" json:"rate"`
+
+	// AfterGroup and AfterState hold the start of the group until the state
+	// AfterState has been signalled as many times as AfterGroup has
+	// instances, i.e. by all of its instances, assuming only they signal it.
+	AfterGroup string `toml:"after_group" json:"after_group" mapstructure:"after_group"`
+	AfterState string `toml:"after_state" json:"after_state" mapstructure:"after_state"`
+}
+
+// DelayDuration returns the parsed Delay, or zero if there's none.
+func (p StartPolicy) DelayDuration() (time.Duration, error) {
+	if p.Delay == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(p.Delay)
 }
 
 type Dependency struct {
@@ -466,6 +544,7 @@ func (g *Group) DefaultRunGroup() (*CompositionRunGroup) {
 		Instances:  g.Instances,
 		TestParams: g.Run.TestParams,
 		Profiles:   g.Run.Profiles,
+		Start:      g.Run.Start,
 	}
 }
 
@@ -475,9 +554,10 @@ func (r *CompositionRunGroup) merge(other *Group) (error) {
 		return err
 	}
 
-	err = mergo.Merge(&r.Instances, other.Instances)
-	if err != nil {
-		return err
+	// counts and percentages are mutually exclusive, so instances are
+	// inherited as a whole.
+	if r.Instances == (Instances{}) {
+		r.Instances = other.Instances
 	}
 
 	err = r.mergeRun(&other.Run)
@@ -499,5 +579,18 @@ func (r *CompositionRunGroup) mergeRun(other *RunParams) (error) {
 		return err
 	}
 
+	// policies are copied, as they may be shared with other groups.
+	if other.Start != nil {
+		var start StartPolicy
+		if r.Start != nil {
+			start = *r.Start
+		}
+		err = mergo.Merge(&start, *other.Start)
+		if err != nil {
+			return err
+		}
+		r.Start = &start
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// unsafeIDChars matches the characters that are not allowed in generated
+// group ids.
+var unsafeIDChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// matrixCell is a single combination of values of a build matrix.
+type matrixCell struct {
+	// coordinates maps module paths to the raw matrix values.
+	coordinates map[string]string
+
+	// suffix is appended to the ids of the group and run groups expanded for
+	// this cell.
+	suffix string
+}
+
+// cells returns the cartesian product of the matrix dimensions, in a stable
+// order.
+func (m *BuildMatrix) cells() ([]matrixCell, error) {
+	modules := make([]string, 0, len(m.Dependencies))
+	for mod, versions := range m.Dependencies {
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("matrix dimension %s has no values", mod)
+		}
+		modules = append(modules, mod)
+	}
+	sort.Strings(modules)
+
+	cells := []matrixCell{{coordinates: map[string]string{}}}
+	for _, mod := range modules {
+		next := make([]matrixCell, 0, len(cells)*len(m.Dependencies[mod]))
+		for _, c := range cells {
+			for _, v := range m.Dependencies[mod] {
+				coords := make(map[string]string, len(c.coordinates)+1)
+				for k, v := range c.coordinates {
+					coords[k] = v
+				}
+				coords[mod] = v
+
+				next = append(next, matrixCell{
+					coordinates: coords,
+					suffix:      c.suffix + "-" + unsafeIDChars.ReplaceAllString(v, "_"),
+				})
+			}
+		}
+		cells = next
+	}
+	return cells, nil
+}
+
+// matrixDependency converts a matrix coordinate into a dependency override.
+// Values are either a version, or a target@version pair.
+func matrixDependency(module, value string) (Dependency, error) {
+	dep := Dependency{Module: module, Version: value}
+	if i := strings.LastIndex(value, "@"); i >= 0 {
+		dep.Target, dep.Version = value[:i], value[i+1:]
+	}
+	if dep.Version == "" {
+		return Dependency{}, fmt.Errorf("invalid matrix value for %s: %q", module, value)
+	}
+	return dep, nil
+}
+
+// ExpandMatrix expands every group with a build matrix into one group per
+// combination of matrix values. Groups without a matrix of their own inherit
+// the global build matrix, if any.
+//
+// Expanded groups receive the id of the original group, suffixed with the
+// matrix values (e.g. libp2p-v0.20.0), and record the values they were
+// expanded from in Coordinates. Run groups referencing an expanded group are
+// expanded alongside it: instance counts apply to each cell, whereas instance
+// percentages are split evenly across cells.
+//
+// This method doesn't modify the composition, it returns a new one. It is
+// idempotent, as expanded groups carry no matrix.
+func (c Composition) ExpandMatrix() (*Composition, error) {
+	var global *BuildMatrix
+	if c.Global.Build != nil && c.Global.Build.Matrix != nil {
+		global = c.Global.Build.Matrix
+
+		build := *c.Global.Build
+		build.Matrix = nil
+		c.Global.Build = &build
+	}
+
+	var (
+		groups    = make(Groups, 0, len(c.Groups))
+		expanded  = make(map[string][]matrixCell)
+		instances = make(map[string]Instances)
+	)
+
+	for _, g := range c.Groups {
+		matrix := g.Build.Matrix
+		if matrix == nil {
+			matrix = global
+		}
+		if matrix == nil || len(matrix.Dependencies) == 0 {
+			if g.Build.Matrix != nil {
+				ng := *g
+				ng.Build.Matrix = nil
+				g = &ng
+			}
+			groups = append(groups, g)
+			continue
+		}
+
+		cells, err := matrix.cells()
+		if err != nil {
+			return nil, fmt.Errorf("invalid matrix in group %s: %w", g.ID, err)
+		}
+
+		for _, cell := range cells {
+			ng := *g
+			ng.ID = g.ID + cell.suffix
+			ng.Build.Matrix = nil
+			ng.Coordinates = cell.coordinates
+
+			// matrix values take precedence over the group dependencies.
+			overrides := make(Dependencies, 0, len(cell.coordinates))
+			for mod, v := range cell.coordinates {
+				dep, err := matrixDependency(mod, v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid matrix in group %s: %w", g.ID, err)
+				}
+				overrides = append(overrides, dep)
+			}
+			sort.Slice(overrides, func(i, j int) bool {
+				return overrides[i].Module < overrides[j].Module
+			})
+			ng.Build.Dependencies = overrides.ApplyDefaults(g.Build.Dependencies)
+
+			if ng.BuildConfig != nil {
+				ng.BuildConfig = make(map[string]interface{}, len(g.BuildConfig))
+				for k, v := range g.BuildConfig {
+					ng.BuildConfig[k] = v
+				}
+			}
+
+			ng.Instances = splitInstances(g.Instances, len(cells))
+			groups = append(groups, &ng)
+		}
+
+		expanded[g.ID] = cells
+		instances[g.ID] = g.Instances
+	}
+
+	c.Groups = groups
+
+	if len(expanded) == 0 {
+		return &c, nil
+	}
+
+	runs := make(Runs, 0, len(c.Runs))
+	for _, r := range c.Runs {
+		nr := *r
+		nr.Groups = make(CompositionRunGroups, 0, len(r.Groups))
+
+		for _, rg := range r.Groups {
+			cells, ok := expanded[rg.EffectiveGroupId()]
+			if !ok {
+				nr.Groups = append(nr.Groups, rg)
+				continue
+			}
+
+			// Run groups may inherit their instances from the group.
+			in := rg.Instances
+			if in.Count == 0 && in.Percentage == 0 {
+				in = instances[rg.EffectiveGroupId()]
+			}
+
+			split, err := cellInstances(in, r.TotalInstances, len(cells))
+			if err != nil {
+				return nil, fmt.Errorf("invalid matrix in run %s:%s: %w", r.ID, rg.ID, err)
+			}
+			for i, cell := range cells {
+				nrg := *rg
+				nrg.ID = rg.ID + cell.suffix
+				nrg.GroupID = rg.EffectiveGroupId() + cell.suffix
+				nrg.Instances = split[i]
+				nr.Groups = append(nr.Groups, &nrg)
+			}
+
+			// counts apply to each cell, so a configured total grows
+			// accordingly.
+			if nr.TotalInstances != 0 {
+				nr.TotalInstances += in.Count * uint(len(cells)-1)
+			}
+		}
+		runs = append(runs, &nr)
+	}
+	c.Runs = runs
+
+	return &c, nil
+}
+
+// cellInstances returns the instances of each of the n cells of a run group
+// of a run of total instances. A percentage is resolved into a count of the
+// whole group, which is spread across cells, so that cells always add up to
+// the instances of the group; the first cells take the remainder.
+func cellInstances(in Instances, total uint, n int) ([]Instances, error) {
+	res := make([]Instances, n)
+	if in.Percentage == 0 || total == 0 {
+		for i := range res {
+			res[i] = splitInstances(in, n)
+		}
+		return res, nil
+	}
+
+	count := uint(math.Round(in.Percentage * float64(total)))
+	if count < uint(n) {
+		return nil, fmt.Errorf("%d instances can't be spread across %d matrix cells", count, n)
+	}
+	for i := range res {
+		res[i].Count = count / uint(n)
+		if uint(i) < count%uint(n) {
+			res[i].Count++
+		}
+	}
+	return res, nil
+}
+
+// splitInstances returns the instances of a single cell out of n cells.
+func splitInstances(in Instances, n int) Instances {
+	if in.Percentage > 0 {
+		in.Percentage /= float64(n)
+	}
+	return in
+}
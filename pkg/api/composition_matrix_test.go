@@ -0,0 +1,179 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/config"
+)
+
+const matrixComposition = `
+[global]
+plan = "foo_plan"
+case = "foo_case"
+builder = "docker:go"
+runner = "local:docker"
+
+[[groups]]
+id = "libp2p"
+instances = { count = 2 }
+
+  [[groups.build.dependencies]]
+  module = "github.com/ipfs/go-cid"
+  version = "v0.1.0"
+
+  [groups.build.matrix.dependencies]
+  "github.com/libp2p/go-libp2p" = ["v0.20.0", "github.com/fork/go-libp2p@v0.21.0"]
+
+[[groups]]
+id = "static"
+instances = { count = 1 }
+
+[[runs]]
+id = "interop"
+total_instances = 3
+
+  [[runs.groups]]
+  id = "libp2p"
+
+  [[runs.groups]]
+  id = "static"
+`
+
+func TestExpandMatrix(t *testing.T) {
+	var c Composition
+	_, err := toml.Decode(matrixComposition, &c)
+	require.NoError(t, err)
+
+	expanded, err := c.ExpandMatrix()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"libp2p-github.com_fork_go-libp2p_v0.21.0", "libp2p-v0.20.0", "static"}, expanded.ListGroupsIds())
+
+	g, err := expanded.GetGroup("libp2p-v0.20.0")
+	require.NoError(t, err)
+	require.Nil(t, g.Build.Matrix)
+	require.Equal(t, map[string]string{"github.com/libp2p/go-libp2p": "v0.20.0"}, g.Coordinates)
+	require.ElementsMatch(t, Dependencies{
+		{Module: "github.com/libp2p/go-libp2p", Version: "v0.20.0"},
+		{Module: "github.com/ipfs/go-cid", Version: "v0.1.0"},
+	}, g.Build.Dependencies)
+
+	g, err = expanded.GetGroup("libp2p-github.com_fork_go-libp2p_v0.21.0")
+	require.NoError(t, err)
+	require.Contains(t, g.Build.Dependencies, Dependency{Module: "github.com/libp2p/go-libp2p", Target: "github.com/fork/go-libp2p", Version: "v0.21.0"})
+
+	// the original composition is left untouched.
+	require.Len(t, c.Groups, 2)
+	require.NotNil(t, c.Groups[0].Build.Matrix)
+
+	// run groups are expanded alongside, and the total grows accordingly.
+	run := expanded.Runs[0]
+	require.Len(t, run.Groups, 3)
+	require.Equal(t, "libp2p-v0.20.0", run.Groups[0].EffectiveGroupId())
+	require.EqualValues(t, 5, run.TotalInstances)
+	require.Equal(t, "libp2p-v0.20.0", run.Groups[0].ID)
+
+	// expansion is idempotent.
+	again, err := expanded.ExpandMatrix()
+	require.NoError(t, err)
+	require.Equal(t, expanded.ListGroupsIds(), again.ListGroupsIds())
+}
+
+func TestExpandMatrixSplitsPercentages(t *testing.T) {
+	c := Composition{
+		Global: Global{
+			Build: &Build{
+				Matrix: &BuildMatrix{Dependencies: map[string][]string{
+					"github.com/libp2p/go-libp2p": {"v0.20.0", "v0.21.0"},
+				}},
+			},
+		},
+		Groups: Groups{
+			{ID: "a", Instances: Instances{Percentage: 0.5}},
+		},
+	}
+
+	expanded, err := c.ExpandMatrix()
+	require.NoError(t, err)
+	require.Nil(t, expanded.Global.Build.Matrix)
+	require.Len(t, expanded.Groups, 2)
+	for _, g := range expanded.Groups {
+		require.Equal(t, 0.25, g.Instances.Percentage)
+	}
+}
+
+func TestExpandMatrixRejectsEmptyDimension(t *testing.T) {
+	c := Composition{
+		Groups: Groups{{
+			ID: "a",
+			Build: Build{Matrix: &BuildMatrix{Dependencies: map[string][]string{
+				"github.com/libp2p/go-libp2p": {},
+			}}},
+		}},
+	}
+
+	_, err := c.ExpandMatrix()
+	require.Error(t, err)
+}
+
+func TestPrepareForRunSpreadsPercentagesAcrossCells(t *testing.T) {
+	c := Composition{
+		Global: Global{
+			Plan:    "foo_plan",
+			Case:    "foo_case",
+			Builder: "docker:go",
+			Runner:  "local:docker",
+			Build: &Build{
+				Matrix: &BuildMatrix{Dependencies: map[string][]string{
+					"github.com/libp2p/go-libp2p": {"v0.19.0", "v0.20.0", "v0.21.0"},
+				}},
+			},
+		},
+		Groups: Groups{
+			{ID: "peers", Instances: Instances{Percentage: 1.0}},
+		},
+		Runs: Runs{{
+			ID:             "interop",
+			TotalInstances: 10,
+			Groups:         CompositionRunGroups{{ID: "peers"}},
+		}},
+	}
+
+	manifest := &TestPlanManifest{
+		Name: "foo_plan",
+		Builders: map[string]config.ConfigMap{
+			"docker:go": {},
+		},
+		Runners: map[string]config.ConfigMap{
+			"local:docker": {},
+		},
+		TestCases: []*TestCase{
+			{
+				Name:      "foo_case",
+				Instances: InstanceConstraints{Minimum: 1, Maximum: 100},
+			},
+		},
+	}
+
+	ret, err := c.PrepareForRun(manifest)
+	require.NoError(t, err)
+	require.NoError(t, ret.ValidateForRun())
+
+	run := ret.Runs[0]
+	require.EqualValues(t, 10, run.TotalInstances)
+	require.Len(t, run.Groups, 3)
+
+	var counts []uint
+	for _, g := range run.Groups {
+		counts = append(counts, g.CalculatedInstanceCount())
+	}
+	require.Equal(t, []uint{4, 3, 3}, counts)
+
+	// cells can't be left without instances.
+	c.Runs[0].TotalInstances = 2
+	_, err = c.PrepareForRun(manifest)
+	require.Error(t, err)
+}
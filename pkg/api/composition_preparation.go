@@ -56,11 +56,18 @@ func (g Group) PrepareForBuild(manifest *TestPlanManifest, c *Composition) (*Gro
 }
 
 // PrepareForBuild verifies that this composition is compatible with
-// the provided manifest for the purposes of a build, and applies any manifest-
-// mandated defaults for the builder configuration.
+// the provided manifest for the purposes of a build, expands any build
+// matrices, and applies any manifest-mandated defaults for the builder
+// configuration.
 //
 // This method doesn't modify the composition, it returns a new one.
 func (c Composition) PrepareForBuild(manifest *TestPlanManifest) (*Composition, error) {
+	expanded, err := c.ExpandMatrix()
+	if err != nil {
+		return nil, err
+	}
+	c = *expanded
+
 	// override the composition plan name with what's in the manifest
 	// rationale: composition.Global.Plan will be a path relative to
 	// $TESTGROUND_HOME/plans; the server doesn't care about our local
@@ -110,14 +117,28 @@ func (c Composition) GenerateDefaultRun() *Composition {
 }
 
 // PrepareForRun verifies that this composition is compatible with the
-// provided manifest for the purposes of a run, verifies the instance count is
-// within bounds, applies any manifest-mandated defaults for the runner
-// configuration, and applies default run parameters.
+// provided manifest for the purposes of a run, expands any parameter sweeps
+// and build matrices, verifies the instance count is within bounds, applies
+// any manifest-mandated defaults for the runner configuration, applies
+// default run parameters, and validates test parameters against the ones
+// declared by the manifest.
 //
 // This method doesn't modify the composition, it returns a new one.
 func (c Composition) PrepareForRun(manifest *TestPlanManifest) (*Composition, error) {
 	c = *c.GenerateDefaultRun()
 
+	swept, err := c.ExpandSweeps()
+	if err != nil {
+		return nil, err
+	}
+	c = *swept
+
+	expanded, err := c.ExpandMatrix()
+	if err != nil {
+		return nil, err
+	}
+	c = *expanded
+
 	// override the composition plan name with what's in the manifest
 	// rationale: composition.Global.Plan will be a path relative to
 	// $TESTGROUND_HOME/plans; the server doesn't care about our local
@@ -277,5 +298,11 @@ func (g CompositionRunGroup) PrepareForRun(manifest *TestPlanManifest, compositi
 		return nil, err
 	}
 
+	// Validate the test params against the ones declared by the test case.
+	_, tcase, _ := manifest.TestCaseByName(composition.Global.Case)
+	if err := tcase.validateParams(g.TestParams); err != nil {
+		return nil, fmt.Errorf("group %s: %w", g.ID, err)
+	}
+
 	return &g, nil
 }
@@ -66,6 +66,9 @@ func TestDefaultTestParamsApplied(t *testing.T) {
 				Name:      "foo_case",
 				Instances: InstanceConstraints{Minimum: 1, Maximum: 100},
 				Parameters: map[string]Parameter{
+					"param1": {Type: "string"},
+					"param2": {Type: "string"},
+					"param3": {Type: "string"},
 					"param4": {
 						Type:    "string",
 						Default: "value4:default:manifest",
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+)
+
+// sweepPoint is a single combination of values of a sweep.
+type sweepPoint struct {
+	// coordinates maps test parameters to their values.
+	coordinates map[string]string
+
+	// suffix is appended to the id of the run expanded for this point.
+	suffix string
+}
+
+// sweepPoints returns the cartesian product of the sweep dimensions, in the
+// order they were declared in.
+func sweepPoints(sweep []*SweepDimension) ([]sweepPoint, error) {
+	seen := make(map[string]bool, len(sweep))
+	points := []sweepPoint{{coordinates: map[string]string{}}}
+	for _, d := range sweep {
+		if d.Param == "" {
+			return nil, fmt.Errorf("sweep dimension has no param")
+		}
+		if seen[d.Param] {
+			return nil, fmt.Errorf("sweep dimension %s is declared more than once", d.Param)
+		}
+		if len(d.Values) == 0 {
+			return nil, fmt.Errorf("sweep dimension %s has no values", d.Param)
+		}
+		seen[d.Param] = true
+
+		next := make([]sweepPoint, 0, len(points)*len(d.Values))
+		for _, p := range points {
+			for _, v := range d.Values {
+				coords := make(map[string]string, len(p.coordinates)+1)
+				for k, v := range p.coordinates {
+					coords[k] = v
+				}
+				coords[d.Param] = v
+
+				next = append(next, sweepPoint{
+					coordinates: coords,
+					suffix:      p.suffix + "-" + unsafeIDChars.ReplaceAllString(v, "_"),
+				})
+			}
+		}
+		points = next
+	}
+	return points, nil
+}
+
+// ExpandSweeps expands every run with a sweep into one run per combination
+// of sweep values.
+//
+// Expanded runs receive the id of the original run, suffixed with the sweep
+// values in the order the dimensions were declared in (e.g. bench-1024-8),
+// and record the values they were expanded from in Coordinates. Sweep values
+// are set on every group of the run, and take precedence over any other
+// value of the test parameter.
+//
+// This method doesn't modify the composition, it returns a new one. It is
+// idempotent, as expanded runs carry no sweep.
+func (c Composition) ExpandSweeps() (*Composition, error) {
+	var (
+		runs  = make(Runs, 0, len(c.Runs))
+		swept bool
+	)
+	for _, r := range c.Runs {
+		if len(r.Sweep) == 0 {
+			runs = append(runs, r)
+			continue
+		}
+
+		points, err := sweepPoints(r.Sweep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sweep in run %s: %w", r.ID, err)
+		}
+		swept = true
+
+		for _, p := range points {
+			nr := *r
+			nr.ID = r.ID + p.suffix
+			nr.Sweep = nil
+			nr.Coordinates = p.coordinates
+
+			nr.Groups = make(CompositionRunGroups, 0, len(r.Groups))
+			for _, rg := range r.Groups {
+				nrg := *rg
+				nrg.TestParams = make(map[string]string, len(rg.TestParams)+len(p.coordinates))
+				for k, v := range rg.TestParams {
+					nrg.TestParams[k] = v
+				}
+				for k, v := range p.coordinates {
+					nrg.TestParams[k] = v
+				}
+				nr.Groups = append(nr.Groups, &nrg)
+			}
+			runs = append(runs, &nr)
+		}
+	}
+
+	if !swept {
+		return &c, nil
+	}
+
+	// values that only differ in unsafe characters yield the same id.
+	ids := make(map[string]bool, len(runs))
+	for _, r := range runs {
+		if ids[r.ID] {
+			return nil, fmt.Errorf("sweep expands into duplicate run id %s", r.ID)
+		}
+		ids[r.ID] = true
+	}
+
+	c.Runs = runs
+	return &c, nil
+}
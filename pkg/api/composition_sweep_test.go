@@ -0,0 +1,148 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/config"
+)
+
+const sweepComposition = `
+[global]
+plan = "foo_plan"
+case = "foo_case"
+builder = "docker:go"
+runner = "local:docker"
+
+[[groups]]
+id = "publishers"
+instances = { count = 1 }
+
+  [groups.run.test_params]
+  fanout = "2"
+
+[[groups]]
+id = "subscribers"
+instances = { count = 2 }
+
+[[runs]]
+id = "bench"
+
+  [runs.test_params]
+  rounds = "10"
+
+  [[runs.groups]]
+  id = "publishers"
+
+  [[runs.groups]]
+  id = "subscribers"
+
+  [[runs.sweep]]
+  param = "message_size"
+  values = ["1 KiB", "1 MiB"]
+
+  [[runs.sweep]]
+  param = "fanout"
+  values = ["4", "8", "16"]
+
+[[runs]]
+id = "smoke"
+
+  [[runs.groups]]
+  id = "publishers"
+`
+
+func TestExpandSweeps(t *testing.T) {
+	var c Composition
+	_, err := toml.Decode(sweepComposition, &c)
+	require.NoError(t, err)
+
+	expanded, err := c.ExpandSweeps()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"bench-1_KiB-16", "bench-1_KiB-4", "bench-1_KiB-8",
+		"bench-1_MiB-16", "bench-1_MiB-4", "bench-1_MiB-8",
+		"smoke",
+	}, expanded.ListRunIds())
+
+	// runs are expanded in declaration order.
+	r := expanded.Runs[1]
+	require.Equal(t, "bench-1_KiB-8", r.ID)
+	require.Nil(t, r.Sweep)
+	require.Equal(t, map[string]string{"message_size": "1 KiB", "fanout": "8"}, r.Coordinates)
+	require.Equal(t, map[string]string{"rounds": "10"}, r.TestParams)
+	for _, g := range r.Groups {
+		require.Equal(t, map[string]string{"message_size": "1 KiB", "fanout": "8"}, g.TestParams)
+	}
+
+	// runs without a sweep are left untouched.
+	require.Same(t, c.Runs[1], expanded.Runs[6])
+
+	// the original composition is left untouched.
+	require.Len(t, c.Runs, 2)
+	require.Len(t, c.Runs[0].Sweep, 2)
+	require.Nil(t, c.Runs[0].Groups[0].TestParams)
+
+	// expansion is idempotent.
+	again, err := expanded.ExpandSweeps()
+	require.NoError(t, err)
+	require.Equal(t, expanded.ListRunIds(), again.ListRunIds())
+}
+
+func TestExpandSweepsRejectsInvalidSweeps(t *testing.T) {
+	cases := map[string][]*SweepDimension{
+		"no param":      {{Values: []string{"1"}}},
+		"no values":     {{Param: "size"}},
+		"duplicate":     {{Param: "size", Values: []string{"1"}}, {Param: "size", Values: []string{"2"}}},
+		"duplicate ids": {{Param: "size", Values: []string{"1 KiB", "1/KiB"}}},
+	}
+
+	for name, sweep := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := Composition{Runs: Runs{{ID: "bench", Sweep: sweep}}}
+			_, err := c.ExpandSweeps()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPrepareForRunExpandsSweeps(t *testing.T) {
+	var c Composition
+	_, err := toml.Decode(sweepComposition, &c)
+	require.NoError(t, err)
+
+	manifest := &TestPlanManifest{
+		Name: "foo_plan",
+		Builders: map[string]config.ConfigMap{
+			"docker:go": {},
+		},
+		Runners: map[string]config.ConfigMap{
+			"local:docker": {},
+		},
+		TestCases: []*TestCase{
+			{
+				Name:      "foo_case",
+				Instances: InstanceConstraints{Minimum: 1, Maximum: 100},
+			},
+		},
+	}
+
+	ret, err := c.PrepareForRun(manifest)
+	require.NoError(t, err)
+	require.NoError(t, ret.ValidateForRun())
+	require.Len(t, ret.Runs, 7)
+
+	// sweep values take precedence over group and run parameters.
+	r := ret.Runs[4]
+	require.Equal(t, "bench-1_MiB-8", r.ID)
+	require.EqualValues(t, 3, r.TotalInstances)
+	require.Equal(t, map[string]string{"message_size": "1 MiB", "fanout": "8", "rounds": "10"}, r.Groups[0].TestParams)
+	require.Equal(t, map[string]string{"message_size": "1 MiB", "fanout": "8", "rounds": "10"}, r.Groups[1].TestParams)
+
+	framed, err := ret.FrameForRuns("bench-1_MiB-8")
+	require.NoError(t, err)
+	require.Len(t, framed.Groups, 2)
+}
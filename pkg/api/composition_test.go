@@ -588,3 +588,66 @@ func TestMarshalIsIdempotent(t *testing.T) {
 	require.Equal(t, c, &composition)
 	require.Equal(t, uint(4), composition.Runs[1].TotalInstances)
 }
+
+func TestValidateStartPolicies(t *testing.T) {
+	cases := []struct {
+		name  string
+		a, b  *StartPolicy
+		valid bool
+	}{
+		{"none", nil, nil, true},
+		{"delay and rate", &StartPolicy{Delay: "10s", Rate: 2}, nil, true},
+		{"after group", nil, &StartPolicy{AfterGroup: "a", AfterState: "ready"}, true},
+		{"chain", nil, &StartPolicy{AfterGroup: "a", AfterState: "ready", Delay: "1m"}, true},
+		{"invalid delay", &StartPolicy{Delay: "soon"}, nil, false},
+		{"negative rate", &StartPolicy{Rate: -1}, nil, false},
+		{"missing state", nil, &StartPolicy{AfterGroup: "a"}, false},
+		{"missing group", nil, &StartPolicy{AfterState: "ready"}, false},
+		{"unknown group", nil, &StartPolicy{AfterGroup: "z", AfterState: "ready"}, false},
+		{"self", &StartPolicy{AfterGroup: "a", AfterState: "ready"}, nil, false},
+		{"cycle", &StartPolicy{AfterGroup: "b", AfterState: "ready"}, &StartPolicy{AfterGroup: "a", AfterState: "ready"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			comp := &Composition{
+				Global: Global{
+					Plan:    "foo_plan",
+					Case:    "foo_case",
+					Builder: "docker:go",
+					Runner:  "local:docker",
+				},
+				Groups: []*Group{{ID: "a"}, {ID: "b"}},
+				Runs: []*Run{{
+					ID: "default",
+					Groups: []*CompositionRunGroup{
+						{ID: "a", Instances: Instances{Count: 1}, Start: c.a},
+						{ID: "b", Instances: Instances{Count: 1}, Start: c.b},
+					},
+				}},
+			}
+
+			err := comp.ValidateForRun()
+			if c.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestStartPolicyIsMerged(t *testing.T) {
+	shared := &StartPolicy{Delay: "10s", Rate: 1}
+
+	r := &CompositionRunGroup{ID: "a", Start: &StartPolicy{Rate: 5}}
+	require.NoError(t, r.mergeRun(&RunParams{Start: shared}))
+	require.Equal(t, &StartPolicy{Delay: "10s", Rate: 5}, r.Start)
+
+	// the policy of the group is left untouched.
+	require.Equal(t, &StartPolicy{Delay: "10s", Rate: 1}, shared)
+
+	r = &CompositionRunGroup{ID: "a"}
+	require.NoError(t, r.mergeRun(&RunParams{}))
+	require.Nil(t, r.Start)
+}
@@ -62,6 +62,13 @@ func (rs Runs) Validate(c *Composition) error {
 		}
 	}
 
+	// Validate start policies
+	for _, r := range rs {
+		if err := r.validateStartPolicies(); err != nil {
+			return err
+		}
+	}
+
 	// Recalculate instance counts
 	for _, r := range rs {
 		err := r.recalculateInstanceCounts()
@@ -74,6 +81,53 @@ func (rs Runs) Validate(c *Composition) error {
 	return nil
 }
 
+// validateStartPolicies validates that the start policies of the groups of
+// the run are well formed, and that groups waiting for other groups don't
+// wait for each other.
+func (r *Run) validateStartPolicies() error {
+	after := make(map[string]string, len(r.Groups))
+	for _, g := range r.Groups {
+		after[g.ID] = ""
+	}
+
+	for _, g := range r.Groups {
+		p := g.Start
+		if p == nil {
+			continue
+		}
+		if _, err := p.DelayDuration(); err != nil {
+			return fmt.Errorf("run %s:%s has an invalid start delay: %w", r.ID, g.ID, err)
+		}
+		if p.Rate < 0 {
+			return fmt.Errorf("run %s:%s has a negative start rate", r.ID, g.ID)
+		}
+		if p.AfterGroup == "" && p.AfterState == "" {
+			continue
+		}
+		if p.AfterGroup == "" || p.AfterState == "" {
+			return fmt.Errorf("run %s:%s must start after both a group and a state", r.ID, g.ID)
+		}
+		if _, ok := after[p.AfterGroup]; !ok || p.AfterGroup == g.ID {
+			return fmt.Errorf("run %s:%s starts after invalid group %s", r.ID, g.ID, p.AfterGroup)
+		}
+		after[g.ID] = p.AfterGroup
+	}
+
+	// every group waits for one group at most; follow the chain of every
+	// group, looking for one that leads back to it.
+	for _, g := range r.Groups {
+		seen := map[string]bool{g.ID: true}
+		for next := after[g.ID]; next != ""; next = after[next] {
+			if seen[next] {
+				return fmt.Errorf("run %s:%s starts after a group that waits for it", r.ID, g.ID)
+			}
+			seen[next] = true
+		}
+	}
+
+	return nil
+}
+
 // ValidateForBuild validates that this Composition is correct for a build.
 func (c *Composition) ValidateForBuild() error {
 	err := compositionValidator.StructExcept(c,
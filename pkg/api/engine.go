@@ -37,6 +37,31 @@ type UnpackedSources struct {
 	ExtraDir string `json:"extra_dir"`
 }
 
+// Source kinds, as used in the keys of a SourceManifest. They match the
+// directories under UnpackedSources.BaseDir.
+const (
+	SourceKindPlan  = "plan"
+	SourceKindSDK   = "sdk"
+	SourceKindExtra = "extra"
+)
+
+// SourceManifest describes the source trees of a build request by content,
+// keyed by source kind (plan, sdk, extra).
+type SourceManifest map[string][]SourceFile
+
+// SourceFile is a single file within a source tree.
+type SourceFile struct {
+	// Path is the slash-separated path of the file, relative to the root of
+	// its source tree.
+	Path string `json:"path"`
+
+	// Hash is the hex-encoded sha256 digest of the file contents.
+	Hash string `json:"hash"`
+
+	// Mode holds the permission bits of the file.
+	Mode uint32 `json:"mode"`
+}
+
 type TasksFilters struct {
 	Types    []task.Type
 	States   []task.State
@@ -62,6 +87,9 @@ type Engine interface {
 	DoCollectOutputs(ctx context.Context, runID string, ow *rpc.OutputWriter) error
 	DoTerminate(ctx context.Context, ctype ComponentType, ref string, ow *rpc.OutputWriter) error
 	DoHealthcheck(ctx context.Context, runner string, fix bool, ow *rpc.OutputWriter) (*HealthcheckReport, error)
+	DoInspectRun(ctx context.Context, taskID string, ow *rpc.OutputWriter) (*RunInspection, error)
+	DoAttach(ctx context.Context, taskID string, opts *AttachOptions, ow *rpc.OutputWriter) (int, error)
+	DoDryRun(ctx context.Context, request *DryRunRequest, ow *rpc.OutputWriter) ([]*DryRun, error)
 
 	EnvConfig() config.EnvConfig
 	Context() context.Context
@@ -36,10 +36,27 @@ type TestCase struct {
 
 // Parameter is metadata about a test case parameter.
 type Parameter struct {
+	// Type is one of int, float, bool, duration, string, enum or json (see
+	// ParamTypes). Values of untyped parameters, or of parameters of other
+	// types, are only checked against their constraints.
 	Type        string
 	Description string `toml:"desc"`
 	Unit        string
 	Default     interface{}
+
+	// Required parameters must be set by compositions, unless they have a
+	// default.
+	Required bool `toml:"required"`
+
+	// Enum lists the values the parameter can take. It's mandatory for enum
+	// parameters, and restricts the values of parameters of other types.
+	Enum []string `toml:"enum"`
+
+	// Min and Max bound the values of int, float and duration parameters,
+	// inclusively. Durations are expressed in time.Duration string
+	// representation (e.g. 5s).
+	Min interface{} `toml:"min"`
+	Max interface{} `toml:"max"`
 }
 
 // InstanceConstraints expresses how many instances this test case can run.
@@ -69,6 +86,8 @@ func (tp *TestPlanManifest) defaultParameters(testcaseName string) (map[string]s
 	defaultsTestParams := make(map[string]string, len(tc.Parameters))
 	for n, v := range tc.Parameters {
 		switch dv := v.Default.(type) {
+		case nil:
+			// no default; the parameter is unset unless the composition sets it.
 		case string:
 			defaultsTestParams[n] = dv
 		default:
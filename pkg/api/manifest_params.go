@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamTypes are the types of test case parameters whose values are checked.
+// Values of parameters of other types are taken as unchecked strings.
+var ParamTypes = []string{"int", "float", "bool", "duration", "string", "enum", "json"}
+
+// UnknownParamTypes returns the parameters of the test cases of this manifest
+// whose types aren't among ParamTypes, as "<test case>.<param>: <type>", for
+// callers to warn about; their values are only checked against their
+// constraints.
+func (tp *TestPlanManifest) UnknownParamTypes() []string {
+	var unknown []string
+	for _, tc := range tp.TestCases {
+		for _, name := range tc.paramNames() {
+			if typ := tc.Parameters[name].Type; typ != "" && !containsString(ParamTypes, typ) {
+				unknown = append(unknown, fmt.Sprintf("%s.%s: %s", tc.Name, name, typ))
+			}
+		}
+	}
+	return unknown
+}
+
+// Validate checks that value is a valid value for this parameter: that it
+// parses as the type of the parameter, and that it satisfies its constraints.
+// Parameters of unknown types are only checked against their constraints.
+func (p Parameter) Validate(value string) error {
+	switch p.Type {
+	case "", "string":
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an int", value)
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a float", value)
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a bool", value)
+		}
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+	case "enum":
+		if len(p.Enum) == 0 {
+			return fmt.Errorf("enum parameter has no enum values")
+		}
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("%q is not valid json", value)
+		}
+	}
+
+	if len(p.Enum) > 0 && !containsString(p.Enum, value) {
+		return fmt.Errorf("%q is not one of %v", value, p.Enum)
+	}
+
+	if p.Min == nil && p.Max == nil {
+		return nil
+	}
+
+	v, err := p.ordinal(value)
+	if err != nil {
+		return err
+	}
+	if p.Min != nil {
+		min, err := p.ordinal(p.Min)
+		if err != nil {
+			return fmt.Errorf("invalid min: %w", err)
+		}
+		if v < min {
+			return fmt.Errorf("%s is less than the minimum %v", value, p.Min)
+		}
+	}
+	if p.Max != nil {
+		max, err := p.ordinal(p.Max)
+		if err != nil {
+			return fmt.Errorf("invalid max: %w", err)
+		}
+		if v > max {
+			return fmt.Errorf("%s is greater than the maximum %v", value, p.Max)
+		}
+	}
+	return nil
+}
+
+// ordinal converts a value, or a bound, of an int, float or duration
+// parameter into a float64 for comparison. Bounds may be numbers, as decoded
+// from TOML or JSON, or strings.
+func (p Parameter) ordinal(v interface{}) (float64, error) {
+	switch p.Type {
+	case "int", "float":
+		switch n := v.(type) {
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case string:
+			return strconv.ParseFloat(n, 64)
+		}
+	case "duration":
+		if s, ok := v.(string); ok {
+			d, err := time.ParseDuration(s)
+			return float64(d), err
+		}
+	default:
+		return 0, fmt.Errorf("min and max only apply to int, float and duration parameters")
+	}
+	return 0, fmt.Errorf("%v is not a valid %s", v, p.Type)
+}
+
+// validateParams checks the test parameters of a group against the
+// parameters declared by this test case: that they're declared, that their
+// values are valid, and that required parameters are set.
+//
+// Test cases that declare no parameters accept any parameter, as plans
+// predating parameter validation don't necessarily declare the parameters
+// they read.
+func (tc *TestCase) validateParams(params map[string]string) error {
+	if len(tc.Parameters) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p, ok := tc.Parameters[name]
+		if !ok {
+			return fmt.Errorf("unknown test param %q for test case %s%s", name, tc.Name, tc.suggestParam(name))
+		}
+		if err := p.Validate(params[name]); err != nil {
+			return fmt.Errorf("invalid test param %s: %w", name, err)
+		}
+	}
+
+	for _, name := range tc.paramNames() {
+		if _, ok := params[name]; !ok && tc.Parameters[name].Required {
+			return fmt.Errorf("missing required test param %s for test case %s", name, tc.Name)
+		}
+	}
+	return nil
+}
+
+func (tc *TestCase) paramNames() []string {
+	names := make([]string, 0, len(tc.Parameters))
+	for name := range tc.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suggestParam returns a hint naming the declared parameter closest to an
+// unknown one, or all declared parameters if none is close enough.
+func (tc *TestCase) suggestParam(name string) string {
+	var (
+		names = tc.paramNames()
+		best  string
+		dist  = 3 // only suggest names at most two edits away.
+	)
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(n)); d < dist {
+			best, dist = n, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("; did you mean %q?", best)
+	}
+	return fmt.Sprintf("; declared: %v", names)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func containsString(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/config"
+)
+
+func TestParameterValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		param Parameter
+		value string
+		err   string
+	}{
+		{"untyped", Parameter{}, "anything", ""},
+		{"string", Parameter{Type: "string"}, "anything", ""},
+		{"int", Parameter{Type: "int"}, "42", ""},
+		{"int invalid", Parameter{Type: "int"}, "4.2", `"4.2" is not an int`},
+		{"float", Parameter{Type: "float"}, "4.2", ""},
+		{"float invalid", Parameter{Type: "float"}, "four", `"four" is not a float`},
+		{"bool", Parameter{Type: "bool"}, "true", ""},
+		{"bool invalid", Parameter{Type: "bool"}, "yes", `"yes" is not a bool`},
+		{"duration", Parameter{Type: "duration"}, "1m30s", ""},
+		{"duration invalid", Parameter{Type: "duration"}, "90", `"90" is not a duration`},
+		{"json", Parameter{Type: "json"}, `{"a": [1, 2]}`, ""},
+		{"json invalid", Parameter{Type: "json"}, `{"a": `, "is not valid json"},
+		{"enum", Parameter{Type: "enum", Enum: []string{"tcp", "quic"}}, "quic", ""},
+		{"enum invalid", Parameter{Type: "enum", Enum: []string{"tcp", "quic"}}, "udp", `"udp" is not one of [tcp quic]`},
+		{"enum without values", Parameter{Type: "enum"}, "udp", "has no enum values"},
+		{"string with enum", Parameter{Type: "string", Enum: []string{"a"}}, "b", `"b" is not one of [a]`},
+		{"unknown type", Parameter{Type: "[]string"}, "a", ""},
+		{"unknown type with enum", Parameter{Type: "[]string", Enum: []string{"a"}}, "b", `"b" is not one of [a]`},
+		{"unknown type with bound", Parameter{Type: "[]string", Min: int64(1)}, "a", "only apply to int, float and duration"},
+		{"int within range", Parameter{Type: "int", Min: int64(1), Max: int64(10)}, "10", ""},
+		{"int below min", Parameter{Type: "int", Min: int64(1)}, "0", "0 is less than the minimum 1"},
+		{"int above max", Parameter{Type: "int", Max: float64(10)}, "11", "11 is greater than the maximum 10"},
+		{"float above string max", Parameter{Type: "float", Max: "0.5"}, "0.75", "greater than the maximum"},
+		{"duration within range", Parameter{Type: "duration", Min: "1s", Max: "1m"}, "30s", ""},
+		{"duration above max", Parameter{Type: "duration", Max: "1m"}, "2m", "2m is greater than the maximum 1m"},
+		{"invalid bound", Parameter{Type: "duration", Min: int64(1)}, "2m", "invalid min"},
+		{"bound on string", Parameter{Type: "string", Max: int64(1)}, "a", "only apply to int, float and duration"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.param.Validate(c.value)
+			if c.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.err)
+		})
+	}
+}
+
+func TestUnknownParamTypes(t *testing.T) {
+	m := &TestPlanManifest{TestCases: []*TestCase{
+		{Name: "a", Parameters: map[string]Parameter{
+			"peers": {Type: "int"},
+			"addrs": {Type: "[]string"},
+			"label": {},
+		}},
+		{Name: "b", Parameters: map[string]Parameter{
+			"topology": {Type: "map"},
+		}},
+	}}
+
+	require.Equal(t, []string{"a.addrs: []string", "b.topology: map"}, m.UnknownParamTypes())
+	require.Empty(t, (&TestPlanManifest{}).UnknownParamTypes())
+}
+
+func TestTestCaseValidateParams(t *testing.T) {
+	tc := &TestCase{
+		Name: "bench",
+		Parameters: map[string]Parameter{
+			"message_size": {Type: "int", Required: true},
+			"fanout":       {Type: "int", Default: 4},
+		},
+	}
+
+	require.NoError(t, tc.validateParams(map[string]string{"message_size": "1024", "fanout": "8"}))
+
+	err := tc.validateParams(map[string]string{"message_size": "1024", "fanuot": "8"})
+	require.EqualError(t, err, `unknown test param "fanuot" for test case bench; did you mean "fanout"?`)
+
+	err = tc.validateParams(map[string]string{"message_size": "1024", "peers": "8"})
+	require.EqualError(t, err, `unknown test param "peers" for test case bench; declared: [fanout message_size]`)
+
+	err = tc.validateParams(map[string]string{"fanout": "8"})
+	require.EqualError(t, err, "missing required test param message_size for test case bench")
+
+	err = tc.validateParams(map[string]string{"message_size": "1 KiB"})
+	require.EqualError(t, err, `invalid test param message_size: "1 KiB" is not an int`)
+
+	// test cases that declare no parameters accept any.
+	require.NoError(t, (&TestCase{Name: "any"}).validateParams(map[string]string{"peers": "8"}))
+}
+
+func TestPrepareForRunValidatesTestParams(t *testing.T) {
+	manifest := &TestPlanManifest{
+		Name: "foo_plan",
+		Builders: map[string]config.ConfigMap{
+			"docker:go": {},
+		},
+		Runners: map[string]config.ConfigMap{
+			"local:docker": {},
+		},
+		TestCases: []*TestCase{
+			{
+				Name:      "foo_case",
+				Instances: InstanceConstraints{Minimum: 1, Maximum: 100},
+				Parameters: map[string]Parameter{
+					"fanout":  {Type: "int", Default: 4, Min: int64(1)},
+					"timeout": {Type: "duration"},
+				},
+			},
+		},
+	}
+
+	comp := func(params map[string]string) *Composition {
+		return &Composition{
+			Global: Global{
+				Plan:    "foo_plan",
+				Case:    "foo_case",
+				Builder: "docker:go",
+				Runner:  "local:docker",
+			},
+			Groups: Groups{{ID: "peers", Instances: Instances{Count: 2}}},
+			Runs: Runs{{
+				ID:     "default",
+				Groups: CompositionRunGroups{{ID: "peers", TestParams: params}},
+			}},
+		}
+	}
+
+	// manifest defaults are validated too, and params without a default are
+	// left unset.
+	ret, err := comp(nil).PrepareForRun(manifest)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"fanout": "4"}, ret.Runs[0].Groups[0].TestParams)
+
+	_, err = comp(map[string]string{"timeout": "30s", "fanout": "0"}).PrepareForRun(manifest)
+	require.EqualError(t, err, "error preparing run default: group peers: invalid test param fanout: 0 is less than the minimum 1")
+
+	_, err = comp(map[string]string{"timout": "30s"}).PrepareForRun(manifest)
+	require.EqualError(t, err, `error preparing run default: group peers: unknown test param "timout" for test case foo_case; did you mean "timeout"?`)
+}
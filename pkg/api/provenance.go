@@ -0,0 +1,84 @@
+package api
+
+import (
+	"time"
+)
+
+// SBOM formats supported by builders that are able to emit a software bill of
+// materials.
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+)
+
+// BuildProvenance records how a build artifact was produced, so that it can be
+// audited after the fact.
+type BuildProvenance struct {
+	// BuildID is the unique ID of the build that produced the artifact.
+	BuildID string `json:"build_id"`
+
+	// BuilderID is the ID of the builder used.
+	BuilderID string `json:"builder_id"`
+
+	// TestPlan is the name of the test plan that was built.
+	TestPlan string `json:"test_plan"`
+
+	// Groups enumerates the composition groups this artifact was built for.
+	Groups []string `json:"groups"`
+
+	// ArtifactPath is the resulting artifact (docker image ID, file location,
+	// etc.).
+	ArtifactPath string `json:"artifact_path"`
+
+	// SourceHash is a content hash of the source tree (plan, sdk and extra
+	// sources) the artifact was built from.
+	SourceHash string `json:"source_hash"`
+
+	// BuildConfig is the coalesced build configuration handed to the builder.
+	BuildConfig interface{} `json:"build_config"`
+
+	// Selectors are the source selectors the build was performed with.
+	Selectors []string `json:"selectors"`
+
+	// DependencyOverrides are the upstream dependency overrides requested for
+	// this build.
+	DependencyOverrides map[string]DependencyTarget `json:"dependency_overrides"`
+
+	// ResolvedDependencies is the collapsed transitive dependency set of the
+	// build, as reported by the builder.
+	ResolvedDependencies map[string]string `json:"resolved_dependencies"`
+
+	// BaseImages enumerates the container images the artifact was built on,
+	// if any.
+	BaseImages []string `json:"base_images"`
+
+	// StartedAt is the time the build started.
+	StartedAt time.Time `json:"started_at"`
+
+	// Duration is how long the build took.
+	Duration time.Duration `json:"duration"`
+
+	// SBOM is the software bill of materials of the artifact, when the
+	// builder supports it and it has been requested.
+	SBOM *SBOM `json:"sbom,omitempty"`
+}
+
+// SBOM is a software bill of materials document in a given format.
+type SBOM struct {
+	// Format is one of SBOMFormatSPDX or SBOMFormatCycloneDX.
+	Format string `json:"format"`
+
+	// Document is the SBOM document, ready to be serialized as JSON.
+	Document interface{} `json:"document"`
+}
+
+// BuildResult is the result of a build task.
+type BuildResult struct {
+	// Artifacts are the artifacts produced for every group of the
+	// composition, in group order.
+	Artifacts []string `json:"artifacts"`
+
+	// Provenance holds a provenance document for every unique build that was
+	// performed.
+	Provenance []*BuildProvenance `json:"provenance"`
+}
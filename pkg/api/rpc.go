@@ -35,6 +35,12 @@ type RunRequest struct {
 
 type CreatedBy task.CreatedBy
 
+// SourcesRequest is the request struct for the `sources` function, which
+// negotiates which source files need to be uploaded for a build or run.
+type SourcesRequest struct {
+	Manifest SourceManifest `json:"manifest"`
+}
+
 type OutputsRequest struct {
 	Runner string `json:"runner"`
 	RunID  string `json:"run_id"`
@@ -65,6 +71,33 @@ type CancelRequest struct {
 	TaskID string `json:"task_id"`
 }
 
+type InspectRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+type AttachRequest struct {
+	TaskID   string `json:"task_id"`
+	GroupID  string `json:"group_id"`
+	Instance int    `json:"instance"`
+
+	// Command is the command to execute in the instance; a shell if empty.
+	Command []string `json:"command"`
+
+	// TTY allocates a terminal of the given size to the command.
+	TTY    bool `json:"tty"`
+	Width  uint `json:"width"`
+	Height uint `json:"height"`
+}
+
+// DryRunRequest is the request struct for the `dry-run` function, which
+// plans the capacity of the runs of a composition without building or
+// running them.
+type DryRunRequest struct {
+	RunIds      []string         `json:"run_ids"`
+	Composition Composition      `json:"composition"`
+	Manifest    TestPlanManifest `json:"manifest"`
+}
+
 type LogsRequest struct {
 	TaskID string `json:"task_id"`
 	Follow bool   `json:"follow"`
@@ -82,6 +115,12 @@ type BuildResponse = []BuildOutput
 
 type RunResponse = RunOutput
 
+// SourcesResponse lists the hashes of the files the daemon does not hold in
+// its content store, and which must therefore be uploaded.
+type SourcesResponse struct {
+	Missing []string `json:"missing"`
+}
+
 type CollectResponse struct {
 	File   bytes.Buffer
 	Exists bool
@@ -92,3 +131,7 @@ type HealthcheckResponse = HealthcheckReport
 type StatusResponse = task.Task
 
 type LogsResponse = task.Task
+
+type InspectResponse = RunInspection
+
+type DryRunResponse = []*DryRun
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"io"
 	"reflect"
 
 	"github.com/testground/testground/pkg/config"
@@ -82,6 +83,14 @@ type RunGroup struct {
 	// Profiles specifies the profiles to capture. Refer to the docs
 	// on Run#Profiles for more info.
 	Profiles map[string]string
+
+	// Coordinates are the build matrix values this group was expanded from,
+	// if any. Refer to the docs on Group#Coordinates for more info.
+	Coordinates map[string]string
+
+	// Start paces the start of the instances of this group. Refer to the
+	// docs on StartPolicy for more info.
+	Start StartPolicy
 }
 
 type RunOutput struct {
@@ -118,3 +127,174 @@ type CollectionInput struct {
 type Terminatable interface {
 	TerminateAll(context.Context, *rpc.OutputWriter) error
 }
+
+// RunTerminatable is the interface to be implemented by a runner that can
+// terminate the containers, pods or processes and networks of a single run,
+// leaving other runs untouched.
+type RunTerminatable interface {
+	TerminateRun(ctx context.Context, runID string, ow *rpc.OutputWriter) error
+}
+
+// BuildConfigurer is the interface to be implemented by a runner that needs
+// the artifacts of its runs built in a particular way, e.g. in the container
+// engine it runs them in.
+type BuildConfigurer interface {
+	// BuildConfig returns the build configuration the runner needs from a
+	// builder, or nil if it needs none. It takes precedence over the
+	// defaults of the builder only.
+	BuildConfig(builder string) map[string]interface{}
+}
+
+// InstanceState is the lifecycle state of a test instance.
+type InstanceState string
+
+const (
+	InstanceStateCreating = InstanceState("creating")
+	InstanceStateRunning  = InstanceState("running")
+	InstanceStateExited   = InstanceState("exited")
+)
+
+// InstanceStatus describes a test instance of a run in progress.
+type InstanceStatus struct {
+	GroupID string `json:"group_id"`
+	Index   int    `json:"index"`
+
+	// ID identifies what backs the instance: a container, a pod or a process.
+	ID    string        `json:"id"`
+	State InstanceState `json:"state"`
+
+	// ExitCode is only meaningful once the instance has exited.
+	ExitCode int `json:"exit_code"`
+	Restarts int `json:"restarts"`
+
+	// Ports are the ports exposed by the instance, in the notation of the
+	// runner, e.g. "6060/tcp -> 0.0.0.0:49153".
+	Ports []string `json:"ports,omitempty"`
+}
+
+// GroupEvents tallies the sync events emitted by the instances of a group.
+// Sync events don't identify the instance that emitted them, only its group.
+type GroupEvents struct {
+	Start   int `json:"start"`
+	Success int `json:"success"`
+	Failure int `json:"failure"`
+	Crash   int `json:"crash"`
+
+	// Last is the last event received: start, success, failure or crash.
+	Last string `json:"last"`
+}
+
+// RunInspection is the state of a run in progress.
+type RunInspection struct {
+	RunID     string                  `json:"run_id"`
+	Runner    string                  `json:"runner"`
+	Instances []InstanceStatus        `json:"instances"`
+	Events    map[string]*GroupEvents `json:"events"`
+}
+
+// Inspectable is the interface to be implemented by a runner that can report
+// the state of the instances of a run in progress.
+type Inspectable interface {
+	InspectRun(ctx context.Context, runID string, ow *rpc.OutputWriter) (*RunInspection, error)
+}
+
+// AttachOptions select an instance of a run in progress, and the command to
+// execute in it.
+type AttachOptions struct {
+	GroupID  string
+	Instance int
+
+	// Command is the command to execute; a shell if empty.
+	Command []string
+
+	// TTY allocates a terminal of the given size to the command, in which
+	// case its stderr is merged with its stdout.
+	TTY    bool
+	Width  uint
+	Height uint
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Attachable is the interface to be implemented by a runner that can execute
+// commands in the instances of a run in progress. Attach returns the exit
+// code of the command.
+type Attachable interface {
+	Attach(ctx context.Context, runID string, opts *AttachOptions, ow *rpc.OutputWriter) (int, error)
+}
+
+// GroupCapacity is the resources requested by the instances of a group.
+type GroupCapacity struct {
+	ID        string `json:"id"`
+	Instances int    `json:"instances"`
+
+	// CPU and Memory are requested by each instance, in cores and bytes;
+	// zero if the instances are not limited.
+	CPU    float64 `json:"cpu"`
+	Memory int64   `json:"memory"`
+}
+
+// CapacityReport compares the resources requested by the instances of a run
+// deployed to a target with the resources the target can allocate to them.
+type CapacityReport struct {
+	// Target is where the instances are deployed to, e.g. a Kubernetes
+	// context and namespace, or the host.
+	Target string          `json:"target"`
+	Groups []GroupCapacity `json:"groups"`
+
+	RequestedCPU    float64 `json:"requested_cpu"`
+	RequestedMemory int64   `json:"requested_memory"`
+
+	// AvailableCPU and AvailableMemory are the resources the target can
+	// allocate to instances; negative if unknown, in which case they are not
+	// checked.
+	AvailableCPU    float64 `json:"available_cpu"`
+	AvailableMemory int64   `json:"available_memory"`
+
+	// Notes qualify the report, e.g. instances that can't be accounted for.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Shortfall returns the resources missing for the requested ones to fit.
+func (r *CapacityReport) Shortfall() (cpu float64, memory int64) {
+	if r.AvailableCPU >= 0 && r.RequestedCPU > r.AvailableCPU {
+		cpu = r.RequestedCPU - r.AvailableCPU
+	}
+	if r.AvailableMemory >= 0 && r.RequestedMemory > r.AvailableMemory {
+		memory = r.RequestedMemory - r.AvailableMemory
+	}
+	return cpu, memory
+}
+
+// Fits returns whether the requested resources fit in the available ones.
+func (r *CapacityReport) Fits() bool {
+	cpu, memory := r.Shortfall()
+	return cpu == 0 && memory == 0
+}
+
+// CapacityPlanner is the interface to be implemented by a runner that can
+// tell whether the instances of a run fit in the resources of its targets,
+// without running them. It returns a report per target.
+type CapacityPlanner interface {
+	PlanCapacity(ctx context.Context, input *RunInput, ow *rpc.OutputWriter) ([]*CapacityReport, error)
+}
+
+// DryRun is the capacity plan of a run of a composition.
+type DryRun struct {
+	RunID          string            `json:"run_id"`
+	Runner         string            `json:"runner"`
+	TotalInstances int               `json:"total_instances"`
+	Capacity       []*CapacityReport `json:"capacity"`
+}
+
+// Fits returns whether the run fits in all its targets.
+func (d *DryRun) Fits() bool {
+	for _, r := range d.Capacity {
+		if !r.Fits() {
+			return false
+		}
+	}
+	return true
+}
@@ -1,11 +1,14 @@
 package build
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/testground/testground/pkg/api"
@@ -13,7 +16,6 @@ import (
 	"github.com/testground/testground/pkg/rpc"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
 )
 
 var (
@@ -28,6 +30,10 @@ type DockerGenericBuilderConfig struct {
 	// Custom base path where we find the test source
 	Path      string             `toml:"path" default:"./"`
 	BuildArgs map[string]*string `toml:"build_args"` // ok if nil
+
+	// Engine is the engine the image is built with, and stored in: "docker"
+	// (default), or "podman" for the local:podman runner.
+	Engine string `toml:"engine"`
 }
 
 // Build builds a testplan written in Go and outputs a Docker container.
@@ -37,11 +43,9 @@ func (b *DockerGenericBuilder) Build(ctx context.Context, in *api.BuildInput, ow
 		return nil, fmt.Errorf("expected configuration type DockerGenericBuilderConfig, was: %T", in.BuildConfig)
 	}
 
-	cliopts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
-
 	var (
 		basesrc  = in.UnpackedSources.BaseDir
-		cli, err = client.NewClientWithOpts(cliopts...)
+		cli, err = docker.NewClient(cfg.Engine)
 	)
 	if err != nil {
 		return nil, err
@@ -88,6 +92,13 @@ func (b *DockerGenericBuilder) Build(ctx context.Context, in *api.BuildInput, ow
 
 	out := &api.BuildOutput{
 		ArtifactPath: imageID,
+		Provenance:   &api.BuildProvenance{},
+	}
+
+	// Record the base images declared in the Dockerfile; this is best-effort.
+	dockerfile := filepath.Join(basesrc, basePathForPlan, "Dockerfile")
+	if out.Provenance.BaseImages, err = dockerfileBaseImages(dockerfile, cfg.BuildArgs); err != nil {
+		ow.Warnw("failed to determine base images", "dockerfile", dockerfile, "err", err)
 	}
 
 	// Testplan image tag
@@ -101,6 +112,65 @@ func (b *DockerGenericBuilder) Build(ctx context.Context, in *api.BuildInput, ow
 	return out, err
 }
 
+// dockerfileBaseImages returns the images referenced by the FROM instructions of
+// a Dockerfile, skipping references to earlier build stages. Build args
+// referenced in image names are expanded with the supplied values.
+func dockerfileBaseImages(path string, args map[string]*string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		images []string
+		stages = make(map[string]struct{})
+		vars   = make(map[string]string)
+	)
+
+	for k, v := range args {
+		if v != nil {
+			vars[k] = *v
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			// record ARG defaults, unless overridden by a build arg.
+			kv := strings.SplitN(fields[1], "=", 2)
+			if _, ok := vars[kv[0]]; !ok && len(kv) == 2 {
+				vars[kv[0]] = kv[1]
+			}
+		case "FROM":
+			// skip flags, e.g. --platform.
+			rest := fields[1:]
+			for len(rest) > 0 && strings.HasPrefix(rest[0], "--") {
+				rest = rest[1:]
+			}
+			if len(rest) == 0 {
+				continue
+			}
+
+			image := os.Expand(rest[0], func(k string) string { return vars[k] })
+			if _, ok := stages[image]; !ok && image != "scratch" {
+				images = append(images, image)
+			}
+			if len(rest) == 3 && strings.EqualFold(rest[1], "AS") {
+				stages[rest[2]] = struct{}{}
+			}
+		}
+	}
+
+	return images, scanner.Err()
+}
+
 func (*DockerGenericBuilder) ID() string {
 	return "docker:generic"
 }
@@ -28,6 +28,10 @@ import (
 const (
 	DefaultGoBuildBaseImage = "golang:1.16-buster"
 
+	// DefaultGoRuntimeImage must match the RUNTIME_IMAGE default in
+	// GoDockerfileTemplate.
+	DefaultGoRuntimeImage = "busybox:1.35.0-glibc"
+
 	buildNetworkName = "testground-build"
 )
 
@@ -113,6 +117,15 @@ type DockerGoBuilderConfig struct {
 
 	// DockefileExtensions enables plans to inject custom Dockerfile directives.
 	DockerfileExtensions DockerfileExtensions `toml:"dockerfile_extensions"`
+
+	// SBOMFormat, if set, makes the builder emit a software bill of materials
+	// in the given format ("spdx" or "cyclonedx") as part of the build
+	// provenance.
+	SBOMFormat string `toml:"sbom_format"`
+
+	// Engine is the engine the image is built with, and stored in: "docker"
+	// (default), or "podman" for the local:podman runner.
+	Engine string `toml:"engine"`
 }
 
 type DockerfileTemplateVars struct {
@@ -130,14 +143,16 @@ func (b *DockerGoBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rpc
 		return nil, fmt.Errorf("expected configuration type DockerGoBuilderConfig, was: %T", in.BuildConfig)
 	}
 
-	cliopts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if err := validateSBOMFormat(cfg.SBOMFormat); err != nil {
+		return nil, err
+	}
 
 	var (
 		baseSrc = in.UnpackedSources.BaseDir
 		planDir = in.UnpackedSources.PlanDir
 		sdkSrc  = in.UnpackedSources.SDKDir
 
-		cli, err = client.NewClientWithOpts(cliopts...)
+		cli, err = docker.NewClient(cfg.Engine)
 	)
 
 	if err != nil {
@@ -344,6 +359,23 @@ func (b *DockerGoBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rpc
 	out := &api.BuildOutput{
 		ArtifactPath: imageID,
 		Dependencies: deps,
+		Provenance: &api.BuildProvenance{
+			BaseImages: []string{cfg.BuildBaseImage},
+		},
+	}
+
+	if !cfg.SkipRuntimeImage {
+		runtimeImage := cfg.RuntimeImage
+		if runtimeImage == "" {
+			runtimeImage = DefaultGoRuntimeImage
+		}
+		out.Provenance.BaseImages = append(out.Provenance.BaseImages, runtimeImage)
+	}
+
+	if cfg.SBOMFormat != "" {
+		if out.Provenance.SBOM, err = generateGoSBOM(cfg.SBOMFormat, in, deps); err != nil {
+			return nil, fmt.Errorf("failed to generate sbom: %w", err)
+		}
 	}
 
 	// Testplan image tag
@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/docker"
 	"github.com/testground/testground/pkg/rpc"
@@ -35,10 +34,9 @@ func (d DockerNodeBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rp
 		return nil, fmt.Errorf("expected configuration type DockerNodeBuilderConfig, was: %T", in.BuildConfig)
 	}
 
-	cliopts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
 	basesrc := in.UnpackedSources.BaseDir
 
-	cli, err := client.NewClientWithOpts(cliopts...)
+	cli, err := docker.NewClient(cfg.Engine)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +87,9 @@ func (d DockerNodeBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rp
 
 	out := &api.BuildOutput{
 		ArtifactPath: imageID,
+		Provenance: &api.BuildProvenance{
+			BaseImages: []string{cfg.BaseImage},
+		},
 	}
 
 	// Testplan image tag
@@ -113,6 +114,10 @@ func (d DockerNodeBuilder) ConfigType() reflect.Type {
 type DockerNodeBuilderConfig struct {
 	Enabled   bool
 	BaseImage string `toml:"base_image"`
+
+	// Engine is the engine the image is built with, and stored in: "docker"
+	// (default), or "podman" for the local:podman runner.
+	Engine string `toml:"engine"`
 }
 
 const NodeDockerfileTemplate = `
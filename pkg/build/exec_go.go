@@ -26,6 +26,11 @@ type ExecGoBuilderConfig struct {
 	ModulePath string `toml:"module_path"`
 	ExecPkg    string `toml:"exec_pkg"`
 	FreshGomod bool   `toml:"fresh_gomod"`
+
+	// SBOMFormat, if set, makes the builder emit a software bill of materials
+	// in the given format ("spdx" or "cyclonedx") as part of the build
+	// provenance.
+	SBOMFormat string `toml:"sbom_format"`
 }
 
 // Build builds a testplan written in Go and outputs an executable.
@@ -35,6 +40,10 @@ func (b *ExecGoBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rpc.O
 		return nil, fmt.Errorf("expected configuration type ExecGoBuilderConfig, was: %T", in.BuildConfig)
 	}
 
+	if err := validateSBOMFormat(cfg.SBOMFormat); err != nil {
+		return nil, err
+	}
+
 	var (
 		id      = in.BuildID
 		plansrc = in.UnpackedSources.PlanDir
@@ -121,10 +130,20 @@ func (b *ExecGoBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rpc.O
 		return nil, fmt.Errorf("unable to list module dependencies; %w", err)
 	}
 
-	return &api.BuildOutput{
+	deps := parseDependencies(string(out))
+	res := &api.BuildOutput{
 		ArtifactPath: path,
-		Dependencies: parseDependencies(string(out)),
-	}, nil
+		Dependencies: deps,
+		Provenance:   &api.BuildProvenance{},
+	}
+
+	if cfg.SBOMFormat != "" {
+		if res.Provenance.SBOM, err = generateGoSBOM(cfg.SBOMFormat, in, deps); err != nil {
+			return nil, fmt.Errorf("failed to generate sbom: %w", err)
+		}
+	}
+
+	return res, nil
 }
 
 func (*ExecGoBuilder) ID() string {
@@ -0,0 +1,140 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+// goModule is a single entry of the `go list -m all` output.
+type goModule struct {
+	Path    string
+	Version string
+}
+
+// purl returns the package URL of this module.
+func (m goModule) purl() string {
+	if m.Version == "" {
+		return fmt.Sprintf("pkg:golang/%s", m.Path)
+	}
+	return fmt.Sprintf("pkg:golang/%s@%s", m.Path, m.Version)
+}
+
+// goModules converts the dependency map produced by parseDependencies into a
+// sorted list of modules, resolving replace directives to the effective
+// module. The main module (which has no version) is excluded.
+func goModules(deps map[string]string) []goModule {
+	mods := make([]goModule, 0, len(deps))
+	for path, version := range deps {
+		if version == "" {
+			// main module.
+			continue
+		}
+
+		// replaced modules are listed as "v1.0.0 => github.com/fork/mod v1.0.1".
+		if parts := strings.SplitN(version, "=>", 2); len(parts) == 2 {
+			repl := strings.Fields(parts[1])
+			switch len(repl) {
+			case 1:
+				// replaced by a local directory; keep the original coordinates.
+				version = strings.TrimSpace(parts[0])
+			case 2:
+				path, version = repl[0], repl[1]
+			}
+		}
+
+		mods = append(mods, goModule{Path: path, Version: version})
+	}
+
+	sort.Slice(mods, func(i, j int) bool {
+		return mods[i].Path < mods[j].Path
+	})
+	return mods
+}
+
+// validateSBOMFormat verifies that the requested SBOM format is supported. An
+// empty format means no SBOM was requested.
+func validateSBOMFormat(format string) error {
+	switch format {
+	case "", api.SBOMFormatSPDX, api.SBOMFormatCycloneDX:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sbom format %q; supported: %s, %s", format, api.SBOMFormatSPDX, api.SBOMFormatCycloneDX)
+	}
+}
+
+// generateGoSBOM produces a software bill of materials for a Go build in the
+// requested format, out of the module list reported by `go list -m all`.
+func generateGoSBOM(format string, in *api.BuildInput, deps map[string]string) (*api.SBOM, error) {
+	var (
+		name    = fmt.Sprintf("tg-plan-%s", in.TestPlan)
+		mods    = goModules(deps)
+		created = time.Now().UTC().Format(time.RFC3339)
+	)
+
+	switch format {
+	case api.SBOMFormatSPDX:
+		pkgs := make([]map[string]interface{}, 0, len(mods))
+		for i, m := range mods {
+			pkgs = append(pkgs, map[string]interface{}{
+				"name":             m.Path,
+				"SPDXID":           fmt.Sprintf("SPDXRef-Package-%d", i),
+				"versionInfo":      m.Version,
+				"downloadLocation": "NOASSERTION",
+				"filesAnalyzed":    false,
+				"externalRefs": []map[string]string{{
+					"referenceCategory": "PACKAGE-MANAGER",
+					"referenceType":     "purl",
+					"referenceLocator":  m.purl(),
+				}},
+			})
+		}
+
+		doc := map[string]interface{}{
+			"spdxVersion":       "SPDX-2.3",
+			"dataLicense":       "CC0-1.0",
+			"SPDXID":            "SPDXRef-DOCUMENT",
+			"name":              name,
+			"documentNamespace": fmt.Sprintf("https://testground.ipfs.io/spdx/%s-%s", name, in.BuildID),
+			"creationInfo": map[string]interface{}{
+				"created":  created,
+				"creators": []string{"Tool: testground"},
+			},
+			"packages": pkgs,
+		}
+		return &api.SBOM{Format: format, Document: doc}, nil
+
+	case api.SBOMFormatCycloneDX:
+		comps := make([]map[string]interface{}, 0, len(mods))
+		for _, m := range mods {
+			comps = append(comps, map[string]interface{}{
+				"type":    "library",
+				"name":    m.Path,
+				"version": m.Version,
+				"purl":    m.purl(),
+			})
+		}
+
+		doc := map[string]interface{}{
+			"bomFormat":    "CycloneDX",
+			"specVersion":  "1.4",
+			"serialNumber": "urn:uuid:" + uuid.New().String(),
+			"version":      1,
+			"metadata": map[string]interface{}{
+				"timestamp": created,
+				"tools":     []map[string]string{{"name": "testground"}},
+				"component": map[string]string{"type": "application", "name": name},
+			},
+			"components": comps,
+		}
+		return &api.SBOM{Format: format, Document: doc}, nil
+
+	default:
+		return nil, validateSBOMFormat(format)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/testground/testground/pkg/config"
 	"github.com/testground/testground/pkg/logging"
 	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/sources"
 
 	"github.com/mholt/archiver"
 	"github.com/mitchellh/mapstructure"
@@ -42,6 +43,17 @@ type Client struct {
 	endpoint string
 }
 
+// StatusError is returned when the daemon replies with an unexpected HTTP
+// status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code received: %s", e.Status)
+}
+
 // New initializes a new API client
 func New(cfg *config.EnvConfig) *Client {
 	endpoint := cfg.Client.Endpoint
@@ -73,11 +85,9 @@ func (c *Client) Run(ctx context.Context, r *api.RunRequest, plandir string, sdk
 
 // runBuild sends a multipart request to the daemon on a certain path.
 //
-// A build (or run) request comprises the following parts:
-//
-//   - Part 1 (Content-Type: application/json): the request json, usually composition.
-//   - Part 2 (optional for runs, mandatory for builds, Content-Type: application/zip): test plan source.
-//   - Part 3 (optional, Content-Type: application/zip): linked sdk.
+// Sources are uploaded incrementally when the daemon supports it (see
+// runBuildIncremental), falling back to uploading full archives of the source
+// directories otherwise (see runBuildArchived).
 //
 // The Body in the response implements an io.ReadCloser and it's up to the
 // caller to close it.
@@ -85,6 +95,198 @@ func (c *Client) Run(ctx context.Context, r *api.RunRequest, plandir string, sdk
 // The response is a stream of `Msg` protocol messages. See
 // `ParseBuildResponse()` for specifics.
 func (c *Client) runBuild(ctx context.Context, r interface{}, path, plandir, sdkdir string, extraSrcs []string) (io.ReadCloser, error) {
+	if plandir == "" && sdkdir == "" && len(extraSrcs) == 0 {
+		return c.runBuildArchived(ctx, r, path, plandir, sdkdir, extraSrcs)
+	}
+
+	rc, err := c.runBuildIncremental(ctx, r, path, plandir, sdkdir, extraSrcs)
+	if err == nil {
+		return rc, nil
+	}
+
+	var se *StatusError
+	if !errors.As(err, &se) || (se.StatusCode != http.StatusNotFound && se.StatusCode != http.StatusMethodNotAllowed) {
+		return nil, err
+	}
+
+	logging.S().Infow("daemon does not support incremental source uploads; uploading full sources")
+	return c.runBuildArchived(ctx, r, path, plandir, sdkdir, extraSrcs)
+}
+
+// runBuildIncremental sends a multipart request to the daemon on a certain
+// path, only uploading the source files that the daemon does not have yet.
+//
+// Before sending the request, the client sends a manifest of the hashes of
+// all source files to the `/sources` endpoint, and the daemon replies with
+// the hashes it's missing. The request then comprises the following parts:
+//
+//   - Part 1 (Content-Type: application/json): the request json, usually composition.
+//   - Part 2 (Content-Type: application/json): the source manifest.
+//   - Parts 3..n (Content-Type: application/octet-stream): the contents of each
+//     missing file, named after its hash.
+func (c *Client) runBuildIncremental(ctx context.Context, r interface{}, path, plandir, sdkdir string, extraSrcs []string) (io.ReadCloser, error) {
+	var (
+		manifest = make(api.SourceManifest)
+		local    = make(map[string]string)
+		cleanup  = func() {}
+	)
+
+	addTree := func(kind string, toplevel bool, dirs ...string) error {
+		tree, err := sources.ManifestDirs(toplevel, dirs...)
+		if err != nil {
+			return err
+		}
+		manifest[kind] = tree.Files
+		for hash, p := range tree.Local {
+			local[hash] = p
+		}
+		return nil
+	}
+
+	if plandir != "" {
+		filteredDir, err := getFilteredDirectory(plandir)
+		if err != nil {
+			return nil, err
+		}
+		cleanup = func() { os.RemoveAll(filteredDir) }
+
+		if err := addTree(api.SourceKindPlan, false, filteredDir); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	if sdkdir != "" {
+		if err := addTree(api.SourceKindSDK, false, sdkdir); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	if len(extraSrcs) != 0 {
+		if err := addTree(api.SourceKindExtra, true, extraSrcs...); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	missing, err := c.negotiateSources(ctx, manifest)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	logging.S().Infow("uploading sources", "files", len(local), "missing", len(missing))
+
+	var (
+		rd, wr = io.Pipe()
+		mp     = multipart.NewWriter(wr)
+	)
+
+	go func() error {
+		defer cleanup()
+
+		var (
+			hcomp     = make(textproto.MIMEHeader) // composition
+			hmanifest = make(textproto.MIMEHeader) // source manifest
+		)
+
+		hcomp.Set("Content-Type", "application/json")
+		hcomp.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "composition.json"}))
+
+		hmanifest.Set("Content-Type", "application/json")
+		hmanifest.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "manifest.json"}))
+
+		// Part 1: composition json.
+		w, err := mp.CreatePart(hcomp)
+		if err != nil {
+			return wr.CloseWithError(err)
+		}
+		if err := json.NewEncoder(w).Encode(r); err != nil {
+			return wr.CloseWithError(err)
+		}
+
+		// Part 2: source manifest.
+		w, err = mp.CreatePart(hmanifest)
+		if err != nil {
+			return wr.CloseWithError(err)
+		}
+		if err := json.NewEncoder(w).Encode(manifest); err != nil {
+			return wr.CloseWithError(err)
+		}
+
+		// Parts 3..n: missing files.
+		for _, hash := range missing {
+			p, ok := local[hash]
+			if !ok {
+				return wr.CloseWithError(fmt.Errorf("daemon requested unknown source file: %s", hash))
+			}
+
+			hblob := make(textproto.MIMEHeader)
+			hblob.Set("Content-Type", "application/octet-stream")
+			hblob.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": hash}))
+
+			w, err = mp.CreatePart(hblob)
+			if err != nil {
+				return wr.CloseWithError(err)
+			}
+			if err := copyFile(w, p); err != nil {
+				return wr.CloseWithError(err)
+			}
+		}
+
+		if err := mp.Close(); err != nil {
+			return wr.CloseWithError(err)
+		}
+		return wr.Close()
+	}() //nolint:errcheck
+
+	contentType := "multipart/related; boundary=" + mp.Boundary()
+	return c.request(ctx, "POST", path, rd, "Content-Type", contentType)
+}
+
+// negotiateSources sends the source manifest to the daemon, and returns the
+// hashes of the files it needs to be sent.
+func (c *Client) negotiateSources(ctx context.Context, manifest api.SourceManifest) ([]string, error) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(&api.SourcesRequest{Manifest: manifest})
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.request(ctx, "POST", "/sources", bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var resp api.SourcesResponse
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode sources response: %w", err)
+	}
+	return resp.Missing, nil
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// runBuildArchived sends a multipart request to the daemon on a certain path,
+// uploading the sources as zip archives.
+//
+// A build (or run) request comprises the following parts:
+//
+//   - Part 1 (Content-Type: application/json): the request json, usually composition.
+//   - Part 2 (optional for runs, mandatory for builds, Content-Type: application/zip): test plan source.
+//   - Part 3 (optional, Content-Type: application/zip): linked sdk.
+func (c *Client) runBuildArchived(ctx context.Context, r interface{}, path, plandir, sdkdir string, extraSrcs []string) (io.ReadCloser, error) {
 	var body bytes.Buffer
 	err := json.NewEncoder(&body).Encode(r)
 	if err != nil {
@@ -348,6 +550,69 @@ func (c *Client) Status(ctx context.Context, r *api.StatusRequest) (io.ReadClose
 	return c.request(ctx, "POST", "/status", bytes.NewReader(body.Bytes()))
 }
 
+// Inspect sends an `inspect` request to the daemon.
+func (c *Client) Inspect(ctx context.Context, r *api.InspectRequest) (io.ReadCloser, error) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.request(ctx, "POST", "/inspect", bytes.NewReader(body.Bytes()))
+}
+
+// DryRun sends a `dry-run` request to the daemon, which plans the capacity
+// of the runs of a composition without building or running them.
+func (c *Client) DryRun(ctx context.Context, r *api.DryRunRequest) (io.ReadCloser, error) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.request(ctx, "POST", "/dry-run", bytes.NewReader(body.Bytes()))
+}
+
+// Attach sends an `attach` request to the daemon. The daemon upgrades the
+// connection, which is returned; it carries the streams of the command as
+// frames. See ParseAttachResponse.
+func (c *Client) Attach(ctx context.Context, r *api.AttachRequest) (io.ReadWriteCloser, error) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint+"/attach", &body)
+	if err != nil {
+		return nil, err
+	}
+
+	token := strings.TrimSpace(c.cfg.Client.Token)
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, errors.New("connection was not upgraded")
+	}
+	return rwc, nil
+}
+
 func (c *Client) Cancel(ctx context.Context, r *api.CancelRequest) (io.ReadCloser, error) {
 	var body bytes.Buffer
 	err := json.NewEncoder(&body).Encode(r)
@@ -558,6 +823,48 @@ func ParseStatusResponse(r io.ReadCloser, progress io.Writer) (api.StatusRespons
 	return resp, err
 }
 
+// ParseInspectResponse parses a response from an `inspect` call
+func ParseInspectResponse(r io.ReadCloser, progress io.Writer) (api.InspectResponse, error) {
+	var resp api.InspectResponse
+	err := parseGeneric(
+		r,
+		progress,
+		nil,
+		parseMarshalAndUnmarshal(&resp),
+	)
+	return resp, err
+}
+
+// ParseDryRunResponse parses a response from a 'dry-run' call
+func ParseDryRunResponse(r io.ReadCloser, progress io.Writer) (api.DryRunResponse, error) {
+	var resp api.DryRunResponse
+	err := parseGeneric(
+		r,
+		progress,
+		nil,
+		parseMarshalAndUnmarshal(&resp),
+	)
+	return resp, err
+}
+
+// ParseAttachResponse relays stdin to the command executed by an `attach`
+// call, and its output to stdout and stderr, until it exits. It returns the
+// exit code of the command.
+func ParseAttachResponse(rw io.ReadWriteCloser, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	defer rw.Close()
+
+	fw := rpc.NewFrameWriter(rw)
+	go func() {
+		if stdin != nil {
+			_, _ = io.Copy(fw.Stream(rpc.FrameStdin), stdin)
+		}
+		// an empty frame closes the input of the command.
+		_ = fw.WriteFrame(rpc.FrameStdin, nil)
+	}()
+
+	return rpc.ReadOutputFrames(rw, stdout, stderr)
+}
+
 // ParseLogsRequest parses a response from a 'logs' call
 func ParseLogsRequest(w io.Writer, r io.ReadCloser) (api.LogsResponse, error) {
 	var resp api.LogsResponse
@@ -595,7 +902,8 @@ func (c *Client) request(ctx context.Context, method string, path string, body i
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("unexpected status code received: %s", resp.Status)
+		resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
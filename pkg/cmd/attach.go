@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/client"
+)
+
+var AttachCommand = cli.Command{
+	Name:      "attach",
+	Usage:     "execute a command, a shell by default, in an instance of a run in progress",
+	ArgsUsage: "[-- command...]",
+	Action:    attachCommand,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "task",
+			Aliases:  []string{"t"},
+			Usage:    "the task id of the run",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "group",
+			Aliases:  []string{"g"},
+			Usage:    "the group of the instance",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:    "instance",
+			Aliases: []string{"i"},
+			Usage:   "the index of the instance within its group",
+		},
+		&cli.BoolFlag{
+			Name:  "no-tty",
+			Usage: "do not allocate a terminal, even if stdin is one",
+		},
+	},
+}
+
+func attachCommand(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(ProcessContext())
+	defer cancel()
+
+	cl, _, err := setupClient(c)
+	if err != nil {
+		return err
+	}
+
+	req := &api.AttachRequest{
+		TaskID:   c.String("task"),
+		GroupID:  c.String("group"),
+		Instance: c.Int("instance"),
+		Command:  c.Args().Slice(),
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !c.Bool("no-tty") && term.IsTerminal(fd) {
+		req.TTY = true
+		if w, h, err := term.GetSize(fd); err == nil {
+			req.Width, req.Height = uint(w), uint(h)
+		}
+	}
+
+	rw, err := cl.Attach(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// the terminal of the instance echoes and interprets input; ours must
+	// relay it as is.
+	if req.TTY {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			rw.Close()
+			return err
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+	}
+
+	code, err := client.ParseAttachResponse(rw, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return cli.Exit("", code)
+	}
+	return nil
+}
@@ -7,8 +7,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/mitchellh/mapstructure"
-
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/client"
 	"github.com/testground/testground/pkg/data"
@@ -257,13 +255,12 @@ func build(c *cli.Context, comp *api.Composition) error {
 		return errors.New(tsk.Error)
 	}
 
-	var artifactPaths []string
-	err = mapstructure.Decode(tsk.Result, &artifactPaths)
+	result, err := data.DecodeBuildResult(tsk.Result)
 	if err != nil {
 		return err
 	}
 
-	for i, ap := range artifactPaths {
+	for i, ap := range result.Artifacts {
 		g := comp.Groups[i]
 		logging.S().Infow("generated build artifact", "group", g.ID, "artifact", ap)
 		g.Run.Artifact = ap
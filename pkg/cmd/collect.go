@@ -24,7 +24,7 @@ var CollectCommand = cli.Command{
 		&cli.StringFlag{
 			Name:     "runner",
 			Aliases:  []string{"r"},
-			Usage:    "runner to use; values include: 'local:exec', 'local:docker', 'cluster:k8s'",
+			Usage:    "runner to use; values include: 'local:exec', 'local:docker', 'local:podman', 'cluster:k8s'",
 			Required: true,
 		},
 		&cli.StringFlag{
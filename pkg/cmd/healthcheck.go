@@ -21,7 +21,7 @@ var HealthcheckCommand = cli.Command{
 		},
 		&cli.StringFlag{
 			Name:     "runner",
-			Usage:    "specifies the runner to use; values include: 'local:exec', 'local:docker', 'cluster:k8s'",
+			Usage:    "specifies the runner to use; values include: 'local:exec', 'local:docker', 'local:podman', 'cluster:k8s'",
 			Required: true,
 		},
 	},
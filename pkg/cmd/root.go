@@ -9,6 +9,7 @@ import (
 // RootCommands collects all subcommands of the testground CLI.
 var RootCommands = cli.CommandsByName{
 	&RunCommand,
+	&AttachCommand,
 	&PlanCommand,
 	&BuildCommand,
 	&DescribeCommand,
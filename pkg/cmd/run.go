@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/mitchellh/mapstructure"
@@ -53,6 +54,10 @@ var RunCommand = cli.Command{
 					Name:  "run-ids",
 					Usage: "run a specific run id, or a comma-separated list of run ids",
 				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "check whether the runs fit in the capacity of the runner, without building or running them",
+				},
 				&cli.StringFlag{
 					Name:    ResultFileOpt,
 					Aliases: []string{"O"},
@@ -98,7 +103,7 @@ var RunCommand = cli.Command{
 				&cli.StringFlag{
 					Name:     "runner",
 					Aliases:  []string{"r"},
-					Usage:    "runner to use; values include: 'local:exec', 'local:docker', 'cluster:k8s'",
+					Usage:    "runner to use; values include: 'local:exec', 'local:docker', 'local:podman', 'cluster:k8s'",
 					Required: true,
 				},
 				&cli.StringSliceFlag{
@@ -139,6 +144,12 @@ var RunCommand = cli.Command{
 				},
 			),
 		},
+		&cli.Command{
+			Name:      "inspect",
+			Usage:     "list the instances of a run in progress, and their states",
+			ArgsUsage: "<task>",
+			Action:    runInspectCmd,
+		},
 	},
 }
 
@@ -158,6 +169,10 @@ func runCompositionCmd(c *cli.Context) (err error) {
 		return fmt.Errorf("invalid composition file: %w", err)
 	}
 
+	if c.Bool("dry-run") {
+		return dryRun(c, comp)
+	}
+
 	err = run(c, comp)
 	if err != nil {
 		return err
@@ -190,6 +205,13 @@ func run(c *cli.Context, comp *api.Composition) (err error) {
 		return fmt.Errorf("failed to resolve test plan: %w", err)
 	}
 
+	// Expand parameter sweeps, so that every point of a sweep is a run of
+	// its own.
+	comp, err = comp.ExpandSweeps()
+	if err != nil {
+		return fmt.Errorf("invalid composition: %w", err)
+	}
+
 	// Retrieve the run ids to use.
 	rawRunIds := c.String("run-ids")
 	var runIds []string
@@ -360,10 +382,11 @@ func (m *MultiRunStrategy) Next(ctx context.Context, cl *client.Client, c *cli.C
 	// Add result
 	result := data.DecodeRunnerResult(tsk.Result)
 	m.Results = append(m.Results, MultiRunResult{
-		RunId:  m.CurrentRunId(),
-		TaskId: taskId,
-		Error:  tsk.Error,
-		Result: *result,
+		RunId:       m.CurrentRunId(),
+		TaskId:      taskId,
+		Error:       tsk.Error,
+		Coordinates: m.CurrentCoordinates(),
+		Result:      *result,
 	})
 
 	// Process the composition
@@ -518,9 +541,10 @@ func (m *MultiRunStrategy) Collect(ctx context.Context, cl *client.Client, taskI
 func (m *MultiRunStrategy) CancelEveryOtherRun() {
 	for m.CurrentRunIndex < len(m.RunIds) {
 		m.Results = append(m.Results, MultiRunResult{
-			RunId:  m.CurrentRunId(),
-			TaskId: "N/A",
-			Error:  "canceled",
+			RunId:       m.CurrentRunId(),
+			TaskId:      "N/A",
+			Error:       "canceled",
+			Coordinates: m.CurrentCoordinates(),
 			Result: runner.Result{
 				Outcome: task.OutcomeCanceled,
 			},
@@ -529,9 +553,38 @@ func (m *MultiRunStrategy) CancelEveryOtherRun() {
 	}
 }
 
+// maxShownFailures is the number of failed instances detailed in the output
+// of a run; all of them are in the result of its task.
+const maxShownFailures = 10
+
+// showFailures summarizes the failed instances of a run, if the runner
+// diagnosed them.
+func showFailures(result MultiRunResult) {
+	summary := result.Result.FailureSummary()
+	if summary == "" {
+		return
+	}
+
+	logging.S().Warnf("failures %s[%s]: %s", result.RunId, result.TaskId, summary)
+	for i, f := range result.Result.Failures {
+		if i == maxShownFailures {
+			logging.S().Warnf("  ... and %d more; see `testground status -t %s --extended`", len(result.Result.Failures)-i, result.TaskId)
+			break
+		}
+		logging.S().Warnf("  %s", f)
+		for _, e := range f.Events {
+			logging.S().Warnf("    event: %s", e)
+		}
+		if n := len(f.LastLogs); n > 0 {
+			logging.S().Warnf("    last log: %s", f.LastLogs[n-1])
+		}
+	}
+}
+
 func (m *MultiRunStrategy) ShowResult() error {
 	for _, result := range m.Results {
 		logging.S().Infof("result %s[%s]: %s", result.RunId, result.TaskId, result.Result.Outcome)
+		showFailures(result)
 	}
 
 	// Output the CSV file
@@ -545,13 +598,20 @@ func (m *MultiRunStrategy) ShowResult() error {
 		w := csv.NewWriter(f)
 		defer w.Flush()
 
-		err = w.Write([]string{"run_id", "task_id", "outcome", "error"})
+		// runs expanded from a sweep get a column per swept parameter.
+		params := m.sweptParams()
+
+		err = w.Write(append([]string{"run_id", "task_id", "outcome", "error"}, params...))
 		if err != nil {
 			return err
 		}
 
 		for _, result := range m.Results {
-			err := w.Write([]string{result.RunId, result.TaskId, string(result.Result.Outcome), result.Error})
+			row := []string{result.RunId, result.TaskId, string(result.Result.Outcome), result.Error}
+			for _, p := range params {
+				row = append(row, result.Coordinates[p])
+			}
+			err := w.Write(row)
 
 			if err != nil {
 				return err
@@ -562,6 +622,33 @@ func (m *MultiRunStrategy) ShowResult() error {
 	return nil
 }
 
+// sweptParams returns the test parameters swept across the results, sorted.
+func (m *MultiRunStrategy) sweptParams() []string {
+	seen := make(map[string]bool)
+	params := make([]string, 0)
+	for _, result := range m.Results {
+		for p := range result.Coordinates {
+			if !seen[p] {
+				seen[p] = true
+				params = append(params, p)
+			}
+		}
+	}
+	sort.Strings(params)
+	return params
+}
+
+// CurrentCoordinates returns the sweep coordinates of the current run, if it
+// was expanded from a sweep.
+func (m *MultiRunStrategy) CurrentCoordinates() map[string]string {
+	for _, r := range m.Composition.Runs {
+		if r.ID == m.CurrentRunId() {
+			return r.Coordinates
+		}
+	}
+	return nil
+}
+
 type MultiRunStrategy struct {
 	// Current RunID Index
 	CurrentRunIndex int
@@ -616,6 +703,9 @@ type MultiRunResult struct {
 	// Error
 	Error string
 
+	// Coordinates of the run in a sweep, if any
+	Coordinates map[string]string
+
 	// Result
 	Result runner.Result
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/client"
+)
+
+// dryRun asks the daemon whether the runs of a composition fit in the
+// capacity of its runner, and exits with an error if any doesn't.
+func dryRun(c *cli.Context, comp *api.Composition) error {
+	ctx, cancel := context.WithCancel(ProcessContext())
+	defer cancel()
+
+	cl, cfg, err := setupClient(c)
+	if err != nil {
+		return err
+	}
+
+	_, manifest, err := resolveTestPlan(cfg, comp.Global.Plan)
+	if err != nil {
+		return fmt.Errorf("failed to resolve test plan: %w", err)
+	}
+
+	req := &api.DryRunRequest{
+		Composition: *comp,
+		Manifest:    *manifest,
+	}
+	if raw := c.String("run-ids"); raw != "" {
+		req.RunIds = strings.Split(raw, ",")
+	}
+
+	r, err := cl.DryRun(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	res, err := client.ParseDryRunResponse(r, c.App.Writer)
+	if err != nil {
+		return err
+	}
+
+	fits := true
+	for _, d := range res {
+		printDryRun(d)
+		fits = fits && d.Fits()
+	}
+	if !fits {
+		return cli.Exit(errors.New("composition does not fit in the capacity of the runner"), 1)
+	}
+	return nil
+}
+
+func printDryRun(d *api.DryRun) {
+	fmt.Printf("Run:\t\t%s\n", d.RunID)
+	fmt.Printf("Runner:\t\t%s\n", d.Runner)
+	fmt.Printf("Instances:\t%d\n", d.TotalInstances)
+
+	for _, rep := range d.Capacity {
+		fmt.Printf("\nTarget:\t\t%s\n\n", rep.Target)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tINSTANCES\tCPU/INSTANCE\tMEMORY/INSTANCE")
+		for _, g := range rep.Groups {
+			// instances that are not limited request no resources.
+			cpu, memory := "-", "-"
+			if g.CPU > 0 {
+				cpu = formatCPU(g.CPU)
+			}
+			if g.Memory > 0 {
+				memory = formatMemory(g.Memory)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", g.ID, g.Instances, cpu, memory)
+		}
+		w.Flush()
+
+		fmt.Println()
+		fmt.Printf("Requested:\t%s CPUs, %s memory\n", formatCPU(rep.RequestedCPU), formatMemory(rep.RequestedMemory))
+		fmt.Printf("Available:\t%s CPUs, %s memory\n", formatCPU(rep.AvailableCPU), formatMemory(rep.AvailableMemory))
+		if rep.Fits() {
+			fmt.Println("Fits:\t\tyes")
+		} else {
+			cpu, memory := rep.Shortfall()
+			fmt.Printf("Shortfall:\t%s CPUs, %s memory\n", formatCPU(cpu), formatMemory(memory))
+		}
+		for _, n := range rep.Notes {
+			fmt.Printf("Note:\t\t%s\n", n)
+		}
+	}
+	fmt.Println()
+}
+
+// formatCPU formats a number of CPUs, which is unknown if negative.
+func formatCPU(cpu float64) string {
+	if cpu < 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.2f", cpu)
+}
+
+// formatMemory formats an amount of memory, which is unknown if negative.
+func formatMemory(memory int64) string {
+	if memory < 0 {
+		return "unknown"
+	}
+	return humanize.IBytes(uint64(memory))
+}
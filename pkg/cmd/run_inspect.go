@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/client"
+)
+
+func runInspectCmd(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(ProcessContext())
+	defer cancel()
+
+	if c.NArg() != 1 {
+		return errors.New("missing task id")
+	}
+
+	cl, _, err := setupClient(c)
+	if err != nil {
+		return err
+	}
+
+	r, err := cl.Inspect(ctx, &api.InspectRequest{TaskID: c.Args().First()})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	res, err := client.ParseInspectResponse(r, c.App.Writer)
+	if err != nil {
+		return err
+	}
+
+	printRunInspection(&res)
+	return nil
+}
+
+func printRunInspection(res *api.RunInspection) {
+	fmt.Printf("Run:\t\t%s\n", res.RunID)
+	fmt.Printf("Runner:\t\t%s\n\n", res.Runner)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "GROUP\tINDEX\tID\tSTATE\tEXIT CODE\tRESTARTS\tPORTS")
+	for _, in := range res.Instances {
+		exit := "-"
+		if in.State == api.InstanceStateExited {
+			exit = fmt.Sprint(in.ExitCode)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%s\n", in.GroupID, in.Index, in.ID, in.State, exit, in.Restarts, strings.Join(in.Ports, ", "))
+	}
+	w.Flush()
+
+	groups := make([]string, 0, len(res.Events))
+	for g := range res.Events {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	fmt.Println()
+	fmt.Fprintln(w, "GROUP\tLAST EVENT\tSTART\tSUCCESS\tFAILURE\tCRASH")
+	for _, g := range groups {
+		e := res.Events[g]
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", g, e.Last, e.Start, e.Success, e.Failure, e.Crash)
+	}
+	w.Flush()
+}
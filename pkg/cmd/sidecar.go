@@ -47,6 +47,7 @@ func sidecarCommand(c *cli.Context) error {
 
 func startHTTPServer() {
 	logging.S().Info("starting http server")
+	sidecar.RegisterHealthHandlers(http.DefaultServeMux)
 	go func() {
 		_ = http.ListenAndServe(":6060", nil)
 	}()
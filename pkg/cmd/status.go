@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/client"
@@ -54,6 +55,18 @@ func statusCommand(c *cli.Context) error {
 
 	printTask(res)
 
+	if res.Type == task.TypeBuild && res.State().State == task.StateComplete {
+		if err := printBuildResult(res); err != nil {
+			return err
+		}
+	}
+
+	if res.Type == task.TypeRun && res.State().State == task.StateComplete {
+		if summary := data.DecodeRunnerResult(res.Result).FailureSummary(); summary != "" {
+			fmt.Printf("Failures:\t%s\n", summary)
+		}
+	}
+
 	if c.Bool("extended") {
 		fmt.Printf("\nInput:\n")
 		input, err := json.Marshal(res.Input)
@@ -89,3 +102,39 @@ func printTask(tsk task.Task) {
 	fmt.Printf("Outcome:\t%s\n", outcomeStr)
 	fmt.Printf("Last update:\t%s\n", tsk.State().Created)
 }
+
+func printBuildResult(tsk task.Task) error {
+	result, err := data.DecodeBuildResult(tsk.Result)
+	if err != nil {
+		return fmt.Errorf("failed to decode build result: %w", err)
+	}
+
+	for _, a := range result.Artifacts {
+		fmt.Printf("Artifact:\t%s\n", a)
+	}
+
+	for _, p := range result.Provenance {
+		fmt.Printf("\nProvenance:\n")
+		fmt.Printf("  Builder:\t%s\n", p.BuilderID)
+		fmt.Printf("  Groups:\t%s\n", strings.Join(p.Groups, ", "))
+		fmt.Printf("  Artifact:\t%s\n", p.ArtifactPath)
+		fmt.Printf("  Source hash:\t%s\n", p.SourceHash)
+		if len(p.Selectors) > 0 {
+			fmt.Printf("  Selectors:\t%s\n", strings.Join(p.Selectors, ", "))
+		}
+		for mod, target := range p.DependencyOverrides {
+			fmt.Printf("  Override:\t%s => %s@%s\n", mod, target.Target, target.Version)
+		}
+		for _, img := range p.BaseImages {
+			fmt.Printf("  Base image:\t%s\n", img)
+		}
+		fmt.Printf("  Started:\t%s\n", p.StartedAt)
+		fmt.Printf("  Duration:\t%s\n", p.Duration)
+		fmt.Printf("  Dependencies:\t%d resolved\n", len(p.ResolvedDependencies))
+		if p.SBOM != nil {
+			fmt.Printf("  SBOM:\t\t%s (use --extended to print)\n", p.SBOM.Format)
+		}
+	}
+
+	return nil
+}
@@ -106,6 +106,9 @@ func loadComposition(path string) (*api.Composition, error) {
 
 	comp = comp.GenerateDefaultRun()
 
+	// Expand build matrices client-side, so that the groups to build and
+	// the artifacts written back refer to the expanded groups.
+	comp, err = comp.ExpandMatrix()
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare composition: %w", err)
 	}
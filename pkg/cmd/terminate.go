@@ -17,7 +17,7 @@ var TerminateCommand = cli.Command{
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "runner",
-			Usage: "runner to terminate; values include: 'local:exec', 'local:docker', 'cluster:k8s'",
+			Usage: "runner to terminate; values include: 'local:exec', 'local:docker', 'local:podman', 'cluster:k8s'",
 		},
 		&cli.StringFlag{
 			Name:  "builder",
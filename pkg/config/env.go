@@ -13,16 +13,44 @@ type EnvConfig struct {
 
 	AWS       AWSConfig            `toml:"aws"`
 	DockerHub DockerHubConfig      `toml:"dockerhub"`
+	Registry  RegistryConfig       `toml:"registry"`
+	K8s       K8sConfig            `toml:"k8s"`
 	Builders  map[string]ConfigMap `toml:"builders"`
 	Runners   map[string]ConfigMap `toml:"runners"`
 	Daemon    DaemonConfig         `toml:"daemon"`
 	Client    ClientConfig         `toml:"client"`
+	Plugins   PluginsConfig        `toml:"plugins"`
 }
 
 func (e EnvConfig) Dirs() Directories {
 	return e.dirs
 }
 
+// WithHome returns a copy of this configuration rooted at the supplied home
+// directory. It is used by processes that receive the configuration over the
+// wire, such as plugins, as the directories are not serialized.
+func (e EnvConfig) WithHome(home string) EnvConfig {
+	e.dirs = Directories{home}
+	return e
+}
+
+// WithoutCredentials returns a copy of this configuration stripped of the
+// credentials and tokens it holds, for handing to processes that don't need
+// them. With keepRegistry, the AWS and DockerHub credentials, which builders
+// and runners may need to push and pull images, are kept.
+func (e EnvConfig) WithoutCredentials(keepRegistry bool) EnvConfig {
+	if !keepRegistry {
+		e.AWS.AccessKeyID = ""
+		e.AWS.SecretAccessKey = ""
+		e.DockerHub.AccessToken = ""
+	}
+	e.Daemon.Tokens = nil
+	e.Daemon.SlackWebhookURL = ""
+	e.Daemon.GithubRepoStatusToken = ""
+	e.Client.Token = ""
+	return e
+}
+
 type AWSConfig struct {
 	AccessKeyID     string `toml:"access_key_id"`
 	SecretAccessKey string `toml:"secret_access_key"`
@@ -35,6 +63,58 @@ type DockerHubConfig struct {
 	AccessToken string `toml:"access_token"`
 }
 
+// RegistryConfig configures a generic OCI registry, such as Harbor or
+// registry:2, to push images to.
+type RegistryConfig struct {
+	// URL is the address of the registry, e.g. "harbor.example.com" or
+	// "localhost:5000". The scheme is https, unless stated otherwise.
+	URL string `toml:"url"`
+	// Repository is the prefix of the repositories images are pushed to, e.g.
+	// the Harbor project.
+	Repository string `toml:"repository"`
+	// DockerConfig is the docker config.json credentials are taken from,
+	// inline or through credential helpers (default: that of the docker CLI).
+	DockerConfig string `toml:"docker_config"`
+	// Insecure allows plain http when the URL has no scheme, and skips the
+	// verification of certificates.
+	Insecure bool `toml:"insecure"`
+	// CAFile is a PEM file of the CAs to verify the registry with.
+	CAFile string `toml:"ca_file"`
+}
+
+// K8sConfig is the allowlist of the Kubernetes clusters and namespaces that
+// compositions may deploy cluster:k8s runs to, through the context and
+// namespace run config.
+type K8sConfig struct {
+	// DefaultContext is the context of runs that don't select one (default:
+	// the current context of the kubeconfig).
+	DefaultContext string `toml:"default_context"`
+	// Contexts are the allowed kubeconfig contexts, by name. Without any,
+	// runs are bound to the current context and the "default" namespace.
+	Contexts map[string]K8sContextConfig `toml:"contexts"`
+}
+
+// K8sContextConfig is a Kubernetes cluster runs may be deployed to.
+type K8sContextConfig struct {
+	// Namespaces are the namespaces runs may be deployed to, the first one
+	// being the default (default: "default").
+	Namespaces []string `toml:"namespaces"`
+	// Kubeconfig is the kubeconfig the context is defined in (default:
+	// ~/.kube/config).
+	Kubeconfig string `toml:"kubeconfig"`
+
+	// SyncServiceHost, RedisHost and InfluxDBURL are the endpoints of the
+	// shared services, as reached from the cluster (default: the services
+	// of the infra namespace of the daemon, by their cluster-local names).
+	//
+	// The daemon collects the outcomes of every run from its own sync
+	// service, the one of the infra namespace of the default context. Other
+	// contexts must declare a SyncServiceHost reaching that same service.
+	SyncServiceHost string `toml:"sync_service_host"`
+	RedisHost       string `toml:"redis_host"`
+	InfluxDBURL     string `toml:"influxdb_url"`
+}
+
 type DaemonConfig struct {
 	Listen                string          `toml:"listen"`
 	Scheduler             SchedulerConfig `toml:"scheduler"`
@@ -58,6 +138,44 @@ type ClientConfig struct {
 	User     string `toml:"user"`
 }
 
+// PluginsConfig declares external builders and runners, keyed by the id
+// they are registered under.
+type PluginsConfig struct {
+	Builders map[string]PluginConfig `toml:"builders"`
+	Runners  map[string]PluginConfig `toml:"runners"`
+}
+
+// PluginConfig declares how to reach an external plugin. Exactly one of Exec
+// and Endpoint must be set.
+type PluginConfig struct {
+	// Exec is the path to an executable implementing the plugin protocol. It
+	// is invoked once per call, with the method appended to Args.
+	Exec string   `toml:"exec"`
+	Args []string `toml:"args"`
+
+	// Endpoint is the host:port address of a gRPC server implementing the
+	// plugin protocol.
+	Endpoint string `toml:"endpoint"`
+
+	// CAFile is a PEM file of the CAs to verify the gRPC server with
+	// (default: the CAs of the system). CertFile and KeyFile are the client
+	// certificate and key the daemon presents to the server, if it requires
+	// one.
+	CAFile   string `toml:"ca_file"`
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
+
+	// Insecure calls the gRPC server over plaintext. Servers on the loopback
+	// interface are called over plaintext unless TLS is configured; all
+	// others require TLS unless this is set.
+	Insecure bool `toml:"insecure"`
+
+	// ForwardCredentials passes the AWS and DockerHub credentials of the env
+	// configuration on to the plugin, which otherwise receives it without
+	// any credentials.
+	ForwardCredentials bool `toml:"forward_credentials"`
+}
+
 // Common config flags kept here to avoid magic strings
 
 // Indicates whether a runner is disabled
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/data"
+	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/task"
+)
+
+// getArtifactsHandler returns the artifacts of a build task, along with the
+// provenance documents recorded for them, as JSON.
+func (d *Daemon) getArtifactsHandler(engine api.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.S().With("req_id", r.Header.Get("X-Request-ID"))
+
+		log.Debugw("handle request", "command", "get artifacts")
+		defer log.Debugw("request handled", "command", "get artifacts")
+
+		id := mux.Vars(r)["id"]
+
+		tsk, err := engine.GetTask(id)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "cannot fetch task %s: %s", id, err)
+			return
+		}
+
+		if tsk.Type != task.TypeBuild {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "task %s is not a build task", id)
+			return
+		}
+
+		if tsk.State().State != task.StateComplete {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, "task %s has not completed yet", id)
+			return
+		}
+
+		result, err := data.DecodeBuildResult(tsk.Result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "cannot decode build result: %s", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			log.Warnw("failed to write artifacts", "err", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// attachHandler executes a command in an instance of a run in progress. The
+// connection is upgraded, like docker does for exec sessions, and the streams
+// of the command are then exchanged as frames (see rpc.FrameType).
+func (d *Daemon) attachHandler(engine api.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.S().With("req_id", r.Header.Get("X-Request-ID"))
+
+		log.Debugw("handle request", "command", "attach")
+		defer log.Debugw("request handled", "command", "attach")
+
+		if r.Header.Get("Upgrade") != "tcp" {
+			http.Error(w, "attach requires a connection upgrade", http.StatusUpgradeRequired)
+			return
+		}
+
+		var req api.AttachRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("attach json decode: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "connection can't be upgraded", http.StatusInternalServerError)
+			return
+		}
+		conn, brw, err := hj.Hijack()
+		if err != nil {
+			log.Warnw("failed to hijack connection", "err", err)
+			return
+		}
+		defer conn.Close()
+
+		// the deadlines of the server no longer apply to a hijacked
+		// connection; sessions last as long as the command.
+		_ = conn.SetDeadline(time.Time{})
+
+		_, err = brw.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		if err == nil {
+			err = brw.Flush()
+		}
+		if err != nil {
+			log.Warnw("failed to upgrade connection", "err", err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(engine.Context())
+		defer cancel()
+
+		stdin, stdinW := io.Pipe()
+		go func() {
+			// copyInputFrames only returns once the client is gone.
+			_ = copyInputFrames(brw.Reader, stdinW)
+			cancel()
+		}()
+
+		fw := rpc.NewFrameWriter(conn)
+		opts := &api.AttachOptions{
+			GroupID:  req.GroupID,
+			Instance: req.Instance,
+			Command:  req.Command,
+			TTY:      req.TTY,
+			Width:    req.Width,
+			Height:   req.Height,
+			Stdin:    stdin,
+			Stdout:   fw.Stream(rpc.FrameStdout),
+			Stderr:   fw.Stream(rpc.FrameStderr),
+		}
+
+		code, err := engine.DoAttach(ctx, req.TaskID, opts, rpc.NewStdoutWriter())
+		_ = stdin.Close()
+		if err != nil {
+			log.Warnw("attach session failed", "task_id", req.TaskID, "err", err)
+			_ = fw.WriteFrame(rpc.FrameError, []byte(err.Error()))
+			return
+		}
+		_ = fw.WriteExit(code)
+	}
+}
+
+// copyInputFrames writes the stdin frames read from r to w, and closes w
+// once the client closes the input. It keeps reading until the connection
+// fails, which is reported as an error, and closes w with it.
+func copyInputFrames(r io.Reader, w *io.PipeWriter) error {
+	for {
+		t, p, err := rpc.ReadFrame(r)
+		if err != nil {
+			_ = w.CloseWithError(err)
+			return err
+		}
+		if t != rpc.FrameStdin {
+			continue
+		}
+		if len(p) == 0 {
+			_ = w.Close()
+			continue
+		}
+		// writes fail once the command no longer reads its input, which is
+		// of no concern to the client.
+		_, _ = w.Write(p)
+	}
+}
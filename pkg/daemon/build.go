@@ -16,6 +16,7 @@ import (
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/logging"
 	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/sources"
 )
 
 func (d *Daemon) buildHandler(engine api.Engine) func(w http.ResponseWriter, r *http.Request) {
@@ -36,7 +37,7 @@ func (d *Daemon) buildHandler(engine api.Engine) func(w http.ResponseWriter, r *
 		}
 
 		var request *api.BuildRequest
-		sources, err := consumeRunBuildRequest(r, &request, dir)
+		sources, err := consumeRunBuildRequest(r, &request, dir, d.sources)
 		if err != nil {
 			tgw.WriteError("failed to consume request", "err", err)
 			return
@@ -84,7 +85,15 @@ func (d *Daemon) buildPurgeHandler(engine api.Engine) func(w http.ResponseWriter
 	}
 }
 
-func consumeRunBuildRequest(r *http.Request, body interface{}, dir string) (*api.UnpackedSources, error) {
+// consumeRunBuildRequest reads a multipart build or run request, decoding the
+// request payload into body and unpacking any sources under dir.
+//
+// Sources can be sent either as zip archives (plan.zip, sdk.zip, extra.zip),
+// or incrementally: as a manifest.json part describing the source trees,
+// followed by one part per file missing from the content store, named after
+// its content hash. In the latter case, the source trees are reconstructed
+// from the store.
+func consumeRunBuildRequest(r *http.Request, body interface{}, dir string, store *sources.Store) (*api.UnpackedSources, error) {
 	var (
 		p   *multipart.Part
 		err error
@@ -117,7 +126,10 @@ func consumeRunBuildRequest(r *http.Request, body interface{}, dir string) (*api
 		return nil, fmt.Errorf("failed to json decode request body: %w", err)
 	}
 
-	var unpacked *api.UnpackedSources
+	var (
+		unpacked *api.UnpackedSources
+		manifest api.SourceManifest
+	)
 
 Outer:
 	for {
@@ -126,15 +138,32 @@ Outer:
 			// we're done.
 			break Outer
 		case nil:
+			filename := p.FileName()
+
+			switch {
+			case filename == "manifest.json":
+				if err := json.NewDecoder(p).Decode(&manifest); err != nil {
+					return nil, fmt.Errorf("failed to json decode source manifest: %w", err)
+				}
+				continue
+			case p.Header.Get("Content-Type") == "application/octet-stream":
+				// a source file missing from the content store, named after its hash.
+				if store == nil {
+					return nil, fmt.Errorf("incremental sources not supported")
+				}
+				if err := store.Put(filename, p); err != nil {
+					return nil, fmt.Errorf("failed to store source file: %w", err)
+				}
+				continue
+			}
+
 			if unpacked == nil {
 				unpacked = new(api.UnpackedSources)
 				unpacked.BaseDir = dir
 			}
 
-			var (
-				filename = p.FileName() // can be sdk.zip or extra.zip
-				kind     = strings.TrimSuffix(filename, ".zip")
-			)
+			// can be plan.zip, sdk.zip or extra.zip
+			kind := strings.TrimSuffix(filename, ".zip")
 
 			// Read the archive.
 			targetzip, err := os.Create(filepath.Join(dir, filename))
@@ -157,11 +186,11 @@ Outer:
 
 			// Set the right directory.
 			switch kind {
-			case "sdk":
+			case api.SourceKindSDK:
 				unpacked.SDKDir = destdir
-			case "extra":
+			case api.SourceKindExtra:
 				unpacked.ExtraDir = destdir
-			case "plan":
+			case api.SourceKindPlan:
 				unpacked.PlanDir = destdir
 			}
 		default:
@@ -170,5 +199,27 @@ Outer:
 		}
 	}
 
-	return unpacked, nil
+	if manifest == nil {
+		return unpacked, nil
+	}
+
+	if unpacked != nil {
+		return nil, fmt.Errorf("cannot combine archived and incremental sources")
+	}
+
+	if store == nil {
+		return nil, fmt.Errorf("incremental sources not supported")
+	}
+
+	// every file in the manifest must now be present in the store.
+	missing, err := store.Missing(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%d source files missing from upload", len(missing))
+	}
+
+	logging.S().Infof("materializing sources from content store to %s", dir)
+	return store.Materialize(manifest, dir)
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -12,16 +13,18 @@ import (
 	"github.com/testground/testground/pkg/engine"
 	"github.com/testground/testground/pkg/logging"
 	"github.com/testground/testground/pkg/metrics"
+	"github.com/testground/testground/pkg/sources"
 
 	"github.com/gorilla/mux"
 	"github.com/pborman/uuid"
 )
 
 type Daemon struct {
-	server *http.Server
-	l      net.Listener
-	mv     *metrics.Viewer
-	doneCh chan struct{}
+	server  *http.Server
+	l       net.Listener
+	mv      *metrics.Viewer
+	sources *sources.Store
+	doneCh  chan struct{}
 }
 
 // New creates a new Daemon and attaches the following handlers:
@@ -29,7 +32,9 @@ type Daemon struct {
 // * GET /list: sends a `list` request to the daemon. list all test plans and test cases.
 // * GET /describe: sends a `describe` request to the daemon. describes a test plan or test case.
 // * POST /build: sends a `build` request to the daemon. builds a test plan.
+// * GET /artifacts/<id>: returns the artifacts and provenance of a build task.
 // * POST /run: sends a `run` request to the daemon. (builds and) runs test case with name `<testplan>/<testcase>`.
+// * POST /sources: negotiates which source files need to be uploaded along with a build or run.
 // A type-safe client for this server can be found in the `pkg/client` package.
 func New(cfg *config.EnvConfig) (srv *Daemon, err error) {
 	srv = new(Daemon)
@@ -44,6 +49,11 @@ func New(cfg *config.EnvConfig) (srv *Daemon, err error) {
 		return nil, err
 	}
 
+	srv.sources, err = sources.NewStore(filepath.Join(cfg.Dirs().Work(), "sources"))
+	if err != nil {
+		return nil, err
+	}
+
 	r := mux.NewRouter().StrictSlash(true)
 
 	if len(cfg.Daemon.Tokens) > 0 {
@@ -88,16 +98,21 @@ func New(cfg *config.EnvConfig) (srv *Daemon, err error) {
 	r.HandleFunc("/logs", srv.getLogsHandler(engine)).Methods("GET")
 	r.HandleFunc("/outputs", srv.getOutputsHandler(engine)).Methods("GET")
 	r.HandleFunc("/journal", srv.getJournalHandler(engine)).Methods("GET")
+	r.HandleFunc("/artifacts/{id}", srv.getArtifactsHandler(engine)).Methods("GET")
 	r.HandleFunc("/", srv.redirect()).Methods("GET")
 
 	r.HandleFunc("/build", srv.buildHandler(engine)).Methods("POST")
 	r.HandleFunc("/build/purge", srv.buildPurgeHandler(engine)).Methods("POST")
 	r.HandleFunc("/run", srv.runHandler(engine)).Methods("POST")
+	r.HandleFunc("/dry-run", srv.dryRunHandler(engine)).Methods("POST")
+	r.HandleFunc("/sources", srv.sourcesHandler(engine)).Methods("POST")
 	r.HandleFunc("/outputs", srv.outputsHandler(engine)).Methods("POST")
 	r.HandleFunc("/terminate", srv.terminateHandler(engine)).Methods("POST")
 	r.HandleFunc("/healthcheck", srv.healthcheckHandler(engine)).Methods("POST")
 	r.HandleFunc("/tasks", srv.tasksHandler(engine)).Methods("POST")
 	r.HandleFunc("/status", srv.statusHandler(engine)).Methods("POST")
+	r.HandleFunc("/inspect", srv.inspectHandler(engine)).Methods("POST")
+	r.HandleFunc("/attach", srv.attachHandler(engine)).Methods("POST")
 	r.HandleFunc("/logs", srv.logsHandler(engine)).Methods("POST")
 
 	srv.doneCh = make(chan struct{})
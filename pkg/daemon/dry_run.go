@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+func (d *Daemon) dryRunHandler(engine api.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.S().With("req_id", r.Header.Get("X-Request-ID"))
+
+		log.Debugw("handle request", "command", "dry-run")
+		defer log.Debugw("request handled", "command", "dry-run")
+
+		tgw := rpc.NewOutputWriter(w, r)
+
+		var req api.DryRunRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			tgw.WriteError("dry-run json decode", "err", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		out, err := engine.DoDryRun(r.Context(), &req, tgw)
+		if err != nil {
+			tgw.WriteError("dry-run error", "err", err.Error())
+			return
+		}
+
+		tgw.WriteResult(out)
+	}
+}
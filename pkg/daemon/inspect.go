@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+func (d *Daemon) inspectHandler(engine api.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.S().With("req_id", r.Header.Get("X-Request-ID"))
+
+		log.Debugw("handle request", "command", "inspect")
+		defer log.Debugw("request handled", "command", "inspect")
+
+		tgw := rpc.NewOutputWriter(w, r)
+
+		var req api.InspectRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			tgw.WriteError("inspect json decode", "err", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		out, err := engine.DoInspectRun(r.Context(), req.TaskID, tgw)
+		if err != nil {
+			tgw.WriteError("inspect error", "err", err.Error())
+			return
+		}
+
+		tgw.WriteResult(out)
+	}
+}
@@ -3,6 +3,8 @@ package daemon
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/data"
@@ -36,7 +38,7 @@ func (d *Daemon) getJournalHandler(engine api.Engine) func(w http.ResponseWriter
 			return
 		}
 
-		if len(result.Journal.Events) == 0 && len(result.Journal.PodsStatuses) == 0 {
+		if len(result.Journal.Events) == 0 && len(result.Journal.PodsStatuses) == 0 && len(result.Journal.Starts) == 0 {
 			_, _ = w.Write([]byte("No events or statuses captured for this run.\n"))
 			return
 		}
@@ -58,5 +60,20 @@ func (d *Daemon) getJournalHandler(engine api.Engine) func(w http.ResponseWriter
 			_, _ = w.Write([]byte(k))
 			_, _ = w.Write([]byte("\n"))
 		}
+
+		if len(result.Journal.Starts) > 0 {
+			_, _ = w.Write([]byte("Starts\n"))
+			_, _ = w.Write([]byte("=================\n"))
+		}
+		tags := make([]string, 0, len(result.Journal.Starts))
+		for tag := range result.Journal.Starts {
+			tags = append(tags, tag)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			return result.Journal.Starts[tags[i]].Before(result.Journal.Starts[tags[j]])
+		})
+		for _, tag := range tags {
+			fmt.Fprintf(w, "%s\t%s\n", result.Journal.Starts[tag].Format(time.RFC3339Nano), tag)
+		}
 	}
 }
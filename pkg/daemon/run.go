@@ -30,7 +30,7 @@ func (d *Daemon) runHandler(engine api.Engine) func(w http.ResponseWriter, r *ht
 		}
 
 		var request *api.RunRequest
-		sources, err := consumeRunBuildRequest(r, &request, dir)
+		sources, err := consumeRunBuildRequest(r, &request, dir, d.sources)
 		if err != nil {
 			tgw.WriteError("failed to consume request", "err", err)
 			return
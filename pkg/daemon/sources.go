@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/logging"
+)
+
+// sourcesHandler replies with the hashes of the files in the request manifest
+// that are missing from the daemon's content store. Unlike most endpoints,
+// the response is a single JSON document rather than a stream of chunks, as
+// it's consumed by the client before the actual build or run request.
+func (d *Daemon) sourcesHandler(engine api.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.S().With("req_id", r.Header.Get("X-Request-ID"))
+
+		log.Debugw("handle request", "command", "sources")
+		defer log.Debugw("request handled", "command", "sources")
+
+		var req api.SourcesRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, "sources json decode: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		missing, err := d.sources.Missing(req.Manifest)
+		if err != nil {
+			http.Error(w, "sources error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Debugw("negotiated sources", "missing", len(missing))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(api.SourcesResponse{Missing: missing}); err != nil {
+			log.Warnw("failed to write sources response", "err", err)
+		}
+	}
+}
@@ -6,9 +6,12 @@
 package data
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/logging"
 	"github.com/testground/testground/pkg/runner"
 	"github.com/testground/testground/pkg/task"
@@ -18,13 +21,45 @@ func DecodeRunnerResult(result interface{}) *runner.Result {
 	r := &runner.Result{
 		Outcome: task.OutcomeSuccess,
 	}
-	err := mapstructure.Decode(result, r)
+	// results read back from the task store carry times as strings.
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
+		Result:     r,
+	})
+	if err == nil {
+		err = dec.Decode(result)
+	}
 	if err != nil {
 		logging.S().Errorw("error while decoding result", "err", err)
 	}
 	return r
 }
 
+// DecodeBuildResult decodes the result of a build task. Tasks persisted by older
+// daemons carry a plain list of artifacts, without provenance.
+func DecodeBuildResult(result interface{}) (*api.BuildResult, error) {
+	r := &api.BuildResult{}
+
+	switch v := result.(type) {
+	case nil:
+		return r, nil
+	case *api.BuildResult:
+		return v, nil
+	case []interface{}, []string:
+		err := mapstructure.Decode(v, &r.Artifacts)
+		return r, err
+	}
+
+	// Round-trip through JSON, as mapstructure cannot decode time.Time
+	// values nor honour json field names.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, r)
+	return r, err
+}
+
 func DecodeTaskOutcome(t *task.Task) (task.Outcome, error) {
 	switch t.State().State {
 	case task.StateCanceled:
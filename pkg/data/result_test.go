@@ -1,6 +1,7 @@
 package data
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -35,6 +36,51 @@ func TestDecodeResult(t *testing.T) {
 	assert.NotNil(t, r2)
 }
 
+func TestDecodeResultStartTimes(t *testing.T) {
+	started := time.Date(2022, 6, 1, 10, 0, 0, 5, time.UTC)
+	result := &runner.Result{
+		Outcome: task.OutcomeSuccess,
+		Journal: &runner.Journal{
+			Starts: map[string]time.Time{"single[000]": started},
+		},
+	}
+
+	// results are read back from the task store as generic maps.
+	b, err := json.Marshal(result)
+	assert.NoError(t, err)
+	var stored map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &stored))
+
+	r := DecodeRunnerResult(stored)
+	if assert.NotNil(t, r.Journal) {
+		assert.True(t, started.Equal(r.Journal.Starts["single[000]"]))
+	}
+}
+
+func TestDecodeResultFailures(t *testing.T) {
+	result := &runner.Result{
+		Outcome: task.OutcomeFailure,
+		Failures: []*runner.InstanceFailure{{
+			GroupID:  "miners",
+			Index:    3,
+			ID:       "tg-plan-run-miners-3",
+			Reason:   runner.FailureOOMKilled,
+			ExitCode: 137,
+			Events:   []string{"BackOff: Back-off restarting failed container"},
+			LastLogs: []string{"allocating 2GiB"},
+		}},
+	}
+
+	b, err := json.Marshal(result)
+	assert.NoError(t, err)
+	var stored map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &stored))
+
+	r := DecodeRunnerResult(stored)
+	assert.Equal(t, result.Failures, r.Failures)
+	assert.Equal(t, "oom_killed: 1 (miners: 1)", r.FailureSummary())
+}
+
 func TestDecodeTaskOutcomeWithGenericRunerAndUnknownOutcome(t *testing.T) {
 	tested := &task.Task{
 		Type:   task.TypeRun,
@@ -107,11 +153,11 @@ func TestDecodeTaskOutcomeWithCanceledState(t *testing.T) {
 }
 
 func TestDecodeTaskOutcomeWithLocalExecRunner(t *testing.T) {
-	// Run with local exec runner => the result is nil, we assume outcome is Success if the task suceeded.
+	// Runs of older local exec runners have a nil result, we assume outcome is Success if the task suceeded.
 	tested := &task.Task{
 		Type:   task.TypeRun,
 		States: successState(),
-		// runner outputs something like: `&api.RunOutput{RunID: input.RunID}`
+		// runner used to output something like: `&api.RunOutput{RunID: input.RunID}`
 		Result: nil,
 	}
 
@@ -119,3 +165,32 @@ func TestDecodeTaskOutcomeWithLocalExecRunner(t *testing.T) {
 	assert.Equal(t, task.OutcomeSuccess, r)
 	assert.Nil(t, e)
 }
+
+func TestDecodeBuildResult(t *testing.T) {
+	// legacy results only carry the artifacts.
+	legacy, err := DecodeBuildResult([]interface{}{"artifact1", "artifact2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"artifact1", "artifact2"}, legacy.Artifacts)
+	assert.Empty(t, legacy.Provenance)
+
+	// results read back from the task storage are generic maps.
+	stored := map[string]interface{}{
+		"artifacts": []interface{}{"artifact1"},
+		"provenance": []interface{}{
+			map[string]interface{}{
+				"build_id":    "abc",
+				"builder_id":  "docker:go",
+				"source_hash": "sha256:1234",
+				"started_at":  "2021-01-01T00:00:00Z",
+				"duration":    float64(time.Second),
+			},
+		},
+	}
+	res, err := DecodeBuildResult(stored)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"artifact1"}, res.Artifacts)
+	assert.Len(t, res.Provenance, 1)
+	assert.Equal(t, "docker:go", res.Provenance[0].BuilderID)
+	assert.Equal(t, "sha256:1234", res.Provenance[0].SourceHash)
+	assert.Equal(t, time.Second, res.Provenance[0].Duration)
+}
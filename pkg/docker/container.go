@@ -164,29 +164,39 @@ func EnsureContainerStarted(ctx context.Context, ow *rpc.OutputWriter, cli *clie
 // DeleteContainers deletes a set of containers in parallel, using a ratelimit
 // of 16 concurrent delete requests. If a deletion fails, it does not
 // short-circuit. Instead, it accumulates errors and returns an multierror.
-func DeleteContainers(cli *client.Client, ow *rpc.OutputWriter, ids []string) (err error) {
+// Containers that no longer exist are considered deleted.
+func DeleteContainers(ctx context.Context, cli *client.Client, ow *rpc.OutputWriter, ids []string) (err error) {
 	ow.Infow("deleting containers", "ids", ids)
 
 	ratelimit := make(chan struct{}, 16)
 
-	errs := make(chan error)
+	// buffered, so that deletions in flight don't block if we return early.
+	errs := make(chan error, len(ids))
 	for _, id := range ids {
 		go func(id string) {
 			ratelimit <- struct{}{}
 			defer func() { <-ratelimit }()
 
 			ow.Infow("deleting container", "id", id)
-			errs <- cli.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true})
+			err := cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
+			if client.IsErrNotFound(err) {
+				err = nil
+			}
+			errs <- err
 		}(id)
 	}
 
 	var merr *multierror.Error
 	for i := 0; i < len(ids); i++ {
-		if err := <-errs; err != nil {
-			ow.Errorw("failed while deleting container", "error", err)
-			merr = multierror.Append(merr, <-errs)
+		select {
+		case err := <-errs:
+			if err != nil {
+				ow.Errorw("failed while deleting container", "error", err)
+				merr = multierror.Append(merr, err)
+			}
+		case <-ctx.Done():
+			return multierror.Append(merr, ctx.Err()).ErrorOrNil()
 		}
 	}
-	close(errs)
 	return merr.ErrorOrNil()
 }
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+const (
+	// EngineDocker is the docker engine of the environment, as configured by
+	// the DOCKER_* environment variables.
+	EngineDocker = "docker"
+
+	// EnginePodman is the podman service of the user, driven through its
+	// docker-compatible API.
+	EnginePodman = "podman"
+)
+
+// NewClient returns a client of a docker-compatible engine, EngineDocker or
+// EnginePodman. The empty engine is EngineDocker.
+func NewClient(engine string) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv}
+
+	switch engine {
+	case "", EngineDocker:
+	case EnginePodman:
+		host, err := PodmanHost()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHost(host))
+	default:
+		return nil, fmt.Errorf("unknown container engine: %s", engine)
+	}
+
+	opts = append(opts, client.WithAPIVersionNegotiation())
+	return client.NewClientWithOpts(opts...)
+}
+
+// PodmanHost returns the address of the API socket of podman: CONTAINER_HOST
+// if set, like the podman CLI does, or else the socket of the rootless service
+// of the user, or else the socket of the system service.
+func PodmanHost() (string, error) {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host, nil
+	}
+
+	var sockets []string
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		sockets = append(sockets, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	sockets = append(sockets,
+		fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid()),
+		"/run/podman/podman.sock",
+	)
+
+	for _, s := range sockets {
+		if _, err := os.Stat(s); err == nil {
+			return "unix://" + s, nil
+		}
+	}
+	return "", fmt.Errorf("podman API socket not found in %s; start it with `systemctl --user start podman.socket`", strings.Join(sockets, ", "))
+}
+
+// IsRootless returns whether the engine behind cli runs rootless, in which
+// case containers can't be granted more than the user has, e.g. higher
+// ulimits.
+func IsRootless(ctx context.Context, cli *client.Client) (bool, error) {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, opt := range info.SecurityOptions {
+		if strings.Contains(opt, "name=rootless") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,50 @@
+package docker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/docker"
+)
+
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestPodmanHostFromContainerHost(t *testing.T) {
+	setenv(t, "CONTAINER_HOST", "tcp://podman:8888")
+
+	host, err := docker.PodmanHost()
+	require.NoError(t, err)
+	require.Equal(t, "tcp://podman:8888", host)
+}
+
+func TestPodmanHostFromRuntimeDir(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "podman", "podman.sock")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sock), 0755))
+	require.NoError(t, os.WriteFile(sock, nil, 0600))
+
+	setenv(t, "CONTAINER_HOST", "")
+	setenv(t, "XDG_RUNTIME_DIR", dir)
+
+	host, err := docker.PodmanHost()
+	require.NoError(t, err)
+	require.Equal(t, "unix://"+sock, host)
+}
+
+func TestNewClientUnknownEngine(t *testing.T) {
+	_, err := docker.NewClient("containerd")
+	require.Error(t, err)
+}
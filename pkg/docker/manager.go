@@ -31,7 +31,7 @@ type Manager struct {
 // NewManager connects to the local docker instance and provides a convenient
 // handle for managing containers.
 func NewManager() (*Manager, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := NewClient(EngineDocker)
 	if err != nil {
 		return nil, err
 	}
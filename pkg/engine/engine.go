@@ -16,6 +16,7 @@ import (
 	"github.com/testground/testground/pkg/build"
 	"github.com/testground/testground/pkg/config"
 	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/plugin"
 	"github.com/testground/testground/pkg/rpc"
 	"github.com/testground/testground/pkg/runner"
 	"github.com/testground/testground/pkg/task"
@@ -32,11 +33,58 @@ var AllBuilders = []api.Builder{
 // AllRunners enumerates all runners known to the system.
 var AllRunners = []api.Runner{
 	&runner.LocalDockerRunner{},
+	runner.NewLocalPodmanRunner(),
 	&runner.LocalExecutableRunner{},
 	&runner.ClusterSwarmRunner{},
 	&runner.ClusterK8sRunner{},
 }
 
+// withPluginBuilders appends the builder plugins declared in the env
+// configuration to the supplied builders.
+func withPluginBuilders(builders []api.Builder, ecfg *config.EnvConfig) ([]api.Builder, error) {
+	plugins, err := plugin.Builders(ecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{}, len(builders))
+	for _, b := range builders {
+		ids[b.ID()] = struct{}{}
+	}
+
+	all := append(builders[:len(builders):len(builders)], plugins...)
+	for _, p := range plugins {
+		if _, ok := ids[p.ID()]; ok {
+			return nil, fmt.Errorf("builder plugin %s conflicts with a built-in builder", p.ID())
+		}
+		logging.S().Infow("registered builder plugin", "builder", p.ID())
+	}
+	return all, nil
+}
+
+// withPluginRunners appends the runner plugins declared in the env
+// configuration to the supplied runners.
+func withPluginRunners(runners []api.Runner, ecfg *config.EnvConfig) ([]api.Runner, error) {
+	plugins, err := plugin.Runners(ecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{}, len(runners))
+	for _, r := range runners {
+		ids[r.ID()] = struct{}{}
+	}
+
+	all := append(runners[:len(runners):len(runners)], plugins...)
+	for _, p := range plugins {
+		if _, ok := ids[p.ID()]; ok {
+			return nil, fmt.Errorf("runner plugin %s conflicts with a built-in runner", p.ID())
+		}
+		logging.S().Infow("registered runner plugin", "runner", p.ID())
+	}
+	return all, nil
+}
+
 // Engine is the central runtime object of the system. It knows about all test
 // plans, builders, and runners. It is supposed to be instantiated as a
 // singleton in all runtimes, whether the testground is run as a CLI tool, or as
@@ -125,9 +173,19 @@ func NewEngine(cfg *EngineConfig) (*Engine, error) {
 }
 
 func NewDefaultEngine(ecfg *config.EnvConfig) (*Engine, error) {
+	builders, err := withPluginBuilders(AllBuilders, ecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	runners, err := withPluginRunners(AllRunners, ecfg)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &EngineConfig{
-		Builders:  AllBuilders,
-		Runners:   AllRunners,
+		Builders:  builders,
+		Runners:   runners,
 		EnvConfig: ecfg,
 	}
 
@@ -328,6 +386,114 @@ func (e *Engine) DoHealthcheck(ctx context.Context, runner string, fix bool, ow
 	return hc.Healthcheck(ctx, e, ow, fix)
 }
 
+// DoInspectRun reports the state of the instances of a run task in progress,
+// if its runner supports it.
+func (e *Engine) DoInspectRun(ctx context.Context, taskID string, ow *rpc.OutputWriter) (*api.RunInspection, error) {
+	tsk, err := e.store.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if tsk.Type != task.TypeRun {
+		return nil, fmt.Errorf("task %s is not a run", taskID)
+	}
+	if st := tsk.State().State; st != task.StateProcessing {
+		return nil, fmt.Errorf("task %s is not in progress; state: %s", taskID, st)
+	}
+
+	in, ok := e.runners[tsk.Runner].(api.Inspectable)
+	if !ok {
+		return nil, fmt.Errorf("runner %s does not support inspecting runs", tsk.Runner)
+	}
+
+	res, err := in.InspectRun(ctx, taskID, ow)
+	if err != nil {
+		return nil, err
+	}
+	res.RunID = taskID
+	res.Runner = tsk.Runner
+	return res, nil
+}
+
+// DoAttach executes a command in an instance of a run task in progress, if
+// its runner supports it, and returns the exit code of the command.
+func (e *Engine) DoAttach(ctx context.Context, taskID string, opts *api.AttachOptions, ow *rpc.OutputWriter) (int, error) {
+	tsk, err := e.store.Get(taskID)
+	if err != nil {
+		return 0, err
+	}
+	if tsk.Type != task.TypeRun {
+		return 0, fmt.Errorf("task %s is not a run", taskID)
+	}
+	if st := tsk.State().State; st != task.StateProcessing {
+		return 0, fmt.Errorf("task %s is not in progress; state: %s", taskID, st)
+	}
+
+	at, ok := e.runners[tsk.Runner].(api.Attachable)
+	if !ok {
+		return 0, fmt.Errorf("runner %s does not support attaching to instances", tsk.Runner)
+	}
+
+	ow.Infow("attaching to instance", "run_id", taskID, "group", opts.GroupID, "instance", opts.Instance, "command", opts.Command)
+	return at.Attach(ctx, taskID, opts, ow)
+}
+
+// DoDryRun plans the capacity of the runs of a composition, if its runner
+// supports it, without building or running them.
+func (e *Engine) DoDryRun(ctx context.Context, request *api.DryRunRequest, ow *rpc.OutputWriter) ([]*api.DryRun, error) {
+	trunner := request.Composition.Global.Runner
+	run, ok := e.runners[trunner]
+	if !ok {
+		return nil, fmt.Errorf("unknown runner: %s", trunner)
+	}
+
+	planner, ok := run.(api.CapacityPlanner)
+	if !ok {
+		return nil, fmt.Errorf("runner %s does not support capacity planning", trunner)
+	}
+
+	comp, err := request.Composition.PrepareForRun(&request.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := comp.ValidateForRun(); err != nil {
+		return nil, err
+	}
+
+	obj, err := e.runnerConfig(trunner, comp)
+	if err != nil {
+		return nil, err
+	}
+
+	runIds := request.RunIds
+	if len(runIds) == 0 {
+		runIds = comp.ListRunIds()
+	}
+
+	res := make([]*api.DryRun, 0, len(runIds))
+	for _, runId := range runIds {
+		in, err := frameRunInput("dry-run", comp, runId, obj)
+		if err != nil {
+			return nil, err
+		}
+		in.EnvConfig = *e.envcfg
+
+		ow.Infow("planning capacity", "run", runId, "runner", trunner, "instances", in.TotalInstances)
+		reports, err := planner.PlanCapacity(ctx, in, ow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan capacity of run %s: %w", runId, err)
+		}
+
+		res = append(res, &api.DryRun{
+			RunID:          runId,
+			Runner:         trunner,
+			TotalInstances: in.TotalInstances,
+			Capacity:       reports,
+		})
+	}
+	return res, nil
+}
+
 func (e *Engine) DoBuildPurge(ctx context.Context, builder, plan string, ow *rpc.OutputWriter) error {
 	bm, ok := e.builders[builder]
 	if !ok {
@@ -415,17 +581,86 @@ func (e *Engine) GetTask(id string) (*task.Task, error) {
 	return e.store.Get(id)
 }
 
-// Kill closes the signal channel for a given task, which signals to the runner to stop it
+// terminateRunTimeout bounds the termination of the run of a killed task.
+const terminateRunTimeout = 2 * time.Minute
+
+// terminateRunGrace is the time the runner of a killed or timed out task is
+// given to stop its run, before the run is terminated from under it.
+var terminateRunGrace = 30 * time.Second
+
+// Kill closes the signal channel for a given task, which signals to the runner to stop it.
+// If the task is a run, and its runner supports it, the containers, pods or processes and
+// networks of the run are terminated too: once the runner has returned, or after a grace
+// period if it hasn't by then. Kill doesn't wait for either.
 func (e *Engine) Kill(id string) error {
-	e.signalsLk.RLock()
-	if ch, ok := e.signals[id]; ok {
+	e.signalsLk.Lock()
+	defer e.signalsLk.Unlock()
+
+	if ch, running := e.signals[id]; running {
 		close(ch)
+		delete(e.signals, id)
 	}
-	e.signalsLk.RUnlock()
-
 	return nil
 }
 
+// superviseRun terminates the run of a task once ctx is done, i.e. once the
+// task is killed or times out, if its runner hasn't returned within
+// terminateRunGrace. The returned function must be called once the runner
+// has returned; it terminates what the runner left behind if ctx is done.
+func (e *Engine) superviseRun(ctx context.Context, tsk *task.Task, ow *rpc.OutputWriter) (returned func()) {
+	var (
+		done       = make(chan struct{})
+		stopped    = make(chan struct{})
+		terminated bool
+	)
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+
+		t := time.NewTimer(terminateRunGrace)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			ow.Warnw("runner still running after the task was stopped; terminating its run", "grace", terminateRunGrace)
+			e.terminateRun(tsk, ow)
+			terminated = true
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if ctx.Err() != nil && !terminated {
+			e.terminateRun(tsk, ow)
+		}
+	}
+}
+
+// terminateRun terminates the run of a task, if its runner implements
+// api.RunTerminatable. Progress is written to ow.
+func (e *Engine) terminateRun(tsk *task.Task, ow *rpc.OutputWriter) {
+	rid := tsk.Runner
+	terminatable, ok := e.runners[rid].(api.RunTerminatable)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), terminateRunTimeout)
+	defer cancel()
+
+	if err := terminatable.TerminateRun(ctx, tsk.ID, ow); err != nil {
+		logging.S().Errorw("failed to terminate run", "task_id", tsk.ID, "runner", rid, "err", err)
+		ow.Errorw("failed to terminate run", "err", err)
+	}
+}
+
 // UnmarshalTask converts the given byte array into a valid task
 func UnmarshalTask(taskData []byte) (*task.Task, error) {
 	finalTask := &task.Task{}
@@ -525,11 +760,9 @@ Outer:
 		select {
 		case <-ctx.Done():
 			if cancel {
-				e.signalsLk.RLock()
-				if ch, ok := e.signals[id]; ok {
-					close(ch)
+				if err := e.Kill(id); err != nil {
+					logging.S().Warnw("failed to kill task", "task_id", id, "err", err)
 				}
-				e.signalsLk.RUnlock()
 			}
 			break Outer
 		default:
@@ -1,12 +1,14 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/rpc"
 	"github.com/testground/testground/pkg/task"
 )
 
@@ -96,3 +98,56 @@ func TestUnmarshalTaskBuild(t *testing.T) {
 		t.Errorf("Unmarshal Build task returned incorrect data")
 	}
 }
+
+type terminatableRunner struct {
+	api.Runner
+	terminated chan string
+}
+
+func (r *terminatableRunner) TerminateRun(_ context.Context, runID string, _ *rpc.OutputWriter) error {
+	r.terminated <- runID
+	return nil
+}
+
+func TestSuperviseRun(t *testing.T) {
+	defer func(grace time.Duration) { terminateRunGrace = grace }(terminateRunGrace)
+	terminateRunGrace = 10 * time.Millisecond
+
+	r := &terminatableRunner{terminated: make(chan string, 2)}
+	e := &Engine{runners: map[string]api.Runner{"local:docker": r}}
+	tsk := &task.Task{ID: "stuck", Runner: "local:docker"}
+
+	// a run that completes isn't terminated.
+	e.superviseRun(context.Background(), tsk, rpc.Discard())()
+	if len(r.terminated) != 0 {
+		t.Fatalf("completed run was terminated")
+	}
+
+	// a killed run whose runner is stuck is terminated after the grace
+	// period, and only once.
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := e.superviseRun(ctx, tsk, rpc.Discard())
+	cancel()
+	select {
+	case id := <-r.terminated:
+		if id != "stuck" {
+			t.Fatalf("terminated run %s; expected stuck", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stuck run wasn't terminated")
+	}
+	returned()
+	if len(r.terminated) != 0 {
+		t.Fatalf("run was terminated twice")
+	}
+
+	// a killed run whose runner returns in time is terminated once it has.
+	terminateRunGrace = time.Hour
+	ctx, cancel = context.WithCancel(context.Background())
+	returned = e.superviseRun(ctx, tsk, rpc.Discard())
+	cancel()
+	returned()
+	if len(r.terminated) != 1 {
+		t.Fatalf("killed run wasn't terminated once its runner returned")
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+// hashSources computes a content hash over the plan, sdk and extra source
+// directories of a build. Files are visited in lexical order, and both their
+// relative paths and their contents contribute to the hash, so that the
+// result is stable across machines.
+func hashSources(src *api.UnpackedSources) (string, error) {
+	h := sha256.New()
+
+	dirs := map[string]string{
+		"plan":  src.PlanDir,
+		"sdk":   src.SDKDir,
+		"extra": src.ExtraDir,
+	}
+
+	kinds := make([]string, 0, len(dirs))
+	for k := range dirs {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		dir := dirs[kind]
+		if dir == "" {
+			continue
+		}
+
+		// filepath.Walk visits files in lexical order.
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			_, _ = fmt.Fprintf(h, "%s/%s\x00", kind, filepath.ToSlash(rel))
+			_, err = io.Copy(h, f)
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to hash %s sources: %w", kind, err)
+		}
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// completeProvenance fills in the provenance document of a build output with
+// the information known to the engine, retaining anything the builder may
+// have already recorded.
+func completeProvenance(in *api.BuildInput, out *api.BuildOutput, groups []string, sourceHash string, start time.Time) *api.BuildProvenance {
+	p := out.Provenance
+	if p == nil {
+		p = new(api.BuildProvenance)
+	}
+
+	p.BuildID = in.BuildID
+	p.BuilderID = out.BuilderID
+	p.TestPlan = in.TestPlan
+	p.Groups = groups
+	p.ArtifactPath = out.ArtifactPath
+	p.SourceHash = sourceHash
+	p.BuildConfig = in.BuildConfig
+	p.Selectors = in.Selectors
+	p.DependencyOverrides = in.Dependencies
+	p.ResolvedDependencies = out.Dependencies
+	p.StartedAt = start.UTC()
+	p.Duration = time.Since(start)
+
+	return p
+}
+
+// buildResult assembles the result of a build task from the outputs of each
+// group, deduplicating provenance documents of groups that shared a build.
+func buildResult(outs []*api.BuildOutput) *api.BuildResult {
+	res := &api.BuildResult{
+		Artifacts:  make([]string, 0, len(outs)),
+		Provenance: make([]*api.BuildProvenance, 0, len(outs)),
+	}
+
+	seen := make(map[*api.BuildProvenance]struct{}, len(outs))
+	for _, out := range outs {
+		res.Artifacts = append(res.Artifacts, out.ArtifactPath)
+
+		if out.Provenance == nil {
+			continue
+		}
+		if _, ok := seen[out.Provenance]; ok {
+			continue
+		}
+		seen[out.Provenance] = struct{}{}
+		res.Provenance = append(res.Provenance, out.Provenance)
+	}
+
+	return res
+}
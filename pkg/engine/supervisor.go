@@ -73,7 +73,6 @@ func (e *Engine) worker(n int) {
 			go func() {
 				select {
 				case <-ch:
-					e.deleteSignal(tsk.ID)
 					cancel()
 				case <-ctx.Done():
 					return
@@ -111,7 +110,9 @@ func (e *Engine) worker(n int) {
 			switch tsk.Type {
 			case task.TypeRun:
 				var res *api.RunOutput
+				returned := e.superviseRun(ctx, tsk, ow)
 				res, errTask = e.doRun(ctx, tsk.ID, tsk.Input.(*RunInput), ow)
+				returned()
 
 				if errTask != nil {
 					errTask = &TaskExecutionError{TaskType: string(tsk.Type), WrappedErr: errTask}
@@ -131,11 +132,7 @@ func (e *Engine) worker(n int) {
 				}
 
 				if res != nil {
-					var artifactPaths []string
-					for _, ap := range res {
-						artifactPaths = append(artifactPaths, ap.ArtifactPath)
-					}
-					result = artifactPaths
+					result = buildResult(res)
 				}
 
 			default:
@@ -440,9 +437,13 @@ func (e *Engine) doBuild(ctx context.Context, input *BuildInput, ow *rpc.OutputW
 			//
 			//  1. CLI --run-param, --build-param flags.
 			//  2. .env.toml.
-			//  3. Builder defaults (applied by the builder itself, nothing to do here).
+			//  3. The build configuration the runner needs, if any.
+			//  4. Builder defaults (applied by the builder itself, nothing to do here).
 			//
 			var cfg config.CoalescedConfig
+			if bc, ok := e.runners[comp.Global.Runner].(api.BuildConfigurer); ok {
+				cfg = cfg.Append(bc.BuildConfig(builder))
+			}
 			cfg = cfg.Append(e.envcfg.Builders[builder]) // env config for the builder
 			groupCfg := cfg.Append(grp.BuildConfig)      // add the group config
 
@@ -464,6 +465,14 @@ func (e *Engine) doBuild(ctx context.Context, input *BuildInput, ow *rpc.OutputW
 				UnpackedSources: src,
 			}
 
+			// Hash the sources before the builder gets to touch them.
+			srcHash, err := hashSources(src)
+			if err != nil {
+				return err
+			}
+
+			start := time.Now()
+
 			res, err := bm.Build(errGroupCtx, in, ow)
 			if err != nil {
 				ow.Infow("build failed", "plan", plan, "groups", grpids, "builder", builder, "error", err)
@@ -471,6 +480,7 @@ func (e *Engine) doBuild(ctx context.Context, input *BuildInput, ow *rpc.OutputW
 			}
 
 			res.BuilderID = bm.ID()
+			res.Provenance = completeProvenance(in, res, grpids, srcHash, start)
 
 			// no need for a mutex as the indices we access do not intersect
 			// across goroutines.
@@ -492,7 +502,25 @@ func (e *Engine) doBuild(ctx context.Context, input *BuildInput, ow *rpc.OutputW
 }
 
 func (e *Engine) doRun(ctx context.Context, id string, input *RunInput, ow *rpc.OutputWriter) (*api.RunOutput, error) {
+	if unknown := input.Manifest.UnknownParamTypes(); len(unknown) > 0 {
+		ow.Warnw("test params of unknown types; checking their constraints only", "params", unknown, "supported", api.ParamTypes)
+	}
+
+	// Prepare the composition upfront, so that invalid test params fail the
+	// run before anything is built.
+	if _, err := input.Composition.PrepareForRun(&input.Manifest); err != nil {
+		return nil, err
+	}
+
 	if len(input.BuildGroups) > 0 {
+		// Build outputs are mapped back to groups by index, which only holds
+		// if build matrices have been expanded by the client.
+		for _, idx := range input.BuildGroups {
+			if idx < len(input.Composition.Groups) && input.Composition.Groups[idx].Build.Matrix != nil {
+				return nil, fmt.Errorf("group %s has an unexpanded build matrix", input.Composition.Groups[idx].ID)
+			}
+		}
+
 		bcomp, err := input.Composition.PickGroups(input.BuildGroups...)
 		if err != nil {
 			return nil, err
@@ -552,6 +580,48 @@ func (e *Engine) doRun(ctx context.Context, id string, input *RunInput, ow *rpc.
 		}
 	}
 
+	obj, err := e.runnerConfig(trunner, comp)
+	if err != nil {
+		return nil, err
+	}
+
+	if (len(input.RunIds) > 1) {
+		// TODO: remove when we can build multiple runs
+		return nil, fmt.Errorf("cannot specify multiple run ids for now")
+	}
+
+	in, err := frameRunInput(id, comp, input.RunIds[0], obj)
+	if err != nil {
+		return nil, err
+	}
+	in.EnvConfig = *e.envcfg
+
+	ow.Infow("starting run", "run_id", id, "plan", in.TestPlan, "case", in.TestCase, "runner", trunner, "instances", in.TotalInstances)
+	out, err := run.Run(ctx, in, ow)
+
+	if err == nil {
+		message := "run finished with outcome unknown"
+		if out.Result != nil {
+			message = fmt.Sprintf("run finished with %v", out.Result)
+		}
+
+		ow.Infow(message, "run_id", id, "plan", plan, "case", tcase, "runner", trunner, "instances", in.TotalInstances)
+	} else if errors.Is(err, context.Canceled) {
+		ow.Infow("run canceled", "run_id", id, "plan", plan, "case", tcase, "runner", trunner, "instances", in.TotalInstances)
+	} else {
+		ow.Warnw("run finished in error", "run_id", id, "plan", plan, "case", tcase, "runner", trunner, "instances", in.TotalInstances, "error", err)
+	}
+
+	if out != nil { // TODO: Make sure all runners return a value, and get rid of nil check
+		out.Composition = *compositionUsedForRun
+	}
+
+	return out, err
+}
+
+// runnerConfig coalesces the configuration of a runner for a prepared
+// composition.
+func (e *Engine) runnerConfig(trunner string, comp *api.Composition) (interface{}, error) {
 	// This var compiles all configurations to coalesce.
 	//
 	// Precedence (highest to lowest):
@@ -575,31 +645,28 @@ func (e *Engine) doRun(ctx context.Context, id string, input *RunInput, ow *rpc.
 
 	// Coalesce all configurations and deserialize into the config type
 	// mandated by the runner.
-	obj, err := cfg.CoalesceIntoType(run.ConfigType())
+	obj, err := cfg.CoalesceIntoType(e.runners[trunner].ConfigType())
 	if err != nil {
 		return nil, fmt.Errorf("error while coalescing configuration values: %w", err)
 	}
+	return obj, nil
+}
 
-	if (len(input.RunIds) > 1) {
-		// TODO: remove when we can build multiple runs
-		return nil, fmt.Errorf("cannot specify multiple run ids for now")
-	}
-
-	runId := input.RunIds[0]
-	framedComp, err := comp.FrameForRuns(runId);
-
+// frameRunInput returns the input of the runner for a run of a prepared
+// composition. The env config is left for the caller to fill.
+func frameRunInput(id string, comp *api.Composition, runId string, runnerCfg interface{}) (*api.RunInput, error) {
+	framedComp, err := comp.FrameForRuns(runId)
 	if err != nil {
 		return nil, fmt.Errorf("error while framing composition for run: %s: %w", runId, err)
 	}
 
 	compRun := framedComp.Runs[0]
 
-	in := api.RunInput{
+	in := &api.RunInput{
 		RunID:          id,
-		EnvConfig:      *e.envcfg,
-		RunnerConfig:   obj,
-		TestPlan:       clean(plan),
-		TestCase:       clean(tcase),
+		RunnerConfig:   runnerCfg,
+		TestPlan:       clean(comp.Global.Plan),
+		TestCase:       clean(comp.Global.Case),
 		TotalInstances: int(compRun.TotalInstances),
 		Groups:         make([]*api.RunGroup, 0, len(compRun.Groups)),
 		DisableMetrics: comp.Global.DisableMetrics,
@@ -618,32 +685,15 @@ func (e *Engine) doRun(ctx context.Context, id string, input *RunInput, ow *rpc.
 			Parameters:   grp.TestParams,
 			Resources:    grp.Resources,
 			Profiles:     grp.Profiles,
+			Coordinates:  buildgroup.Coordinates,
 		}
-
-		in.Groups = append(in.Groups, g)
-	}
-
-	ow.Infow("starting run", "run_id", id, "plan", in.TestPlan, "case", in.TestCase, "runner", trunner, "instances", in.TotalInstances)
-	out, err := run.Run(ctx, &in, ow)
-
-	if err == nil {
-		message := "run finished with outcome unknown"
-		if out.Result != nil {
-			message = fmt.Sprintf("run finished with %v", out.Result)
+		if grp.Start != nil {
+			g.Start = *grp.Start
 		}
 
-		ow.Infow(message, "run_id", id, "plan", plan, "case", tcase, "runner", trunner, "instances", in.TotalInstances)
-	} else if errors.Is(err, context.Canceled) {
-		ow.Infow("run canceled", "run_id", id, "plan", plan, "case", tcase, "runner", trunner, "instances", in.TotalInstances)
-	} else {
-		ow.Warnw("run finished in error", "run_id", id, "plan", plan, "case", tcase, "runner", trunner, "instances", in.TotalInstances, "error", err)
-	}
-
-	if out != nil { // TODO: Make sure all runners return a value, and get rid of nil check
-		out.Composition = *compositionUsedForRun
+		in.Groups = append(in.Groups, g)
 	}
-
-	return out, err
+	return in, nil
 }
 
 func clean(name string) string {
@@ -10,6 +10,9 @@ import (
 	"github.com/testground/testground/pkg/docker"
 	"github.com/testground/testground/pkg/rpc"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/client"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -118,6 +121,40 @@ func CheckK8sPods(ctx context.Context, client *kubernetes.Clientset, label strin
 	}
 }
 
+// CheckSwarmService returns a checker which verifies that a docker swarm service exists, and that
+// at least one of its tasks is running.
+func CheckSwarmService(ctx context.Context, cli *client.Client, name string) Checker {
+	return func() (bool, string, error) {
+		services, err := cli.ServiceList(ctx, types.ServiceListOptions{
+			Filters: filters.NewArgs(filters.Arg("name", name)),
+		})
+		if err != nil {
+			return false, fmt.Sprintf("failed to list services %s", name), err
+		}
+		for _, svc := range services {
+			// the name filter matches prefixes.
+			if svc.Spec.Name != name {
+				continue
+			}
+			tasks, err := cli.TaskList(ctx, types.TaskListOptions{
+				Filters: filters.NewArgs(filters.Arg("service", svc.ID), filters.Arg("desired-state", "running")),
+			})
+			if err != nil {
+				return false, fmt.Sprintf("failed to list tasks of service %s", name), err
+			}
+			var running int
+			for _, t := range tasks {
+				if t.Status.State == swarm.TaskStateRunning {
+					running++
+				}
+			}
+			msg := fmt.Sprintf("service exists; %d tasks running", running)
+			return running > 0, msg, nil
+		}
+		return false, "service not found.", nil
+	}
+}
+
 // CheckRedisPort returns a checker which verifies if the default port of redis (6379) is already binded
 // on localhost. If it is, it fails. If not, it succeeds.
 func CheckRedisPort(ctx context.Context, ow *rpc.OutputWriter, cli *client.Client) Checker {
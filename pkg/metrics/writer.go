@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	client "github.com/influxdata/influxdb1-client/v2"
+	"github.com/testground/testground/pkg/config"
+)
+
+// Writer writes points to the testground database, which test plans report
+// their metrics to.
+type Writer struct {
+	db string
+	cl client.Client
+}
+
+func NewWriter(cfg *config.EnvConfig) (*Writer, error) {
+	cl, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr:    cfg.Daemon.InfluxDBEndpoint,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{db: "testground", cl: cl}, nil
+}
+
+// Write writes a single point of the measurement.
+func (w *Writer) Write(measurement string, tags map[string]string, fields map[string]interface{}, t time.Time) error {
+	bp, err := client.NewBatchPoints(client.BatchPointsConfig{Database: w.db})
+	if err != nil {
+		return err
+	}
+
+	p, err := client.NewPoint(measurement, tags, fields, t)
+	if err != nil {
+		return err
+	}
+	bp.AddPoint(p)
+
+	return w.cl.Write(bp)
+}
+
+func (w *Writer) Close() error {
+	return w.cl.Close()
+}
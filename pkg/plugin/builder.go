@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// BuilderService is the name of the gRPC service builder plugins implement.
+const BuilderService = "testground.plugin." + ProtocolVersion + ".Builder"
+
+// Builder is an api.Builder backed by an external plugin.
+type Builder struct {
+	id   string
+	home string
+	t    transport
+
+	// credentials forwards the registry credentials of the env
+	// configuration to the plugin.
+	credentials bool
+}
+
+var (
+	_ api.Builder       = (*Builder)(nil)
+	_ api.Healthchecker = (*Builder)(nil)
+)
+
+// NewBuilder returns a Builder registered under id, which calls the plugin
+// declared by cfg. home is the testground home directory passed on to the
+// plugin.
+func NewBuilder(id string, cfg config.PluginConfig, home string) (*Builder, error) {
+	t, err := newTransport(cfg, BuilderService)
+	if err != nil {
+		return nil, fmt.Errorf("invalid builder plugin %s: %w", id, err)
+	}
+	return &Builder{id: id, home: home, t: t, credentials: cfg.ForwardCredentials}, nil
+}
+
+// Builders returns the builder plugins declared in the env configuration.
+func Builders(cfg *config.EnvConfig) ([]api.Builder, error) {
+	builders := make([]api.Builder, 0, len(cfg.Plugins.Builders))
+	for id, pcfg := range cfg.Plugins.Builders {
+		b, err := NewBuilder(id, pcfg, cfg.Dirs().Home())
+		if err != nil {
+			return nil, err
+		}
+		builders = append(builders, b)
+	}
+	return builders, nil
+}
+
+func newTransport(cfg config.PluginConfig, service string) (transport, error) {
+	switch {
+	case cfg.Exec != "" && cfg.Endpoint != "":
+		return nil, fmt.Errorf("exec and endpoint are mutually exclusive")
+	case cfg.Exec != "":
+		return &execTransport{path: cfg.Exec, args: cfg.Args}, nil
+	case cfg.Endpoint != "":
+		return newGRPCTransport(cfg, service)
+	default:
+		return nil, fmt.Errorf("either exec or endpoint must be set")
+	}
+}
+
+func (b *Builder) ID() string {
+	return b.id
+}
+
+func (b *Builder) Build(ctx context.Context, in *api.BuildInput, ow *rpc.OutputWriter) (*api.BuildOutput, error) {
+	// the input is shared with the engine; strip credentials off a copy.
+	input := *in
+	input.EnvConfig = in.EnvConfig.WithoutCredentials(b.credentials)
+
+	req := &BuildRequest{
+		Home:  b.home,
+		Input: &input,
+	}
+
+	var out api.BuildOutput
+	if err := b.t.call(ctx, MethodBuild, req, ow, &out); err != nil {
+		return nil, err
+	}
+
+	out.BuilderID = b.id
+	return &out, nil
+}
+
+func (b *Builder) Purge(ctx context.Context, testplan string, ow *rpc.OutputWriter) error {
+	req := &PurgeRequest{
+		Home:     b.home,
+		TestPlan: testplan,
+	}
+	return b.t.call(ctx, MethodPurge, req, ow, nil)
+}
+
+// ConfigType returns a generic map, as the configuration is coalesced into
+// its actual type by the plugin.
+func (b *Builder) ConfigType() reflect.Type {
+	return reflect.TypeOf(config.ConfigMap{})
+}
+
+// Healthcheck verifies that the plugin is reachable, and that it identifies
+// itself with the id it's registered under.
+func (b *Builder) Healthcheck(ctx context.Context, engine api.Engine, ow *rpc.OutputWriter, fix bool) (*api.HealthcheckReport, error) {
+	_, item := checkInfo(ctx, b.t, b.id, ow)
+	return &api.HealthcheckReport{Checks: []api.HealthcheckItem{item}}, nil
+}
+
+// handshake calls the Info method of a plugin, and verifies that it
+// identifies with id.
+func handshake(ctx context.Context, t transport, id string, ow *rpc.OutputWriter) (*InfoResponse, error) {
+	var resp InfoResponse
+	if err := t.call(ctx, MethodInfo, struct{}{}, ow, &resp); err != nil {
+		return nil, fmt.Errorf("plugin unreachable: %w", err)
+	}
+	if resp.ID != id {
+		return nil, fmt.Errorf("plugin identifies as %s; registered as %s", resp.ID, id)
+	}
+	return &resp, nil
+}
+
+// info calls handshake, and further verifies that the plugin speaks our
+// protocol version.
+func info(ctx context.Context, t transport, id string, ow *rpc.OutputWriter) (*InfoResponse, error) {
+	resp, err := handshake(ctx, t, id, ow)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("plugin implements protocol %q; expected %q", resp.ProtocolVersion, ProtocolVersion)
+	}
+	return resp, nil
+}
+
+// checkInfo calls info, and reports the outcome as a healthcheck item.
+func checkInfo(ctx context.Context, t transport, id string, ow *rpc.OutputWriter) (*InfoResponse, api.HealthcheckItem) {
+	item := api.HealthcheckItem{Name: "plugin", Status: api.HealthcheckStatusOK, Message: "plugin reachable"}
+
+	resp, err := info(ctx, t, id, ow)
+	if err != nil {
+		item.Status = api.HealthcheckStatusFailed
+		item.Message = err.Error()
+	}
+	return resp, item
+}
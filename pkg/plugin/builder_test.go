@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/rpc/rpctest"
+)
+
+type fakeBuilderConfig struct {
+	Tag   string `toml:"tag"`
+	Count int    `toml:"count"`
+	Fail  bool   `toml:"fail"`
+}
+
+type fakeBuilder struct{}
+
+func (*fakeBuilder) ID() string {
+	return "fake:builder"
+}
+
+func (*fakeBuilder) Build(ctx context.Context, in *api.BuildInput, ow *rpc.OutputWriter) (*api.BuildOutput, error) {
+	cfg, ok := in.BuildConfig.(*fakeBuilderConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected configuration type fakeBuilderConfig, was: %T", in.BuildConfig)
+	}
+	if cfg.Fail {
+		return nil, errors.New("build failed on purpose")
+	}
+	if in.EnvConfig.AWS.SecretAccessKey != "" || in.EnvConfig.Client.Token != "" {
+		return nil, errors.New("received credentials")
+	}
+
+	ow.Infow("building fake artifact", "plan", in.TestPlan)
+
+	return &api.BuildOutput{
+		ArtifactPath: fmt.Sprintf("%s/%s:%s-%d", in.EnvConfig.Dirs().Work(), in.TestPlan, cfg.Tag, cfg.Count),
+	}, nil
+}
+
+func (*fakeBuilder) Purge(ctx context.Context, testplan string, ow *rpc.OutputWriter) error {
+	return nil
+}
+
+func (*fakeBuilder) ConfigType() reflect.Type {
+	return reflect.TypeOf(fakeBuilderConfig{})
+}
+
+// TestHelperBuilderPlugin is not a real test; it's the executable plugin
+// invoked by TestExecBuilder.
+func TestHelperBuilderPlugin(t *testing.T) {
+	if os.Getenv("TESTGROUND_PLUGIN_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	if err := ServeBuilderExec(&fakeBuilder{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// progress returns the concatenated payloads of the progress chunks in b.
+func progress(t *testing.T, b []byte) string {
+	var out strings.Builder
+	for dec := json.NewDecoder(bytes.NewReader(b)); dec.More(); {
+		var c chunk
+		require.NoError(t, dec.Decode(&c))
+		if c.Type != rpc.ChunkTypeProgress {
+			continue
+		}
+		var p []byte
+		require.NoError(t, json.Unmarshal(c.Payload, &p))
+		out.Write(p)
+	}
+	return out.String()
+}
+
+func testBuilderPlugin(t *testing.T, b *Builder) {
+	ctx := context.Background()
+
+	rep, err := b.Healthcheck(ctx, nil, rpc.Discard(), false)
+	require.NoError(t, err)
+	require.True(t, rep.ChecksSucceeded(), rep.String())
+
+	// credentials are not forwarded to plugins.
+	env := config.EnvConfig{}.WithHome("/tg")
+	env.AWS.SecretAccessKey = "secret"
+	env.Client.Token = "token"
+	in := &api.BuildInput{
+		BuildID:     "abc",
+		EnvConfig:   env,
+		TestPlan:    "plan",
+		BuildConfig: &config.ConfigMap{"tag": "latest", "count": 3},
+	}
+
+	rec, ow := rpctest.NewRecordedOutputWriter("test")
+	out, err := b.Build(ctx, in, ow)
+	require.NoError(t, err)
+	require.Equal(t, "fake:builder", out.BuilderID)
+	require.Equal(t, "/tg/data/work/plan:latest-3", out.ArtifactPath)
+	require.Contains(t, progress(t, rec.Body.Bytes()), "building fake artifact")
+
+	in.BuildConfig = &config.ConfigMap{"fail": true}
+	_, err = b.Build(ctx, in, rpc.Discard())
+	require.EqualError(t, err, "build failed on purpose")
+
+	require.NoError(t, b.Purge(ctx, "plan", rpc.Discard()))
+}
+
+func TestGRPCBuilder(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	RegisterBuilderServer(s, &fakeBuilder{})
+	go s.Serve(l) //nolint:errcheck
+	defer s.Stop()
+
+	b, err := NewBuilder("fake:builder", config.PluginConfig{Endpoint: l.Addr().String()}, "/tg")
+	require.NoError(t, err)
+
+	testBuilderPlugin(t, b)
+}
+
+func TestExecBuilder(t *testing.T) {
+	os.Setenv("TESTGROUND_PLUGIN_HELPER", "1")
+	defer os.Unsetenv("TESTGROUND_PLUGIN_HELPER")
+
+	b, err := NewBuilder("fake:builder", config.PluginConfig{
+		Exec: os.Args[0],
+		Args: []string{"-test.run=^TestHelperBuilderPlugin$", "--"},
+	}, "/tg")
+	require.NoError(t, err)
+
+	testBuilderPlugin(t, b)
+}
+
+func TestBuilderIDMismatch(t *testing.T) {
+	os.Setenv("TESTGROUND_PLUGIN_HELPER", "1")
+	defer os.Unsetenv("TESTGROUND_PLUGIN_HELPER")
+
+	b, err := NewBuilder("other:builder", config.PluginConfig{
+		Exec: os.Args[0],
+		Args: []string{"-test.run=^TestHelperBuilderPlugin$", "--"},
+	}, "/tg")
+	require.NoError(t, err)
+
+	rep, err := b.Healthcheck(context.Background(), nil, rpc.Discard(), false)
+	require.NoError(t, err)
+	require.False(t, rep.ChecksSucceeded())
+}
+
+func TestInvalidPluginConfig(t *testing.T) {
+	_, err := NewBuilder("x", config.PluginConfig{}, "")
+	require.Error(t, err)
+
+	_, err = NewBuilder("x", config.PluginConfig{Exec: "a", Endpoint: "b"}, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the gRPC content subtype plugins communicate with.
+const codecName = "json"
+
+// jsonCodec is a gRPC codec that encodes messages as JSON, so that the
+// protocol messages can be plain Go types, rather than generated protobufs.
+type jsonCodec struct{}
+
+var _ encoding.Codec = jsonCodec{}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
@@ -0,0 +1,211 @@
+// Package conformance is a test suite that exercises a runner, typically a
+// runner plugin, against the placebo and network test plans, and verifies
+// that it honours the contract of api.Runner.
+//
+// Plugin authors call Run from a test of their own, supplying artifacts of
+// both plans built with a builder the runner is compatible with:
+//
+//	func TestConformance(t *testing.T) {
+//		r, _ := plugin.NewRunner("local:mine", config.PluginConfig{Endpoint: "localhost:9000"}, home)
+//		conformance.Run(t, conformance.Config{
+//			Runner:          r,
+//			Builder:         "docker:go",
+//			ProtocolVersion: plugin.ProtocolVersion,
+//			EnvConfig:       env,
+//			PlaceboArtifact: "placebo:latest",
+//			NetworkArtifact: "network:latest",
+//		})
+//	}
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/data"
+	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/rpc/rpctest"
+	"github.com/testground/testground/pkg/task"
+)
+
+// DefaultTimeout bounds each run of the suite, unless overridden.
+const DefaultTimeout = 5 * time.Minute
+
+// Config configures a conformance run.
+type Config struct {
+	// Runner is the runner under test.
+	Runner api.Runner
+
+	// Builder is the id of the builder that produced the artifacts. The
+	// runner must report it as compatible.
+	Builder string
+
+	// EnvConfig is handed to the runner in every input.
+	EnvConfig config.EnvConfig
+
+	// RunnerConfig is the runner configuration used for every run.
+	RunnerConfig config.ConfigMap
+
+	// PlaceboArtifact and NetworkArtifact are the artifacts of the placebo
+	// and network test plans. Cases of a plan without an artifact are
+	// skipped.
+	PlaceboArtifact string
+	NetworkArtifact string
+
+	// ProtocolVersion is the version of the plugin protocol the runner must
+	// report, typically plugin.ProtocolVersion. It's checked for runners
+	// that implement Handshaker.
+	ProtocolVersion string
+
+	// Timeout bounds each run. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Handshaker is implemented by runners backed by a plugin, such as
+// plugin.Runner, to report the protocol version the plugin speaks.
+type Handshaker interface {
+	Handshake(ctx context.Context) (version string, err error)
+}
+
+// Run runs the conformance suite against the runner in cfg.
+func Run(t *testing.T, cfg Config) {
+	require.NotNil(t, cfg.Runner, "no runner under test")
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	t.Run("protocol-version", func(t *testing.T) {
+		h, ok := cfg.Runner.(Handshaker)
+		if !ok {
+			t.Skip("runner is not a plugin")
+		}
+		require.NotEmpty(t, cfg.ProtocolVersion, "no protocol version to check against")
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+
+		v, err := h.Handshake(ctx)
+		require.NoError(t, err)
+		require.Equal(t, cfg.ProtocolVersion, v, "unexpected protocol version")
+	})
+
+	t.Run("compatible-builders", func(t *testing.T) {
+		require.Contains(t, cfg.Runner.CompatibleBuilders(), cfg.Builder)
+	})
+
+	t.Run("healthcheck", func(t *testing.T) {
+		hc, ok := cfg.Runner.(api.Healthchecker)
+		if !ok {
+			t.Skip("runner does not support healthchecks")
+		}
+		rep, err := hc.Healthcheck(context.Background(), nil, rpc.Discard(), false)
+		require.NoError(t, err)
+		require.True(t, rep.ChecksSucceeded(), rep.String())
+	})
+
+	runs := []struct {
+		name      string
+		plan      string
+		artifact  string
+		testcase  string
+		instances int
+		outcome   task.Outcome
+	}{
+		{"placebo/ok", "placebo", cfg.PlaceboArtifact, "ok", 1, task.OutcomeSuccess},
+		{"placebo/panic", "placebo", cfg.PlaceboArtifact, "panic", 1, task.OutcomeFailure},
+		{"placebo/abort", "placebo", cfg.PlaceboArtifact, "abort", 1, task.OutcomeFailure},
+		{"network/ping-pong", "network", cfg.NetworkArtifact, "ping-pong", 2, task.OutcomeSuccess},
+	}
+
+	for i, r := range runs {
+		r := r
+		runID := fmt.Sprintf("conformance%d%d", time.Now().Unix(), i)
+
+		t.Run(r.name, func(t *testing.T) {
+			if r.artifact == "" {
+				t.Skipf("no artifact for plan %s", r.plan)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+			defer cancel()
+
+			in := &api.RunInput{
+				RunID:          runID,
+				EnvConfig:      cfg.EnvConfig,
+				RunnerConfig:   runnerConfig(cfg),
+				TestPlan:       r.plan,
+				TestCase:       r.testcase,
+				TotalInstances: r.instances,
+				Groups: []*api.RunGroup{{
+					ID:           "single",
+					Instances:    r.instances,
+					ArtifactPath: r.artifact,
+					Parameters:   map[string]string{},
+				}},
+			}
+
+			out, err := cfg.Runner.Run(ctx, in, rpc.Discard())
+			require.NoError(t, err)
+			require.NotNil(t, out)
+
+			res := data.DecodeRunnerResult(out.Result)
+			require.NotNil(t, res, "run produced no result")
+			require.Equal(t, r.outcome, res.Outcome, "unexpected outcome")
+			if g, ok := res.Outcomes["single"]; ok {
+				require.Equal(t, r.instances, g.Total, "unexpected instance count")
+			}
+
+			// outputs must be collectable once the run has completed.
+			rec, ow := rpctest.NewRecordedOutputWriter(runID)
+			err = cfg.Runner.CollectOutputs(ctx, &api.CollectionInput{
+				EnvConfig:    cfg.EnvConfig,
+				RunID:        runID,
+				RunnerID:     cfg.Runner.ID(),
+				RunnerConfig: runnerConfig(cfg),
+			}, ow)
+			require.NoError(t, err)
+			require.True(t, hasBinary(t, rec.Body.Bytes()), "outputs archive is empty")
+		})
+	}
+
+	t.Run("terminate-all", func(t *testing.T) {
+		term, ok := cfg.Runner.(api.Terminatable)
+		if !ok {
+			t.Skip("runner is not terminatable")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		require.NoError(t, term.TerminateAll(ctx, rpc.Discard()))
+	})
+}
+
+// runnerConfig returns a copy of the runner configuration, as runners may
+// mutate it.
+func runnerConfig(cfg Config) *config.ConfigMap {
+	m := make(config.ConfigMap, len(cfg.RunnerConfig))
+	for k, v := range cfg.RunnerConfig {
+		m[k] = v
+	}
+	return &m
+}
+
+// hasBinary returns whether the chunks in b contain a non-empty binary
+// chunk.
+func hasBinary(t *testing.T, b []byte) bool {
+	for dec := json.NewDecoder(bytes.NewReader(b)); dec.More(); {
+		var c rpc.Chunk
+		require.NoError(t, dec.Decode(&c))
+		if c.Type == rpc.ChunkTypeBinary && c.Payload != nil && c.Payload != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package conformance_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/plugin"
+	"github.com/testground/testground/pkg/plugin/conformance"
+)
+
+// TestPluginConformance runs the suite against the runner plugin declared by
+// the TESTGROUND_CONFORMANCE_* environment variables, e.g.:
+//
+//	TESTGROUND_CONFORMANCE_RUNNER=local:mine \
+//	TESTGROUND_CONFORMANCE_ENDPOINT=localhost:9000 \
+//	TESTGROUND_CONFORMANCE_BUILDER=docker:go \
+//	TESTGROUND_CONFORMANCE_PLACEBO=<artifact> \
+//	TESTGROUND_CONFORMANCE_NETWORK=<artifact> \
+//	go test ./pkg/plugin/conformance
+//
+// TESTGROUND_CONFORMANCE_EXEC can be set instead of the endpoint to test an
+// executable plugin.
+func TestPluginConformance(t *testing.T) {
+	id := os.Getenv("TESTGROUND_CONFORMANCE_RUNNER")
+	if id == "" {
+		t.Skip("TESTGROUND_CONFORMANCE_RUNNER not set")
+	}
+
+	pcfg := config.PluginConfig{
+		Exec:     os.Getenv("TESTGROUND_CONFORMANCE_EXEC"),
+		Endpoint: os.Getenv("TESTGROUND_CONFORMANCE_ENDPOINT"),
+	}
+
+	env := &config.EnvConfig{}
+	require.NoError(t, env.Load())
+
+	r, err := plugin.NewRunner(id, pcfg, env.Dirs().Home())
+	require.NoError(t, err)
+
+	conformance.Run(t, conformance.Config{
+		Runner:          r,
+		Builder:         os.Getenv("TESTGROUND_CONFORMANCE_BUILDER"),
+		ProtocolVersion: plugin.ProtocolVersion,
+		EnvConfig:       *env,
+		RunnerConfig:    env.Runners[id],
+		PlaceboArtifact: os.Getenv("TESTGROUND_CONFORMANCE_PLACEBO"),
+		NetworkArtifact: os.Getenv("TESTGROUND_CONFORMANCE_NETWORK"),
+	})
+}
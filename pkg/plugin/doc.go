@@ -0,0 +1,52 @@
+// Package plugin implements external builders and runners, which live outside
+// of the daemon binary and are declared in the [plugins] section of
+// .env.toml:
+//
+//	[plugins.builders."exec:mylang"]
+//	exec = "/usr/local/bin/testground-mylang-builder"
+//
+//	[plugins.builders."docker:mylang"]
+//	endpoint = "localhost:9000"
+//
+//	[plugins.runners."cluster:nomad"]
+//	endpoint = "localhost:9001"
+//
+// Plugins are reached either by invoking an executable once per call, or by
+// calling a gRPC server. Each call takes a JSON request, and replies with a
+// stream of rpc.Chunk messages: any number of progress (and, for
+// CollectOutputs, binary) chunks, which the daemon relays to the client,
+// followed by exactly one result or error chunk.
+//
+// The protocol is versioned; this package implements ProtocolVersion. All
+// plugins implement the Info method, through which they report their id,
+// protocol version, configuration schema and, for runners, the builders they
+// are compatible with. Builders further implement Build and Purge; runners
+// implement Run, CollectOutputs, TerminateAll and Healthcheck.
+//
+// Executables receive the method as their last argument and the request on
+// stdin, and write the chunks on stdout, one JSON document per line. Lines
+// that are not chunks are relayed as progress, as is anything written to
+// stderr.
+//
+// gRPC servers implement the testground.plugin.v1.Builder or
+// testground.plugin.v1.Runner service, whose methods are all
+// server-streaming, using JSON instead of protobuf as the message encoding
+// (i.e. the application/grpc+json content type).
+//
+// gRPC plugins are called over TLS, configured with the ca_file, cert_file
+// and key_file keys of their declaration, unless their endpoint is on the
+// loopback interface or insecure is set. Requests carry the env
+// configuration of the daemon, stripped of its credentials and tokens;
+// forward_credentials passes the AWS and DockerHub credentials on to plugins
+// that push or pull images.
+//
+// Plugins written in Go can implement api.Builder or api.Runner, and serve
+// them with ServeBuilderExec/RegisterBuilderServer or
+// ServeRunnerExec/RegisterRunnerServer. Configuration is coalesced into the
+// type returned by ConfigType on the plugin side. Plugins must share the
+// filesystem of the daemon, as sources and artifacts are passed by path.
+//
+// The wire format is specified in docs/plugin-protocol.md, for plugins
+// written in other languages. Runner plugins can be verified with the suite
+// in the conformance package.
+package plugin
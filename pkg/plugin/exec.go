@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// execTransport calls a plugin by invoking an executable once per call.
+type execTransport struct {
+	path string
+	args []string
+}
+
+var _ transport = (*execTransport)(nil)
+
+func (t *execTransport) call(ctx context.Context, method string, req interface{}, ow *rpc.OutputWriter, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	args := append(append([]string{}, t.args...), method)
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stderr = progressLineWriter{ow}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", t.path, err)
+	}
+
+	var (
+		rd     = bufio.NewReader(stdout)
+		done   bool
+		result error
+	)
+
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && !done {
+			var c chunk
+			if json.Unmarshal(line, &c) != nil || c.Type == 0 {
+				// not a chunk; relay the line as is.
+				_, _ = ow.WriteProgress(line)
+			} else if done, result = relay(&c, ow, res); result != nil && !done {
+				_ = cmd.Process.Kill()
+				_ = cmd.Wait()
+				return result
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return fmt.Errorf("failed to read plugin output: %w", err)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil && result == nil {
+		result = fmt.Errorf("plugin %s failed: %w", t.path, err)
+	}
+	if !done && result == nil {
+		result = errNoResult
+	}
+	return result
+}
+
+// progressLineWriter relays everything written to it as progress.
+type progressLineWriter struct {
+	ow *rpc.OutputWriter
+}
+
+func (w progressLineWriter) Write(p []byte) (int, error) {
+	if _, err := w.ow.WriteProgress(append([]byte(nil), p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// lineWriter terminates every write with a newline, so that every chunk an
+// OutputWriter emits lands on a line of its own.
+type lineWriter struct {
+	w io.Writer
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && p[len(p)-1] != '\n' {
+		if _, err := w.w.Write(append(append([]byte(nil), p...), '\n')); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/testground/testground/pkg/config"
+
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// grpcTransport calls a plugin exposed as a gRPC service. The connection is
+// established lazily, so that plugins need not be up when the daemon starts.
+type grpcTransport struct {
+	endpoint string
+	service  string
+
+	// creds secures the connection; nil for plaintext.
+	creds credentials.TransportCredentials
+
+	lk   sync.Mutex
+	conn *grpc.ClientConn
+}
+
+var _ transport = (*grpcTransport)(nil)
+
+// newGRPCTransport returns a transport to the gRPC plugin declared by cfg.
+// Plugins are called over TLS, unless they're on the loopback interface and
+// no TLS is configured, or insecure is explicitly set.
+func newGRPCTransport(cfg config.PluginConfig, service string) (*grpcTransport, error) {
+	t := &grpcTransport{endpoint: cfg.Endpoint, service: service}
+
+	hasTLS := cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != ""
+	if cfg.Insecure || (!hasTLS && isLoopback(cfg.Endpoint)) {
+		return t, nil
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("cert_file and key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	t.creds = credentials.NewTLS(tlsCfg)
+	return t, nil
+}
+
+// isLoopback returns whether a host:port endpoint is on the loopback
+// interface.
+func isLoopback(endpoint string) bool {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (t *grpcTransport) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.conn != nil {
+		return t.conn, nil
+	}
+
+	opt := grpc.WithInsecure()
+	if t.creds != nil {
+		opt = grpc.WithTransportCredentials(t.creds)
+	}
+
+	conn, err := grpc.DialContext(ctx, t.endpoint, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial plugin at %s: %w", t.endpoint, err)
+	}
+	t.conn = conn
+	return conn, nil
+}
+
+func (t *grpcTransport) call(ctx context.Context, method string, req interface{}, ow *rpc.OutputWriter, res interface{}) error {
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	desc := &grpc.StreamDesc{StreamName: method, ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, fmt.Sprintf("/%s/%s", t.service, method), grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return fmt.Errorf("failed to call plugin at %s: %w", t.endpoint, err)
+	}
+
+	if err := stream.SendMsg(req); err != nil {
+		return fmt.Errorf("failed to send request to plugin: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		var c chunk
+		err := stream.RecvMsg(&c)
+		if errors.Is(err, io.EOF) {
+			return errNoResult
+		}
+		if err != nil {
+			return fmt.Errorf("failed to receive from plugin: %w", err)
+		}
+
+		if done, err := relay(&c, ow, res); done || err != nil {
+			return err
+		}
+	}
+}
+
+// streamWriter sends every write as a message on a server stream. Every write
+// of an OutputWriter is a whole chunk, so messages are always well-formed.
+type streamWriter struct {
+	stream grpc.ServerStream
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	msg := json.RawMessage(append([]byte(nil), p...))
+	if err := w.stream.SendMsg(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
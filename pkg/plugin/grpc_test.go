@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+func TestGRPCTransportSecurity(t *testing.T) {
+	cases := []struct {
+		name   string
+		cfg    config.PluginConfig
+		secure bool
+	}{
+		{"loopback", config.PluginConfig{Endpoint: "127.0.0.1:9000"}, false},
+		{"localhost", config.PluginConfig{Endpoint: "localhost:9000"}, false},
+		{"remote", config.PluginConfig{Endpoint: "nomad.example.com:9000"}, true},
+		{"remote insecure", config.PluginConfig{Endpoint: "nomad.example.com:9000", Insecure: true}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tr, err := newGRPCTransport(c.cfg, RunnerService)
+			require.NoError(t, err)
+			require.Equal(t, c.secure, tr.creds != nil)
+		})
+	}
+
+	_, err := newGRPCTransport(config.PluginConfig{Endpoint: "127.0.0.1:9000", CertFile: "cert.pem"}, RunnerService)
+	require.EqualError(t, err, "cert_file and key_file must be set together")
+}
+
+func TestGRPCBuilderOverTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert := writeSelfSignedCert(t, dir)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	RegisterBuilderServer(s, &fakeBuilder{})
+	go s.Serve(l) //nolint:errcheck
+	defer s.Stop()
+
+	b, err := NewBuilder("fake:builder", config.PluginConfig{
+		Endpoint: l.Addr().String(),
+		CAFile:   filepath.Join(dir, "cert.pem"),
+	}, "/tg")
+	require.NoError(t, err)
+
+	testBuilderPlugin(t, b)
+
+	// a plaintext client can't reach the server.
+	b, err = NewBuilder("fake:builder", config.PluginConfig{Endpoint: l.Addr().String()}, "/tg")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rep, err := b.Healthcheck(ctx, nil, rpc.Discard(), false)
+	require.NoError(t, err)
+	require.False(t, rep.ChecksSucceeded())
+}
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 to
+// cert.pem in dir, and returns it.
+func writeSelfSignedCert(t *testing.T, dir string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "plugin"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0o600))
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return cert
+}
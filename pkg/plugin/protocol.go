@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// ProtocolVersion is the version of the plugin protocol implemented by this
+// package. It's part of the gRPC service names, and reported by plugins in
+// their InfoResponse.
+const ProtocolVersion = "v1"
+
+// Methods of the plugin protocol. Info is implemented by all plugins.
+const (
+	MethodInfo = "Info"
+
+	// builder methods.
+	MethodBuild = "Build"
+	MethodPurge = "Purge"
+
+	// runner methods.
+	MethodRun            = "Run"
+	MethodCollectOutputs = "CollectOutputs"
+	MethodTerminateAll   = "TerminateAll"
+	MethodHealthcheck    = "Healthcheck"
+)
+
+// BuildRequest is the request of the Build method.
+type BuildRequest struct {
+	// Home is the testground home directory of the daemon, which the
+	// directories of the env configuration derive from.
+	Home string `json:"home"`
+
+	Input *api.BuildInput `json:"input"`
+}
+
+// PurgeRequest is the request of the Purge method.
+type PurgeRequest struct {
+	Home     string `json:"home"`
+	TestPlan string `json:"test_plan"`
+}
+
+// RunRequest is the request of the Run method.
+type RunRequest struct {
+	Home  string        `json:"home"`
+	Input *api.RunInput `json:"input"`
+}
+
+// CollectOutputsRequest is the request of the CollectOutputs method. The
+// plugin writes the outputs archive as binary chunks.
+type CollectOutputsRequest struct {
+	Home  string               `json:"home"`
+	Input *api.CollectionInput `json:"input"`
+}
+
+// TerminateAllRequest is the request of the TerminateAll method.
+type TerminateAllRequest struct {
+	Home string `json:"home"`
+}
+
+// HealthcheckRequest is the request of the Healthcheck method.
+type HealthcheckRequest struct {
+	Home      string           `json:"home"`
+	EnvConfig config.EnvConfig `json:"env_config"`
+	Fix       bool             `json:"fix"`
+}
+
+// InfoResponse is the result of the Info method.
+type InfoResponse struct {
+	// ID is the id the plugin identifies as; it must match the id the plugin
+	// is registered under.
+	ID string `json:"id"`
+
+	// ProtocolVersion is the version of the protocol the plugin implements.
+	ProtocolVersion string `json:"protocol_version"`
+
+	// CompatibleBuilders enumerates the builders whose artifacts a runner
+	// plugin can work with. Empty for builder plugins.
+	CompatibleBuilders []string `json:"compatible_builders,omitempty"`
+
+	// ConfigSchema describes the configuration the plugin accepts.
+	ConfigSchema []ConfigField `json:"config_schema,omitempty"`
+}
+
+// ConfigField describes a key of the configuration of a plugin.
+type ConfigField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// transport carries calls to a plugin.
+type transport interface {
+	// call invokes a method of the plugin, relaying progress chunks to ow,
+	// and decoding the result chunk into res, if non-nil.
+	call(ctx context.Context, method string, req interface{}, ow *rpc.OutputWriter, res interface{}) error
+}
+
+// chunk mirrors rpc.Chunk, deferring the decoding of the payload until the
+// chunk type is known.
+type chunk struct {
+	Type    rpc.ChunkType   `json:"t"`
+	Payload json.RawMessage `json:"p,omitempty"`
+	Error   *rpc.Error      `json:"e,omitempty"`
+}
+
+// errNoResult is returned when a plugin call ends without a result or error
+// chunk.
+var errNoResult = errors.New("plugin call ended without a result")
+
+// relay processes a chunk received from a plugin. It returns true once the
+// call has completed, i.e. when a result or error chunk is received.
+func relay(c *chunk, ow *rpc.OutputWriter, res interface{}) (bool, error) {
+	switch c.Type {
+	case rpc.ChunkTypeProgress:
+		var p []byte
+		if err := json.Unmarshal(c.Payload, &p); err != nil {
+			return false, fmt.Errorf("failed to decode progress chunk: %w", err)
+		}
+		_, err := ow.WriteProgress(p)
+		return false, err
+
+	case rpc.ChunkTypeBinary:
+		var p []byte
+		if err := json.Unmarshal(c.Payload, &p); err != nil {
+			return false, fmt.Errorf("failed to decode binary chunk: %w", err)
+		}
+		_, err := ow.WriteBinary(p)
+		return false, err
+
+	case rpc.ChunkTypeResult:
+		if res == nil || len(c.Payload) == 0 {
+			return true, nil
+		}
+		if err := json.Unmarshal(c.Payload, res); err != nil {
+			return true, fmt.Errorf("failed to decode plugin result: %w", err)
+		}
+		return true, nil
+
+	case rpc.ChunkTypeError:
+		msg := "unknown error"
+		if c.Error != nil {
+			msg = c.Error.Msg
+		}
+		return true, errors.New(msg)
+
+	default:
+		return false, fmt.Errorf("unknown chunk type: %c", c.Type)
+	}
+}
@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// RunnerService is the name of the gRPC service runner plugins implement.
+const RunnerService = "testground.plugin." + ProtocolVersion + ".Runner"
+
+// infoTimeout bounds the Info call issued to discover the builders a runner
+// plugin is compatible with.
+const infoTimeout = 10 * time.Second
+
+// Runner is an api.Runner backed by an external plugin.
+type Runner struct {
+	id   string
+	home string
+	t    transport
+
+	// credentials forwards the registry credentials of the env
+	// configuration to the plugin.
+	credentials bool
+
+	lk   sync.Mutex
+	info *InfoResponse
+}
+
+var (
+	_ api.Runner        = (*Runner)(nil)
+	_ api.Terminatable  = (*Runner)(nil)
+	_ api.Healthchecker = (*Runner)(nil)
+)
+
+// NewRunner returns a Runner registered under id, which calls the plugin
+// declared by cfg. home is the testground home directory passed on to the
+// plugin.
+func NewRunner(id string, cfg config.PluginConfig, home string) (*Runner, error) {
+	t, err := newTransport(cfg, RunnerService)
+	if err != nil {
+		return nil, fmt.Errorf("invalid runner plugin %s: %w", id, err)
+	}
+	return &Runner{id: id, home: home, t: t, credentials: cfg.ForwardCredentials}, nil
+}
+
+// Runners returns the runner plugins declared in the env configuration.
+func Runners(cfg *config.EnvConfig) ([]api.Runner, error) {
+	runners := make([]api.Runner, 0, len(cfg.Plugins.Runners))
+	for id, pcfg := range cfg.Plugins.Runners {
+		r, err := NewRunner(id, pcfg, cfg.Dirs().Home())
+		if err != nil {
+			return nil, err
+		}
+		runners = append(runners, r)
+	}
+	return runners, nil
+}
+
+// Info returns the information the plugin reports about itself. It's cached
+// after the first successful call.
+func (r *Runner) Info(ctx context.Context) (*InfoResponse, error) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if r.info != nil {
+		return r.info, nil
+	}
+
+	resp, err := info(ctx, r.t, r.id, rpc.Discard())
+	if err != nil {
+		return nil, err
+	}
+	r.info = resp
+	return resp, nil
+}
+
+// Handshake calls the Info method of the plugin, and returns the protocol
+// version it reports, without requiring it to match ours. It implements
+// conformance.Handshaker.
+func (r *Runner) Handshake(ctx context.Context) (string, error) {
+	resp, err := handshake(ctx, r.t, r.id, rpc.Discard())
+	if err != nil {
+		return "", err
+	}
+	return resp.ProtocolVersion, nil
+}
+
+func (r *Runner) ID() string {
+	return r.id
+}
+
+func (r *Runner) Run(ctx context.Context, in *api.RunInput, ow *rpc.OutputWriter) (*api.RunOutput, error) {
+	info, err := r.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg, ok := in.RunnerConfig.(*config.ConfigMap); ok && cfg != nil {
+		if err := validateConfig(info.ConfigSchema, withoutDisabledFlag(*cfg)); err != nil {
+			return nil, fmt.Errorf("invalid configuration for runner %s: %w", r.id, err)
+		}
+	}
+
+	// the input is shared with the engine; strip credentials off a copy.
+	input := *in
+	input.EnvConfig = in.EnvConfig.WithoutCredentials(r.credentials)
+
+	req := &RunRequest{
+		Home:  r.home,
+		Input: &input,
+	}
+
+	var out api.RunOutput
+	if err := r.t.call(ctx, MethodRun, req, ow, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *Runner) CollectOutputs(ctx context.Context, in *api.CollectionInput, ow *rpc.OutputWriter) error {
+	req := &CollectOutputsRequest{
+		Home:  r.home,
+		Input: in,
+	}
+	return r.t.call(ctx, MethodCollectOutputs, req, ow, nil)
+}
+
+func (r *Runner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
+	req := &TerminateAllRequest{Home: r.home}
+	return r.t.call(ctx, MethodTerminateAll, req, ow, nil)
+}
+
+// ConfigType returns a generic map, as the configuration is coalesced into
+// its actual type by the plugin. Keys are validated against the schema the
+// plugin reports before running.
+func (r *Runner) ConfigType() reflect.Type {
+	return reflect.TypeOf(config.ConfigMap{})
+}
+
+// CompatibleBuilders returns the builders reported by the plugin. It returns
+// nil if the plugin can't be reached, which renders the runner incompatible
+// with every builder.
+func (r *Runner) CompatibleBuilders() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+	defer cancel()
+
+	info, err := r.Info(ctx)
+	if err != nil {
+		logging.S().Warnw("failed to query runner plugin", "runner", r.id, "err", err)
+		return nil
+	}
+	return info.CompatibleBuilders
+}
+
+// Healthcheck verifies that the plugin is reachable and identifies itself
+// with the id it's registered under, and then delegates to the healthcheck
+// of the plugin.
+func (r *Runner) Healthcheck(ctx context.Context, engine api.Engine, ow *rpc.OutputWriter, fix bool) (*api.HealthcheckReport, error) {
+	_, item := checkInfo(ctx, r.t, r.id, ow)
+	if item.Status != api.HealthcheckStatusOK {
+		return &api.HealthcheckReport{Checks: []api.HealthcheckItem{item}}, nil
+	}
+
+	req := &HealthcheckRequest{Home: r.home, Fix: fix}
+	if engine != nil {
+		req.EnvConfig = engine.EnvConfig().WithoutCredentials(r.credentials)
+	}
+
+	var rep api.HealthcheckReport
+	if err := r.t.call(ctx, MethodHealthcheck, req, ow, &rep); err != nil {
+		return nil, err
+	}
+	rep.Checks = append([]api.HealthcheckItem{item}, rep.Checks...)
+	return &rep, nil
+}
+
+// withoutDisabledFlag returns cfg without the flag the engine uses to
+// disable runners, which is not part of the configuration of any runner.
+func withoutDisabledFlag(cfg config.ConfigMap) map[string]interface{} {
+	m := make(map[string]interface{}, len(cfg))
+	for k, v := range cfg {
+		if k != config.RunnerDisabledFlag {
+			m[k] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/plugin/conformance"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+type fakeRunnerConfig struct {
+	Region string `toml:"region"`
+}
+
+// fakeRunner succeeds on the test cases the conformance suite expects to
+// succeed, and fails on the others.
+type fakeRunner struct{}
+
+var (
+	_ api.Terminatable  = (*fakeRunner)(nil)
+	_ api.Healthchecker = (*fakeRunner)(nil)
+)
+
+func (*fakeRunner) ID() string {
+	return "fake:runner"
+}
+
+func (*fakeRunner) Run(ctx context.Context, in *api.RunInput, ow *rpc.OutputWriter) (*api.RunOutput, error) {
+	if _, ok := in.RunnerConfig.(*fakeRunnerConfig); !ok {
+		return nil, fmt.Errorf("expected configuration type fakeRunnerConfig, was: %T", in.RunnerConfig)
+	}
+
+	outcome := "success"
+	switch in.TestCase {
+	case "panic", "abort":
+		outcome = "failure"
+	}
+
+	ow.Infow("running fake test case", "case", in.TestCase)
+
+	outcomes := make(map[string]interface{}, len(in.Groups))
+	for _, g := range in.Groups {
+		ok := g.Instances
+		if outcome != "success" {
+			ok = 0
+		}
+		outcomes[g.ID] = map[string]interface{}{"ok": ok, "total": g.Instances}
+	}
+
+	return &api.RunOutput{
+		RunID:  in.RunID,
+		Result: map[string]interface{}{"outcome": outcome, "outcomes": outcomes},
+	}, nil
+}
+
+func (*fakeRunner) ConfigType() reflect.Type {
+	return reflect.TypeOf(fakeRunnerConfig{})
+}
+
+func (*fakeRunner) CompatibleBuilders() []string {
+	return []string{"docker:go"}
+}
+
+func (*fakeRunner) CollectOutputs(ctx context.Context, in *api.CollectionInput, ow *rpc.OutputWriter) error {
+	_, err := ow.WriteBinary([]byte("outputs of " + in.RunID))
+	return err
+}
+
+func (*fakeRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
+	return nil
+}
+
+func (*fakeRunner) Healthcheck(ctx context.Context, engine api.Engine, ow *rpc.OutputWriter, fix bool) (*api.HealthcheckReport, error) {
+	item := api.HealthcheckItem{Name: "home", Status: api.HealthcheckStatusOK}
+	if home := engine.EnvConfig().Dirs().Home(); home != "/tg" {
+		item.Status = api.HealthcheckStatusFailed
+		item.Message = "unexpected home: " + home
+	}
+	return &api.HealthcheckReport{Checks: []api.HealthcheckItem{item}}, nil
+}
+
+// TestHelperRunnerPlugin is not a real test; it's the executable plugin
+// invoked by TestExecRunner.
+func TestHelperRunnerPlugin(t *testing.T) {
+	if os.Getenv("TESTGROUND_PLUGIN_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	if err := ServeRunnerExec(&fakeRunner{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func testRunnerPlugin(t *testing.T, r *Runner) {
+	info, err := r.Info(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, ProtocolVersion, info.ProtocolVersion)
+	require.Equal(t, []ConfigField{{Name: "region", Type: "string"}}, info.ConfigSchema)
+
+	conformance.Run(t, conformance.Config{
+		Runner:          r,
+		Builder:         "docker:go",
+		ProtocolVersion: ProtocolVersion,
+		RunnerConfig:    config.ConfigMap{"region": "eu"},
+		PlaceboArtifact: "placebo:latest",
+		NetworkArtifact: "network:latest",
+	})
+
+	// keys outside of the schema are rejected before reaching the plugin.
+	_, err = r.Run(context.Background(), &api.RunInput{
+		RunnerConfig: &config.ConfigMap{"zone": "a", config.RunnerDisabledFlag: false},
+	}, rpc.Discard())
+	require.EqualError(t, err, "invalid configuration for runner fake:runner: unknown configuration keys: zone")
+}
+
+func TestGRPCRunner(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	RegisterRunnerServer(s, &fakeRunner{})
+	go s.Serve(l) //nolint:errcheck
+	defer s.Stop()
+
+	r, err := NewRunner("fake:runner", config.PluginConfig{Endpoint: l.Addr().String()}, "/tg")
+	require.NoError(t, err)
+
+	testRunnerPlugin(t, r)
+}
+
+func TestExecRunner(t *testing.T) {
+	os.Setenv("TESTGROUND_PLUGIN_HELPER", "1")
+	defer os.Unsetenv("TESTGROUND_PLUGIN_HELPER")
+
+	r, err := NewRunner("fake:runner", config.PluginConfig{
+		Exec: os.Args[0],
+		Args: []string{"-test.run=^TestHelperRunnerPlugin$", "--"},
+	}, "/tg")
+	require.NoError(t, err)
+
+	testRunnerPlugin(t, r)
+}
+
+func TestUnreachableRunner(t *testing.T) {
+	r, err := NewRunner("fake:runner", config.PluginConfig{Exec: "/nonexistent"}, "/tg")
+	require.NoError(t, err)
+
+	require.Empty(t, r.CompatibleBuilders())
+
+	rep, err := r.Healthcheck(context.Background(), nil, rpc.Discard(), false)
+	require.NoError(t, err)
+	require.False(t, rep.ChecksSucceeded())
+}
+
+// infoTransport answers Info calls with a fixed response.
+type infoTransport struct {
+	resp InfoResponse
+}
+
+func (t infoTransport) call(ctx context.Context, method string, req interface{}, ow *rpc.OutputWriter, res interface{}) error {
+	*res.(*InfoResponse) = t.resp
+	return nil
+}
+
+func TestRunnerHandshake(t *testing.T) {
+	r := &Runner{id: "fake:runner", t: infoTransport{InfoResponse{ID: "fake:runner", ProtocolVersion: "v0"}}}
+
+	// the handshake reports the version of the plugin, which Info refuses.
+	v, err := r.Handshake(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "v0", v)
+
+	_, err = r.Info(context.Background())
+	require.EqualError(t, err, `plugin implements protocol "v0"; expected "v1"`)
+
+	r = &Runner{id: "fake:runner", t: infoTransport{InfoResponse{ID: "other:runner", ProtocolVersion: ProtocolVersion}}}
+	_, err = r.Handshake(context.Background())
+	require.EqualError(t, err, "plugin identifies as other:runner; registered as fake:runner")
+}
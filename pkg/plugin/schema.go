@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// configSchema describes the configuration keys of typ, as declared by the
+// toml tags of its fields. It returns nil for types other than structs, e.g.
+// config.ConfigMap, which accept any key.
+func configSchema(typ reflect.Type) []ConfigField {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []ConfigField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported.
+		}
+		name := strings.Split(f.Tag.Get("toml"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields = append(fields, ConfigField{Name: name, Type: kindOf(f.Type)})
+	}
+	return fields
+}
+
+func kindOf(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Ptr:
+		return kindOf(typ.Elem())
+	default:
+		return "table"
+	}
+}
+
+// validateConfig verifies that cfg only contains keys declared in schema. An
+// empty schema accepts any key.
+func validateConfig(schema []ConfigField, cfg map[string]interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	known := make(map[string]struct{}, len(schema))
+	for _, f := range schema {
+		known[f.Name] = struct{}{}
+	}
+
+	var unknown []string
+	for k := range cfg {
+		if _, ok := known[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown configuration keys: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// ServeBuilderExec serves a single call of the builder protocol, as an
+// executable plugin: the method is read from the last command line argument,
+// the request from stdin, and the chunks are written on stdout.
+//
+// Anything else written on stdout is relayed to the user as progress, but
+// builders should log through the OutputWriter they are handed.
+func ServeBuilderExec(b api.Builder) error {
+	if len(os.Args) < 2 {
+		return fmt.Errorf("usage: %s <method>", os.Args[0])
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	method := os.Args[len(os.Args)-1]
+	return serveBuilder(ctx, b, method, json.NewDecoder(os.Stdin).Decode, lineWriter{os.Stdout})
+}
+
+// RegisterBuilderServer registers b as the implementation of the builder
+// service on s.
+func RegisterBuilderServer(s *grpc.Server, b api.Builder) {
+	handler := func(method string) grpc.StreamHandler {
+		return func(srv interface{}, stream grpc.ServerStream) error {
+			return serveBuilder(stream.Context(), srv.(api.Builder), method, stream.RecvMsg, streamWriter{stream})
+		}
+	}
+
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: BuilderService,
+		HandlerType: (*api.Builder)(nil),
+		Streams: []grpc.StreamDesc{
+			{StreamName: MethodInfo, Handler: handler(MethodInfo), ServerStreams: true},
+			{StreamName: MethodBuild, Handler: handler(MethodBuild), ServerStreams: true},
+			{StreamName: MethodPurge, Handler: handler(MethodPurge), ServerStreams: true},
+		},
+	}, b)
+}
+
+// serveBuilder decodes the request of a call, dispatches it to the builder,
+// and writes the outcome as chunks on w. Errors returned by the builder are
+// sent to the caller as error chunks; only errors of the transport itself are
+// returned.
+func serveBuilder(ctx context.Context, b api.Builder, method string, decode func(interface{}) error, w io.Writer) error {
+	ow := rpc.NewChunkOutputWriter(w)
+
+	switch method {
+	case MethodInfo:
+		var req struct{}
+		if err := decode(&req); err != nil {
+			return err
+		}
+		ow.WriteResult(&InfoResponse{
+			ID:              b.ID(),
+			ProtocolVersion: ProtocolVersion,
+			ConfigSchema:    configSchema(b.ConfigType()),
+		})
+
+	case MethodBuild:
+		var req BuildRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		if req.Input == nil {
+			ow.WriteError("build request without input")
+			return nil
+		}
+
+		in := req.Input
+		in.EnvConfig = in.EnvConfig.WithHome(req.Home)
+
+		cfg, err := coalesce(in.BuildConfig, b.ConfigType())
+		if err != nil {
+			ow.WriteError("invalid build configuration", "err", err)
+			return nil
+		}
+		in.BuildConfig = cfg
+
+		out, err := b.Build(ctx, in, ow)
+		if err != nil {
+			ow.WriteError(err.Error())
+			return nil
+		}
+		ow.WriteResult(out)
+
+	case MethodPurge:
+		var req PurgeRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		if err := b.Purge(ctx, req.TestPlan, ow); err != nil {
+			ow.WriteError(err.Error())
+			return nil
+		}
+		ow.WriteResult(nil)
+
+	default:
+		ow.WriteError(fmt.Sprintf("unknown method: %s", method))
+	}
+
+	return nil
+}
+
+// ServeRunnerExec serves a single call of the runner protocol, as an
+// executable plugin. Refer to ServeBuilderExec for details.
+func ServeRunnerExec(r api.Runner) error {
+	if len(os.Args) < 2 {
+		return fmt.Errorf("usage: %s <method>", os.Args[0])
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	method := os.Args[len(os.Args)-1]
+	return serveRunner(ctx, r, method, json.NewDecoder(os.Stdin).Decode, lineWriter{os.Stdout})
+}
+
+// RegisterRunnerServer registers r as the implementation of the runner
+// service on s.
+func RegisterRunnerServer(s *grpc.Server, r api.Runner) {
+	handler := func(method string) grpc.StreamHandler {
+		return func(srv interface{}, stream grpc.ServerStream) error {
+			return serveRunner(stream.Context(), srv.(api.Runner), method, stream.RecvMsg, streamWriter{stream})
+		}
+	}
+
+	methods := []string{MethodInfo, MethodRun, MethodCollectOutputs, MethodTerminateAll, MethodHealthcheck}
+	streams := make([]grpc.StreamDesc, 0, len(methods))
+	for _, m := range methods {
+		streams = append(streams, grpc.StreamDesc{StreamName: m, Handler: handler(m), ServerStreams: true})
+	}
+
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: RunnerService,
+		HandlerType: (*api.Runner)(nil),
+		Streams:     streams,
+	}, r)
+}
+
+// serveRunner decodes the request of a call, dispatches it to the runner,
+// and writes the outcome as chunks on w. Refer to serveBuilder for details.
+func serveRunner(ctx context.Context, r api.Runner, method string, decode func(interface{}) error, w io.Writer) error {
+	ow := rpc.NewChunkOutputWriter(w)
+
+	switch method {
+	case MethodInfo:
+		var req struct{}
+		if err := decode(&req); err != nil {
+			return err
+		}
+		ow.WriteResult(&InfoResponse{
+			ID:                 r.ID(),
+			ProtocolVersion:    ProtocolVersion,
+			CompatibleBuilders: r.CompatibleBuilders(),
+			ConfigSchema:       configSchema(r.ConfigType()),
+		})
+
+	case MethodRun:
+		var req RunRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		if req.Input == nil {
+			ow.WriteError("run request without input")
+			return nil
+		}
+
+		in := req.Input
+		in.EnvConfig = in.EnvConfig.WithHome(req.Home)
+
+		cfg, err := coalesce(in.RunnerConfig, r.ConfigType())
+		if err != nil {
+			ow.WriteError("invalid run configuration", "err", err)
+			return nil
+		}
+		in.RunnerConfig = cfg
+
+		out, err := r.Run(ctx, in, ow)
+		if err != nil {
+			ow.WriteError(err.Error())
+			return nil
+		}
+		ow.WriteResult(out)
+
+	case MethodCollectOutputs:
+		var req CollectOutputsRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		if req.Input == nil {
+			ow.WriteError("collect outputs request without input")
+			return nil
+		}
+
+		in := req.Input
+		in.EnvConfig = in.EnvConfig.WithHome(req.Home)
+
+		cfg, err := coalesce(in.RunnerConfig, r.ConfigType())
+		if err != nil {
+			ow.WriteError("invalid run configuration", "err", err)
+			return nil
+		}
+		in.RunnerConfig = cfg
+
+		if err := r.CollectOutputs(ctx, in, ow); err != nil {
+			ow.WriteError(err.Error())
+			return nil
+		}
+		ow.WriteResult(nil)
+
+	case MethodTerminateAll:
+		var req TerminateAllRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		t, ok := r.(api.Terminatable)
+		if !ok {
+			ow.WriteError(fmt.Sprintf("runner %s is not terminatable", r.ID()))
+			return nil
+		}
+		if err := t.TerminateAll(ctx, ow); err != nil {
+			ow.WriteError(err.Error())
+			return nil
+		}
+		ow.WriteResult(nil)
+
+	case MethodHealthcheck:
+		var req HealthcheckRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		hc, ok := r.(api.Healthchecker)
+		if !ok {
+			// nothing to check beyond the reachability of the plugin.
+			ow.WriteResult(&api.HealthcheckReport{})
+			return nil
+		}
+		engine := &pluginEngine{ctx: ctx, env: req.EnvConfig.WithHome(req.Home)}
+		rep, err := hc.Healthcheck(ctx, engine, ow, req.Fix)
+		if err != nil {
+			ow.WriteError(err.Error())
+			return nil
+		}
+		ow.WriteResult(rep)
+
+	default:
+		ow.WriteError(fmt.Sprintf("unknown method: %s", method))
+	}
+
+	return nil
+}
+
+// pluginEngine is the api.Engine handed to the healthchecks of runner
+// plugins. The engine lives in the daemon, so only its configuration and
+// context are available; calling any other method panics.
+type pluginEngine struct {
+	api.Engine
+
+	ctx context.Context
+	env config.EnvConfig
+}
+
+func (e *pluginEngine) EnvConfig() config.EnvConfig {
+	return e.env
+}
+
+func (e *pluginEngine) Context() context.Context {
+	return e.ctx
+}
+
+// coalesce converts a configuration decoded from JSON into the type a
+// builder or runner expects.
+func coalesce(v interface{}, typ reflect.Type) (interface{}, error) {
+	if typ == nil {
+		return v, nil
+	}
+	m, _ := normalizeNumbers(v).(map[string]interface{})
+	return config.CoalescedConfig{m}.CoalesceIntoType(typ)
+}
+
+// normalizeNumbers converts the integral numbers of a decoded JSON value into
+// integers. JSON decodes all numbers as floats, which TOML (used to coalesce
+// configurations) refuses to decode into integer fields.
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+		return v
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeNumbers(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeNumbers(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// dockerConfig is the subset of the config.json of the docker CLI that holds
+// credentials.
+type dockerConfig struct {
+	Auths       map[string]types.AuthConfig `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+// DefaultDockerConfig returns the path of the config.json of the docker CLI,
+// honouring DOCKER_CONFIG.
+func DefaultDockerConfig() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadAuth returns the credentials of the docker config.json at path for the
+// registry at host, like `docker login` stored them: through the credential
+// helper of the registry, or else through the credentials store, or else
+// inline. It returns empty credentials if there are none, or if the file
+// doesn't exist, for anonymous access.
+func LoadAuth(path, host string) (types.AuthConfig, error) {
+	none := types.AuthConfig{ServerAddress: host}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return none, nil
+	} else if err != nil {
+		return none, fmt.Errorf("failed to read docker config: %w", err)
+	}
+
+	var cfg dockerConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return none, fmt.Errorf("failed to parse docker config %s: %w", path, err)
+	}
+
+	if helper, ok := cfg.CredHelpers[host]; ok {
+		return helperAuth(helper, host)
+	}
+	if cfg.CredsStore != "" {
+		return helperAuth(cfg.CredsStore, host)
+	}
+
+	for server, auth := range cfg.Auths {
+		if hostOf(server) != host {
+			continue
+		}
+		auth.ServerAddress = host
+		if auth.Auth != "" {
+			dec, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return none, fmt.Errorf("failed to decode auth of %s: %w", server, err)
+			}
+			splt := strings.SplitN(string(dec), ":", 2)
+			if len(splt) != 2 {
+				return none, fmt.Errorf("unexpected format of auth of %s", server)
+			}
+			auth.Username, auth.Password, auth.Auth = splt[0], splt[1], ""
+		}
+		return auth, nil
+	}
+	return none, nil
+}
+
+// helperAuth gets the credentials for host from a docker credential helper,
+// i.e. the docker-credential-<helper> executable.
+func helperAuth(helper, host string) (types.AuthConfig, error) {
+	none := types.AuthConfig{ServerAddress: host}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(host)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// helpers report missing credentials on stdout.
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return none, nil
+		}
+		return none, fmt.Errorf("credential helper %s failed: %w: %s", helper, err, strings.TrimSpace(stderr.String()+stdout.String()))
+	}
+
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return none, fmt.Errorf("failed to parse the output of credential helper %s: %w", helper, err)
+	}
+
+	// this is how helpers store identity tokens.
+	if creds.Username == "<token>" {
+		return types.AuthConfig{ServerAddress: host, IdentityToken: creds.Secret}, nil
+	}
+	return types.AuthConfig{ServerAddress: host, Username: creds.Username, Password: creds.Secret}, nil
+}
+
+// EncodeAuth encodes credentials the way the docker API expects them in the
+// X-Registry-Auth header, e.g. for pushes.
+func EncodeAuth(auth types.AuthConfig) string {
+	b, _ := json.Marshal(auth)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// hostOf returns the host of a registry address, which may be a URL.
+func hostOf(addr string) string {
+	if i := strings.Index(addr, "://"); i >= 0 {
+		addr = addr[i+3:]
+	}
+	return strings.SplitN(addr, "/", 2)[0]
+}
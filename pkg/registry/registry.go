@@ -0,0 +1,252 @@
+// Package registry is a client of generic OCI registries, such as Harbor or
+// registry:2, speaking the distribution API just enough to push images
+// through docker only when needed.
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/testground/testground/pkg/config"
+)
+
+// manifestTypes are the media types of the image manifests we understand.
+// Manifest lists are not, as pushes of docker don't produce them.
+var manifestTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// Client is a client of a registry.
+type Client struct {
+	host   string
+	scheme string
+	prefix string
+	auth   types.AuthConfig
+	http   *http.Client
+}
+
+// New returns a client of the registry of cfg, with the credentials found in
+// its docker config.
+func New(cfg config.RegistryConfig) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("registry url not set")
+	}
+
+	c := &Client{
+		host:   hostOf(cfg.URL),
+		scheme: "https",
+		prefix: strings.Trim(cfg.Repository, "/"),
+	}
+	switch {
+	case strings.HasPrefix(cfg.URL, "http://"):
+		c.scheme = "http"
+	case strings.HasPrefix(cfg.URL, "https://"):
+	case cfg.Insecure:
+		c.scheme = "http"
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in registry CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.http = &http.Client{Transport: transport, Timeout: time.Minute}
+
+	dockerConfig := cfg.DockerConfig
+	if dockerConfig == "" {
+		dockerConfig = DefaultDockerConfig()
+	}
+	auth, err := LoadAuth(dockerConfig, c.host)
+	if err != nil {
+		return nil, err
+	}
+	c.auth = auth
+
+	return c, nil
+}
+
+// Auth returns the credentials of the registry.
+func (c *Client) Auth() types.AuthConfig {
+	return c.auth
+}
+
+// Repository returns the reference of the repository of images by name, under
+// the prefix of the registry, e.g. harbor.example.com/testground/network.
+func (c *Client) Repository(name string) string {
+	return c.host + "/" + c.path(name)
+}
+
+func (c *Client) path(name string) string {
+	return path.Join(c.prefix, name)
+}
+
+// ConfigDigest returns the digest of the config of the image tagged in the
+// repository by name, which is the ID of the image, or "" if there is no such
+// tag.
+func (c *Client) ConfigDigest(ctx context.Context, name, tag string) (string, error) {
+	u := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", c.scheme, c.host, c.path(name), tag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unexpected status fetching manifest %s:%s: %s", c.path(name), tag, resp.Status)
+	}
+
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return "", fmt.Errorf("failed to decode manifest %s:%s: %w", c.path(name), tag, err)
+	}
+	return manifest.Config.Digest, nil
+}
+
+// do performs a request, authenticating as challenged by the registry, with
+// basic auth or with a bearer token from its token service.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+
+	scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	retry := req.Clone(req.Context())
+	switch strings.ToLower(scheme) {
+	case "basic":
+		retry.SetBasicAuth(c.auth.Username, c.auth.Password)
+	case "bearer":
+		token, err := c.token(req.Context(), params)
+		if err != nil {
+			return nil, err
+		}
+		retry.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return nil, fmt.Errorf("unsupported registry authentication challenge: %q", resp.Header.Get("WWW-Authenticate"))
+	}
+	return c.http.Do(retry)
+}
+
+// token fetches a bearer token from the token service of the registry.
+func (c *Client) token(ctx context.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid token realm in registry challenge: %q", params["realm"])
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			q.Set(k, v)
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.auth.Username != "" {
+		req.SetBasicAuth(c.auth.Username, c.auth.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to get registry token: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header, e.g.
+// Bearer realm="https://auth.example.com/token",service="registry".
+func parseChallenge(header string) (scheme string, params map[string]string) {
+	params = make(map[string]string)
+
+	splt := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	scheme = splt[0]
+	if len(splt) == 1 {
+		return scheme, params
+	}
+
+	rest := splt[1]
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = strings.TrimSpace(rest[:end]), rest[end:]
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/config"
+)
+
+const testImageID = "sha256:3cde7451eb28a3199f2c7d4e8e02a98f2e96b9a34dd4a9bc7eeaa5a192a1536f"
+
+// newTestRegistry returns a registry holding testground/plan:3cde7451eb28,
+// which authenticates clients with bearer tokens for user:pass, like Harbor.
+func newTestRegistry(t *testing.T, tls bool) *httptest.Server {
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pwd, ok := r.BasicAuth()
+		if !ok || user != "user" || pwd != "pass" || r.URL.Query().Get("service") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token": "t0ken"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:testground/plan:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/testground/plan/manifests/3cde7451eb28" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", manifestTypes[0])
+		fmt.Fprintf(w, `{"schemaVersion": 2, "mediaType": %q, "config": {"digest": %q}}`, manifestTypes[0], testImageID)
+	})
+
+	if tls {
+		srv = httptest.NewTLSServer(mux)
+	} else {
+		srv = httptest.NewServer(mux)
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writeDockerConfig writes a docker config.json with inline credentials of
+// user:pass for host.
+func writeDockerConfig(t *testing.T, host string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	cfg := fmt.Sprintf(`{"auths": {"https://%s": {"auth": %q}}}`, host, auth)
+	require.NoError(t, os.WriteFile(path, []byte(cfg), 0600))
+	return path
+}
+
+func TestConfigDigest(t *testing.T) {
+	srv := newTestRegistry(t, false)
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	reg, err := New(config.RegistryConfig{
+		URL:          host,
+		Repository:   "testground",
+		DockerConfig: writeDockerConfig(t, host),
+		Insecure:     true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, host+"/testground/plan", reg.Repository("plan"))
+	require.Equal(t, "user", reg.Auth().Username)
+
+	digest, err := reg.ConfigDigest(context.Background(), "plan", "3cde7451eb28")
+	require.NoError(t, err)
+	require.Equal(t, testImageID, digest)
+
+	digest, err = reg.ConfigDigest(context.Background(), "plan", "000000000000")
+	require.NoError(t, err)
+	require.Empty(t, digest)
+}
+
+func TestConfigDigestWithCAFile(t *testing.T) {
+	srv := newTestRegistry(t, true)
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(ca, cert, 0600))
+
+	reg, err := New(config.RegistryConfig{
+		URL:          srv.URL,
+		Repository:   "testground",
+		DockerConfig: writeDockerConfig(t, host),
+		CAFile:       ca,
+	})
+	require.NoError(t, err)
+
+	digest, err := reg.ConfigDigest(context.Background(), "plan", "3cde7451eb28")
+	require.NoError(t, err)
+	require.Equal(t, testImageID, digest)
+}
+
+func TestLoadAuthFromHelper(t *testing.T) {
+	dir := t.TempDir()
+	helper := "#!/bin/sh\nread server\necho \"{\\\"ServerURL\\\": \\\"$server\\\", \\\"Username\\\": \\\"helped\\\", \\\"Secret\\\": \\\"s3cret\\\"}\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(helper), 0700))
+
+	prev := os.Getenv("PATH")
+	require.NoError(t, os.Setenv("PATH", dir+string(os.PathListSeparator)+prev))
+	t.Cleanup(func() { _ = os.Setenv("PATH", prev) })
+
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"credHelpers": {"harbor.example.com": "test"}}`), 0600))
+
+	auth, err := LoadAuth(path, "harbor.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "helped", auth.Username)
+	require.Equal(t, "s3cret", auth.Password)
+
+	auth, err = LoadAuth(path, "other.example.com")
+	require.NoError(t, err)
+	require.Empty(t, auth.Username)
+}
+
+func TestLoadAuthWithoutConfig(t *testing.T) {
+	auth, err := LoadAuth(filepath.Join(t.TempDir(), "config.json"), "localhost:5000")
+	require.NoError(t, err)
+	require.Empty(t, auth.Username)
+	require.Equal(t, "localhost:5000", auth.ServerAddress)
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="harbor-registry",scope="repository:a/b:pull,push"`)
+	require.Equal(t, "Bearer", scheme)
+	require.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "harbor-registry",
+		"scope":   "repository:a/b:pull,push",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm=registry`)
+	require.Equal(t, "Basic", scheme)
+	require.Equal(t, map[string]string{"realm": "registry"}, params)
+}
@@ -0,0 +1,143 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// FrameType identifies the stream a frame of an attach session belongs to.
+//
+// Once the daemon has upgraded the connection of an attach request, both
+// ends exchange frames made of a one-byte type, the big endian uint32
+// length of the payload, and the payload itself.
+type FrameType byte
+
+const (
+	// FrameStdin carries the input of the command, from the client. An
+	// empty frame closes the input.
+	FrameStdin FrameType = iota
+	// FrameStdout and FrameStderr carry the output of the command.
+	FrameStdout
+	FrameStderr
+	// FrameExit carries the exit code of the command, in decimal, and ends
+	// the session.
+	FrameExit
+	// FrameError carries the reason the session failed, and ends it.
+	FrameError
+)
+
+// maxFrameSize bounds the payload of the frames read, to fend off corrupt
+// streams.
+const maxFrameSize = 1 << 20
+
+// FrameWriter writes frames to an underlying writer. It's safe for
+// concurrent use.
+type FrameWriter struct {
+	lk sync.Mutex
+	w  io.Writer
+}
+
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: w}
+}
+
+// WriteFrame writes a frame of type t, carrying p.
+func (fw *FrameWriter) WriteFrame(t FrameType, p []byte) error {
+	var hdr [5]byte
+	hdr[0] = byte(t)
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(p)))
+
+	fw.lk.Lock()
+	defer fw.lk.Unlock()
+
+	if _, err := fw.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := fw.w.Write(p)
+	return err
+}
+
+// Stream returns a writer that writes every non-empty write as a frame of
+// type t.
+func (fw *FrameWriter) Stream(t FrameType) io.Writer {
+	return &frameStream{fw: fw, t: t}
+}
+
+type frameStream struct {
+	fw *FrameWriter
+	t  FrameType
+}
+
+func (s *frameStream) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for b := p; len(b) > 0; {
+		n := len(b)
+		if n > maxFrameSize {
+			n = maxFrameSize
+		}
+		if err := s.fw.WriteFrame(s.t, b[:n]); err != nil {
+			return len(p) - len(b), err
+		}
+		b = b[n:]
+	}
+	return len(p), nil
+}
+
+// WriteExit ends the session with the exit code of the command.
+func (fw *FrameWriter) WriteExit(code int) error {
+	return fw.WriteFrame(FrameExit, []byte(strconv.Itoa(code)))
+}
+
+// ReadFrame reads the next frame from r.
+func ReadFrame(r io.Reader) (FrameType, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[1:])
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large: %d bytes", size)
+	}
+	p := make([]byte, size)
+	if _, err := io.ReadFull(r, p); err != nil {
+		return 0, nil, err
+	}
+	return FrameType(hdr[0]), p, nil
+}
+
+// ReadOutputFrames demultiplexes the output frames read from r onto stdout
+// and stderr, until the session ends. It returns the exit code of the
+// command, or the error the session failed with.
+func ReadOutputFrames(r io.Reader, stdout, stderr io.Writer) (int, error) {
+	for {
+		t, p, err := ReadFrame(r)
+		if err == io.EOF {
+			return 0, errors.New("session closed before the command exited")
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		switch t {
+		case FrameStdout:
+			_, err = stdout.Write(p)
+		case FrameStderr:
+			_, err = stderr.Write(p)
+		case FrameExit:
+			return strconv.Atoi(string(p))
+		case FrameError:
+			return 0, errors.New(string(p))
+		default:
+			err = fmt.Errorf("unexpected frame type: %d", t)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package rpc_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/rpc"
+)
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	fw := rpc.NewFrameWriter(&buf)
+
+	_, err := io.WriteString(fw.Stream(rpc.FrameStdout), "hello ")
+	require.NoError(t, err)
+	_, err = io.WriteString(fw.Stream(rpc.FrameStderr), "oops")
+	require.NoError(t, err)
+	// empty writes don't produce frames, which would close the input if
+	// written to stdin.
+	_, err = fw.Stream(rpc.FrameStdout).Write(nil)
+	require.NoError(t, err)
+	_, err = io.WriteString(fw.Stream(rpc.FrameStdout), "world")
+	require.NoError(t, err)
+	require.NoError(t, fw.WriteExit(42))
+
+	var stdout, stderr bytes.Buffer
+	code, err := rpc.ReadOutputFrames(&buf, &stdout, &stderr)
+	require.NoError(t, err)
+	require.Equal(t, 42, code)
+	require.Equal(t, "hello world", stdout.String())
+	require.Equal(t, "oops", stderr.String())
+}
+
+func TestFramesError(t *testing.T) {
+	var buf bytes.Buffer
+	fw := rpc.NewFrameWriter(&buf)
+	require.NoError(t, fw.WriteFrame(rpc.FrameError, []byte("instance is not running")))
+
+	_, err := rpc.ReadOutputFrames(&buf, io.Discard, io.Discard)
+	require.EqualError(t, err, "instance is not running")
+
+	// sessions must end with an exit code or an error.
+	fw = rpc.NewFrameWriter(&buf)
+	_, err = io.WriteString(fw.Stream(rpc.FrameStdout), "truncated")
+	require.NoError(t, err)
+	_, err = rpc.ReadOutputFrames(&buf, io.Discard, io.Discard)
+	require.Error(t, err)
+}
@@ -102,6 +102,35 @@ func NewOutputWriter(w http.ResponseWriter, r *http.Request) *OutputWriter {
 	return ow
 }
 
+// NewChunkOutputWriter returns an OutputWriter that emits newline-delimited
+// chunks on w, and nothing else. Unlike the other writers, log output is not
+// echoed to stdout. It is used by plugins, whose output is relayed to the
+// client by the daemon.
+func NewChunkOutputWriter(w io.Writer) *OutputWriter {
+	// progressWriter will emit log output as progress messages.
+	progressWriter := &progressWriter{out: w, newline: true}
+
+	// binaryWriter will emit binary chunks
+	binaryWriter := &binaryWriter{}
+
+	writeSyncer := zapcore.Lock(zapcore.AddSync(progressWriter))
+	logger := zap.New(zapcore.NewCore(logging.Encoder(), writeSyncer, zapcore.DebugLevel))
+
+	ow := &OutputWriter{
+		SugaredLogger: logger.Sugar(),
+		out:           w,
+		pw:            progressWriter,
+		bw:            binaryWriter,
+	}
+
+	// we need to wire this back for the lock.
+	progressWriter.ow = ow
+
+	// we need to wire this back for the lock.
+	binaryWriter.ow = ow
+	return ow
+}
+
 func Discard() *OutputWriter {
 	pw := &progressWriter{out: ioutil.Discard}
 	bw := &binaryWriter{}
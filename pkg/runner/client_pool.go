@@ -5,7 +5,6 @@ import (
 
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 type pool struct {
@@ -14,7 +13,7 @@ type pool struct {
 
 // newPool returns a pool of Kubernetes clientset connections
 func newPool(workers int, config KubernetesConfig) (*pool, error) {
-	k8scfg, err := clientcmd.BuildConfigFromFlags("", config.KubeConfigPath)
+	k8scfg, err := config.restConfig()
 	if err != nil {
 		return nil, fmt.Errorf("could not start k8s client from config: %v", err)
 	}
@@ -1,7 +1,7 @@
 package runner
 
 import (
-	"bufio"
+	"archive/tar"
 	"bytes"
 	"context"
 	"encoding/base64"
@@ -12,6 +12,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
@@ -31,6 +32,7 @@ import (
 	"github.com/testground/testground/pkg/conv"
 	"github.com/testground/testground/pkg/healthcheck"
 	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/registry"
 	"github.com/testground/testground/pkg/rpc"
 	"github.com/testground/testground/pkg/task"
 	"golang.org/x/sync/errgroup"
@@ -43,16 +45,20 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 )
 
 var (
-	_             api.Runner        = (*ClusterK8sRunner)(nil)
-	_             api.Terminatable  = (*ClusterK8sRunner)(nil)
-	_             api.Healthchecker = (*ClusterK8sRunner)(nil)
-	mu                              = sync.Mutex{}
-	errSyncClient                   = errors.New("failed to start sync client")
+	_             api.Runner          = (*ClusterK8sRunner)(nil)
+	_             api.Terminatable    = (*ClusterK8sRunner)(nil)
+	_             api.RunTerminatable = (*ClusterK8sRunner)(nil)
+	_             api.Healthchecker   = (*ClusterK8sRunner)(nil)
+	_             api.Inspectable     = (*ClusterK8sRunner)(nil)
+	_             api.Attachable      = (*ClusterK8sRunner)(nil)
+	_             api.CapacityPlanner = (*ClusterK8sRunner)(nil)
+	mu                                = sync.Mutex{}
+	errSyncClient                     = errors.New("failed to start sync client")
 )
 
 const (
@@ -74,6 +80,14 @@ const (
 	// check sdk/sync for more information
 	NetworkInitialisationSuccessful = "network initialisation successful"
 	NetworkInitialisationFailed     = "network initialisation failed"
+
+	// defaultUsageSamplingInterval matches the default resolution of the
+	// metrics server.
+	defaultUsageSamplingInterval = 15 * time.Second
+
+	// defaultSidecarReadyTimeout leaves room for the pods of instances to be
+	// scheduled, and for their images to be pulled.
+	defaultSidecarReadyTimeout = 5 * time.Minute
 )
 
 var k8sSubnetIdx uint64 = 0
@@ -105,7 +119,9 @@ type ClusterK8sRunnerConfig struct {
 
 	KeepService bool `toml:"keep_service"`
 
-	// Provider is the infrastructure provider to use
+	// Provider is the infrastructure provider to use, which images are pushed
+	// to: "aws" (ECR), "dockerhub", or "registry" (the generic registry of the
+	// environment, see config.RegistryConfig).
 	Provider string `toml:"provider"`
 
 	// Whether Kubernetes cluster has an autoscaler running
@@ -123,7 +139,70 @@ type ClusterK8sRunnerConfig struct {
 
 	RunTimeoutMin int `toml:"run_timeout_min"`
 
+	// SidecarReadyTimeout is the time the sidecar is given to manage all the
+	// instances once their pods are created, before the run fails (default:
+	// 5m).
+	SidecarReadyTimeout time.Duration `toml:"sidecar_ready_timeout"`
+
 	Sysctls []string `toml:"sysctls"`
+
+	// UsageSamplingIntervalSec is the interval, in seconds, at which the
+	// resource usage of pods is sampled from the metrics API (default: 15).
+	UsageSamplingIntervalSec int `toml:"usage_sampling_interval_sec"`
+	// DisableUsageSampling disables the sampling of resource usage (default:
+	// false).
+	DisableUsageSampling bool `toml:"disable_usage_sampling"`
+
+	// PodTemplate is overlaid on the pods of all instances, e.g. to set node
+	// selectors, tolerations, affinity, volumes, sidecar containers, the
+	// runtime class or image pull secrets. It's a strategic merge patch of a
+	// v1.Pod, in YAML or JSON, rendered as a Go template with the fields of
+	// podTemplateData first. Fields are removed by setting them to null,
+	// e.g. the persistentVolumeClaim of the "efs-shared" volume, to source it
+	// from elsewhere.
+	//
+	// Compositions are Go templates themselves, so actions in templates
+	// there must be quoted, e.g. {{ "{{ .GroupID }}" }}.
+	PodTemplate string `toml:"pod_template"`
+	// GroupPodTemplates are overlaid on the pods of the instances of single
+	// groups, by group ID, after PodTemplate.
+	GroupPodTemplates map[string]string `toml:"group_pod_templates"`
+
+	// OutputsStorage is where the outputs of instances are stored, and
+	// collected from: "pvc" (default), a volume shared by all pods, which
+	// must be ReadWriteMany; "s3", an S3-compatible object storage; or "pod",
+	// the outputs directory of the daemon. With "s3" and "pod", instances
+	// write outputs to a volume of their pod, which the runner uploads once
+	// they exit, so that outputs of instances still running at the end of
+	// the run are lost.
+	OutputsStorage string `toml:"outputs_storage"`
+	// OutputsPVC is the claim of the "pvc" outputs storage (default: "efs").
+	OutputsPVC string `toml:"outputs_pvc"`
+	// OutputsS3 configures the "s3" outputs storage.
+	OutputsS3 K8sS3OutputsConfig `toml:"outputs_s3"`
+
+	// Workload is how instances are deployed: "pods" (default), a pod per
+	// instance, created and deleted by the runner; or "jobs", an Indexed Job
+	// per group, whose completion indices are the indices of its instances,
+	// so that pods are created by the job controller, and deleted along with
+	// their job, even if the daemon dies. Jobs require Kubernetes 1.29 or
+	// later, so that failed instances aren't retried.
+	Workload string `toml:"workload"`
+	// JobTTLSecondsAfterFinished is the time after which finished jobs are
+	// deleted by the cluster, unless the service is kept (default: 3600).
+	JobTTLSecondsAfterFinished int `toml:"job_ttl_seconds_after_finished"`
+
+	// Context and Namespace are the kubeconfig context and the namespace the
+	// run is deployed to, which must be allowed by the k8s section of the env
+	// config (default: its default context, and the first namespace allowed
+	// there).
+	Context   string `toml:"context"`
+	Namespace string `toml:"namespace"`
+	// GroupContexts spreads groups across clusters sharing the sync service:
+	// groups listed there, by ID, are deployed to the namespace of the run in
+	// another context, which must declare the sync_service_host of the
+	// daemon. Outputs must then be stored in "s3" or "pod".
+	GroupContexts map[string]string `toml:"group_contexts"`
 }
 
 // ClusterK8sRunner is a runner that creates a Docker service to launch as
@@ -134,11 +213,24 @@ type ClusterK8sRunner struct {
 	pool        *pool
 	imagesLRU   *lru.Cache
 	syncClient  *ss.DefaultClient
+	events      runEvents
+
+	// targets are the runners of the other clusters and namespaces runs
+	// have been deployed to, sharing pools by cluster; runs are the
+	// placements of the runs in progress.
+	targetsLk sync.Mutex
+	targets   map[KubernetesConfig]*ClusterK8sRunner
+	pools     map[KubernetesConfig]*pool
+	runs      map[string]*k8sPlacement
 }
 
 type Journal struct {
 	Events       map[string]string   `json:"events"`
 	PodsStatuses map[string]struct{} `json:"pods_statuses"`
+
+	// Starts records when every instance was started, by instance tag,
+	// e.g. miner[003].
+	Starts map[string]time.Time `json:"starts,omitempty"`
 }
 
 func (r *Result) String() string {
@@ -153,6 +245,10 @@ func (r *Result) StringOutcomes() string {
 type GroupOutcome struct {
 	Ok    int `json:"ok"`
 	Total int `json:"total"`
+
+	// Coordinates are the build matrix values of the group, if it was
+	// expanded from a matrix.
+	Coordinates map[string]string `json:"coordinates,omitempty"`
 }
 
 func (g *GroupOutcome) String() string {
@@ -162,6 +258,9 @@ func (g *GroupOutcome) String() string {
 type KubernetesConfig struct {
 	// KubeConfigPath is the path to your kubernetes configuration path
 	KubeConfigPath string `json:"kubeConfigPath"`
+	// Context is the context of the kubernetes configuration to use, or the
+	// current one if empty
+	Context string `json:"context"`
 	// Namespace is the kubernetes namespaces where the pods should be running
 	Namespace string `json:"namespace"`
 }
@@ -198,6 +297,9 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 
 	ow = ow.With("runner", "cluster:k8s", "run_id", input.RunID)
 
+	c.events.begin(input.RunID)
+	defer c.events.end(input.RunID)
+
 	cfg := *input.RunnerConfig.(*ClusterK8sRunnerConfig)
 
 	// if `provider` is set, we have to push to a docker registry
@@ -221,6 +323,51 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		return
 	}
 
+	placement, err := c.place(&input.EnvConfig.K8s, &cfg, input.Groups)
+	if err != nil {
+		runerr = err
+		return
+	}
+	home := placement.clusters[0]
+
+	c.targetsLk.Lock()
+	if c.runs == nil {
+		c.runs = make(map[string]*k8sPlacement)
+	}
+	c.runs[input.RunID] = placement
+	c.targetsLk.Unlock()
+	defer func() {
+		c.targetsLk.Lock()
+		delete(c.runs, input.RunID)
+		c.targetsLk.Unlock()
+	}()
+
+	outputs, err := home.outputsStorage(&cfg, &input.EnvConfig)
+	if err != nil {
+		runerr = err
+		return
+	}
+	if len(placement.clusters) > 1 && !outputs.perPod() {
+		runerr = fmt.Errorf("groups spread across kubernetes contexts require the %q or %q outputs storage", k8sOutputsS3, k8sOutputsPod)
+		return
+	}
+
+	switch cfg.Workload {
+	case "", k8sWorkloadPods, k8sWorkloadJobs:
+	default:
+		runerr = fmt.Errorf("unknown workload %q; must be %q or %q", cfg.Workload, k8sWorkloadPods, k8sWorkloadJobs)
+		return
+	}
+
+	// fail before creating any pod if templates are broken.
+	for _, g := range input.Groups {
+		data := podTemplateData{RunID: input.RunID, TestPlan: input.TestPlan, TestCase: input.TestCase, GroupID: g.ID, Namespace: placement.groups[g.ID].config.Namespace}
+		if _, err := overlayPod(&v1.Pod{}, &cfg, data); err != nil {
+			runerr = err
+			return
+		}
+	}
+
 	template := runtime.RunParams{
 		TestPlan:           input.TestPlan,
 		TestCase:           input.TestCase,
@@ -245,25 +392,77 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 
 	template.TestSubnet = &ptypes.IPNet{IPNet: *subnet}
 
-	enoughResources, err := c.checkClusterResources(ow, input.Groups, defaultMemory, defaultCPU)
-	if err != nil {
-		runerr = fmt.Errorf("couldn't check cluster resources: %v", err)
-		return
-	}
-
-	if !enoughResources {
-		if cfg.AutoscalerEnabled {
-			ow.Warnw("too many test instances requested, will have to wait for cluster autoscaler to kick in")
-		} else {
-			runerr = errors.New("too many test instances requested, resize cluster if you need more capacity")
+	for _, cluster := range placement.clusters {
+		part := placement.input(input, cluster)
+		if part.TotalInstances == 0 {
+			continue
+		}
+		enoughResources, err := cluster.checkClusterResources(ow, part.Groups, defaultMemory, defaultCPU)
+		if err != nil {
+			runerr = fmt.Errorf("couldn't check cluster resources: %v", err)
 			return
 		}
+
+		if !enoughResources {
+			if cfg.AutoscalerEnabled {
+				ow.Warnw("too many test instances requested, will have to wait for cluster autoscaler to kick in", "context", cluster.config.Context)
+			} else {
+				runerr = errors.New("too many test instances requested, resize cluster if you need more capacity")
+				return
+			}
+		}
 	}
 
 	jobName := fmt.Sprintf("tg-%s", input.TestPlan)
 
 	ow.Infow("deploying testground testplan run on k8s", "job-name", jobName)
 
+	// sample the resource usage of pods until the run is over.
+	usageCtx, stopUsage := context.WithCancel(ctx)
+	defer stopUsage()
+
+	var (
+		usage   *usageRecorder
+		metrics = make(map[*ClusterK8sRunner]*podMetricsSampler, len(placement.clusters))
+	)
+	if !cfg.DisableUsageSampling {
+		interval := time.Duration(cfg.UsageSamplingIntervalSec) * time.Second
+		if interval <= 0 {
+			interval = defaultUsageSamplingInterval
+		}
+		usage = newUsageRecorder(input, interval)
+		for _, cluster := range placement.clusters {
+			metrics[cluster] = cluster.newPodMetricsSampler(input.RunID, interval)
+		}
+	}
+
+	// The run fails fast if the sidecar can't manage its instances.
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	sidecarErr := make(chan error, 1)
+	defer func() {
+		select {
+		case runerr = <-sidecarErr:
+		default:
+		}
+	}()
+
+	failSidecar := func(err error) {
+		ow.Errorw("sidecar failed", "err", err)
+		select {
+		case sidecarErr <- err:
+		default:
+		}
+		cancelRun()
+	}
+
+	watch, err := watchSidecar(runCtx, c.syncClient, &template, failSidecar)
+	if err != nil {
+		runerr = err
+		return
+	}
+
 	var eg errgroup.Group
 
 	eg.Go(func() error {
@@ -275,8 +474,18 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 			ow.Errorw("could not start collecting outcomes", "err", err)
 		}
 
-		err = c.watchRunPods(ctx, ow, input, result, &template)
-		if err != nil {
+		// every cluster is watched for the groups deployed there.
+		var watches errgroup.Group
+		for _, cluster := range placement.clusters {
+			cluster, part := cluster, placement.input(input, cluster)
+			if part.TotalInstances == 0 {
+				continue
+			}
+			watches.Go(func() error {
+				return cluster.watchRunPods(runCtx, ow, part, result, &template, outputs, watch)
+			})
+		}
+		if err := watches.Wait(); err != nil {
 			return err
 		}
 
@@ -285,7 +494,19 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		return nil
 	})
 
-	sem := make(chan struct{}, 30) // limit the number of concurrent k8s api calls
+	sem := make(chan struct{}, maxConcurrentAPICalls) // limit the number of concurrent k8s api calls
+
+	// pods are created as paced by the start policies of their groups.
+	sched, err := newStartScheduler(input, syncBarrier(c.syncClient, &template))
+	if err != nil {
+		runerr = err
+		return
+	}
+	defer sched.record(result.Journal)
+
+	// pods (or jobs) are created apart, so that the sidecar is awaited once
+	// they all are.
+	var creates errgroup.Group
 
 	for _, g := range input.Groups {
 		runenv := template
@@ -295,13 +516,14 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		runenv.TestCaptureProfiles = g.Profiles
 
 		result.Outcomes[g.ID] = &GroupOutcome{
-			Total: g.Instances,
+			Total:       g.Instances,
+			Coordinates: g.Coordinates,
 		}
 
+		gc := placement.groups[g.ID]
+
 		env := conv.ToEnvVar(runenv.ToEnvVars())
-		env = append(env, v1.EnvVar{Name: "REDIS_HOST", Value: "testground-infra-redis"})
-		env = append(env, v1.EnvVar{Name: "SYNC_SERVICE_HOST", Value: "testground-sync-service"})
-		env = append(env, v1.EnvVar{Name: "INFLUXDB_URL", Value: "http://influxdb:8086"})
+		env = append(env, placement.serviceEnv(g.ID)...)
 		// This subnet should correspond to the secondary CNI's IP range (usually Weave)
 		env = append(env, v1.EnvVar{Name: "TEST_SUBNET", Value: "10.32.0.0/12"})
 
@@ -338,10 +560,48 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 			}
 		}
 
+		if cfg.Workload == k8sWorkloadJobs {
+			g := g
+			name := groupJobName(jobName, input.RunID, g.ID)
+
+			defer func() {
+				if cfg.KeepService {
+					return
+				}
+				ow.Debugw("deleting job", "job", name)
+				if err := gc.deleteJob(ctx, name); err != nil {
+					ow.Errorw("couldn't remove job", "job", name, "err", err)
+				}
+			}()
+
+			// the instances of a job share its pod template, and learn their
+			// index from the annotation of their pod.
+			jobEnv := make([]v1.EnvVar, len(env), len(env)+2)
+			copy(jobEnv, env)
+			jobEnv = append(jobEnv, v1.EnvVar{
+				Name:      "TG_INSTANCE_INDEX",
+				ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", jobCompletionIndexAnnotation)}},
+			})
+			jobEnv = append(jobEnv, v1.EnvVar{
+				Name:  "TEST_OUTPUTS_PATH",
+				Value: fmt.Sprintf("/outputs/%s/%s/$(TG_INSTANCE_INDEX)", input.RunID, g.ID),
+			})
+
+			pod, err := gc.testplanPod(name, input, runenv, jobEnv, g, -1, podMemory, podCPU, outputs)
+			if err != nil {
+				runerr = err
+				return
+			}
+
+			creates.Go(func() error {
+				return gc.runGroupJob(runCtx, ow, name, pod, g, &cfg, sched, usage, usageCtx, metrics[gc])
+			})
+			continue
+		}
+
 		for i := 0; i < g.Instances; i++ {
 			i := i
 			g := g
-			sem <- struct{}{}
 
 			podName := fmt.Sprintf("%s-%s-%s-%d", jobName, input.RunID, g.ID, i)
 
@@ -349,16 +609,23 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 				if cfg.KeepService {
 					return
 				}
-				client := c.pool.Acquire()
-				defer c.pool.Release(client)
+				client := gc.pool.Acquire()
+				defer gc.pool.Release(client)
 				ow.Debugw("deleting pod", "pod", podName)
-				err = client.CoreV1().Pods(c.config.Namespace).Delete(ctx, podName, metav1.DeleteOptions{})
+				err = client.CoreV1().Pods(gc.config.Namespace).Delete(ctx, podName, metav1.DeleteOptions{})
 				if err != nil {
 					ow.Errorw("couldn't remove pod", "pod", podName, "err", err)
 				}
 			}()
 
-			eg.Go(func() error {
+			// pods waiting for their turn don't hold a slot, so that the
+			// groups they wait for can be created.
+			creates.Go(func() error {
+				if err := sched.wait(runCtx, ow, g.ID, i); err != nil {
+					return err
+				}
+
+				sem <- struct{}{}
 				defer func() { <-sem }()
 
 				currentEnv := make([]v1.EnvVar, len(env))
@@ -369,33 +636,71 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 					Value: fmt.Sprintf("/outputs/%s/%s/%d", input.RunID, g.ID, i),
 				})
 
-				return c.createTestplanPod(ctx, podName, input, runenv, currentEnv, g, i, podMemory, podCPU)
+				if err := gc.createTestplanPod(runCtx, podName, input, runenv, currentEnv, g, i, podMemory, podCPU, outputs); err != nil {
+					return err
+				}
+				sched.started(g.ID, i)
+				if usage != nil {
+					usage.track(usageCtx, g.ID, i, metrics[gc].probe(podName))
+				}
+				return nil
 			})
 		}
 	}
 
+	eg.Go(creates.Wait)
+	go func() {
+		if creates.Wait() != nil {
+			return
+		}
+		timeout := cfg.SidecarReadyTimeout
+		if timeout == 0 {
+			timeout = defaultSidecarReadyTimeout
+		}
+		if err := watch.awaitManaged(runCtx, input.TotalInstances, timeout); err != nil && runCtx.Err() == nil {
+			failSidecar(err)
+		}
+	}()
+
+	// pods are deleted once the run is over, so stop sampling them first.
+	if usage != nil {
+		defer func() {
+			stopUsage()
+			usage.wait()
+			usage.summarize(result)
+			if err := c.writeUsageSeries(ctx, input, usage, outputs); err != nil {
+				ow.Warnw("failed to write resource usage to outputs", "err", err)
+			}
+		}()
+	}
+
 	// we want to fetch logs even in an event of error
 	defer func() {
 		if input.TotalInstances <= 200 {
 			var gg errgroup.Group
 
-			for _, g := range input.Groups {
-				for i := 0; i < g.Instances; i++ {
-					i := i
-					g := g
+			// pods of jobs are named by the job controller.
+			for _, cluster := range placement.clusters {
+				cluster := cluster
+				pods, err := cluster.listRunPods(context.Background(), input.RunID)
+				if err != nil {
+					ow.Errorw("error while listing pods to fetch logs of", "err", err.Error())
+					continue
+				}
+
+				for _, pod := range pods {
+					pod := pod
 					sem <- struct{}{}
 
 					gg.Go(func() error {
 						defer func() { <-sem }()
 
-						podName := fmt.Sprintf("%s-%s-%s-%d", jobName, input.RunID, g.ID, i)
-
-						ow.Debugw("fetching logs", "pod", podName)
-						logs, err := c.getPodLogs(ow, podName)
+						ow.Debugw("fetching logs", "pod", pod.Name)
+						logs, err := cluster.getPodLogs(ow, pod.Name, instanceContainer(&pod))
 						if err != nil {
 							return err
 						}
-						ow.Debugw("got logs", "pod", podName, "len", len(logs))
+						ow.Debugw("got logs", "pod", pod.Name, "len", len(logs))
 
 						_, err = ow.WriteProgress([]byte(logs))
 						return err
@@ -412,6 +717,17 @@ func (c *ClusterK8sRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		}
 	}()
 
+	// diagnose failed instances before their pods are deleted.
+	defer func() {
+		for _, cluster := range placement.clusters {
+			result.Failures = append(result.Failures, cluster.diagnoseRun(context.Background(), ow, input.RunID)...)
+		}
+		sortFailures(result.Failures)
+		if summary := result.FailureSummary(); summary != "" {
+			ow.Warnw("testplan instances failed", "failures", summary)
+		}
+	}()
+
 	err = eg.Wait()
 	if err != nil {
 		runerr = err
@@ -516,7 +832,7 @@ func (c *ClusterK8sRunner) initPool() error {
 		return err
 	}
 
-	c.syncClient, err = ss.NewGenericClient(context.Background(), logging.S())
+	c.syncClient, err = newSyncClient(context.Background(), "", "")
 	if err != nil {
 		return fmt.Errorf("%w: %s", errSyncClient, err)
 	}
@@ -531,71 +847,219 @@ func (c *ClusterK8sRunner) CollectOutputs(ctx context.Context, input *api.Collec
 	}
 
 	log := ow.With("runner", "cluster:k8s", "run_id", input.RunID)
-	err := c.ensureCollectOutputsPod(ctx, input)
+
+	cfg := input.RunnerConfig.(*ClusterK8sRunnerConfig)
+	target, _, err := resolveK8sTarget(&input.EnvConfig.K8s, cfg.Context, cfg.Namespace)
 	if err != nil {
 		return err
 	}
+	home, err := c.on(target)
+	if err != nil {
+		return fmt.Errorf("could not init pool of kubernetes context %q: %w", target.Context, err)
+	}
 
-	client := c.pool.Acquire()
-	defer c.pool.Release(client)
+	outputs, err := home.outputsStorage(cfg, &input.EnvConfig)
+	if err != nil {
+		return err
+	}
+
+	log.Info("collecting outputs")
 
-	// This is the same line found in client_pool.go...
-	// I need the restCfg, for remotecommand.
-	// TODO: Reorganize not to repeat ourselves.
-	k8sCfg, err := clientcmd.BuildConfigFromFlags("", c.config.KubeConfigPath)
+	err = outputs.collect(ctx, input, ow)
 	if err != nil {
+		log.Warnf("failed to collect results from remote collection command: %v", err)
 		return err
 	}
+	return nil
+}
 
-	// This request is sent to the collect-outputs pod
-	// tar, compress, and write to stdout.
+// execInCollectOutputsPod runs a command in the collect-outputs pod, which
+// mounts the volume test plans write their outputs to.
+func (c *ClusterK8sRunner) execInCollectOutputsPod(command []string, stdin io.Reader, stdout io.Writer) error {
 	// stdout will remain connected so we can read it later.
+	return c.execInPod(collectOutputsPodName, &v1.PodExecOptions{
+		Container: "collect-outputs",
+		Command:   command,
+		Stdin:     stdin != nil,
+		Stderr:    false,
+		Stdout:    stdout != nil,
+	}, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+	})
+}
 
-	log.Info("collecting outputs")
+// execInPod executes a command in a container of a pod, through the exec
+// subresource, and relays its streams.
+func (c *ClusterK8sRunner) execInPod(podName string, opts *v1.PodExecOptions, streams remotecommand.StreamOptions) error {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	// remotecommand needs the rest config itself.
+	k8sCfg, err := c.config.restConfig()
+	if err != nil {
+		return err
+	}
 
 	req := client.
 		CoreV1().
 		RESTClient().
 		Post().
 		Resource("pods").
-		Name("collect-outputs").
-		Namespace("default").
+		Name(podName).
+		Namespace(c.config.Namespace).
 		SubResource("exec").
-		Param("container", "collect-outputs").
-		VersionedParams(&v1.PodExecOptions{
-			Container: "collect-outputs",
-			Command: []string{
-				"tar",
-				"-C",
-				"/outputs",
-				"-czf",
-				"-",
-				input.RunID,
-			},
-			Stdin:  false,
-			Stderr: false,
-			Stdout: true,
-		}, scheme.ParameterCodec)
+		Param("container", opts.Container).
+		VersionedParams(opts, scheme.ParameterCodec)
 
-	log.Debug("sending command to remote server: ", req.URL())
+	logging.S().Debug("sending command to remote server: ", req.URL())
 	exec, err := remotecommand.NewSPDYExecutor(k8sCfg, "POST", req.URL())
 	if err != nil {
-		log.Warnf("failed to send remote collection command: %v", err)
-		return err
+		return fmt.Errorf("failed to send remote command: %w", err)
 	}
 
-	// Connect stdout of the above command to the output file
-	// Connect stderr to a buffer which we can read from to display any errors to the user.
-	outbuf := bufio.NewWriter(ow.BinaryWriter())
-	defer outbuf.Flush()
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout: outbuf,
-	})
-	if err != nil {
-		log.Warnf("failed to collect results from remote collection command: %v", err)
+	return exec.Stream(streams)
+}
+
+// writeUsageSeries writes the resource usage time series of every instance
+// to its outputs directory, in the outputs storage.
+func (c *ClusterK8sRunner) writeUsageSeries(ctx context.Context, input *api.RunInput, usage *usageRecorder, outputs k8sOutputsStorage) error {
+	var (
+		buf bytes.Buffer
+		tw  = tar.NewWriter(&buf)
+		n   int
+	)
+	for _, g := range input.Groups {
+		for i := 0; i < g.Instances; i++ {
+			var series bytes.Buffer
+			if err := usage.writeSeries(g.ID, i, &series); err != nil {
+				return err
+			}
+			if series.Len() == 0 {
+				continue
+			}
+			hdr := &tar.Header{
+				Name:    path.Join(input.RunID, g.ID, strconv.Itoa(i), UsageFile),
+				Mode:    0644,
+				Size:    int64(series.Len()),
+				ModTime: time.Now(),
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(series.Bytes()); err != nil {
+				return err
+			}
+			n++
+		}
+	}
+	if err := tw.Close(); err != nil {
 		return err
 	}
-	return nil
+	if n == 0 {
+		return nil
+	}
+	return outputs.store(ctx, input.TestPlan, &buf)
+}
+
+// podMetrics is the subset of the PodMetrics resource of the metrics API
+// (metrics.k8s.io/v1beta1) we consume.
+type podMetrics struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Timestamp  time.Time `json:"timestamp"`
+	Containers []struct {
+		Name  string            `json:"name"`
+		Usage map[string]string `json:"usage"`
+	} `json:"containers"`
+}
+
+// podMetricsSampler samples the resource usage of the pods of a run in a
+// cluster from the metrics API, which only reports CPU and memory. The pods
+// are listed at most once per interval, and their metrics are fanned out to
+// the probes of their instances.
+type podMetricsSampler struct {
+	interval time.Duration
+	list     func(ctx context.Context) ([]podMetrics, error)
+
+	lk     sync.Mutex
+	listed time.Time
+	pods   map[string]*podMetrics
+}
+
+// newPodMetricsSampler returns a sampler of the pods of a run in the
+// namespace of this runner, selected by their run ID label.
+func (c *ClusterK8sRunner) newPodMetricsSampler(runID string, interval time.Duration) *podMetricsSampler {
+	list := func(ctx context.Context) ([]podMetrics, error) {
+		client := c.pool.Acquire()
+		defer c.pool.Release(client)
+
+		raw, err := client.CoreV1().RESTClient().Get().
+			AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", c.config.Namespace, "pods").
+			Param("labelSelector", fmt.Sprintf("testground.run_id=%s", runID)).
+			DoRaw(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var res struct {
+			Items []podMetrics `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return nil, fmt.Errorf("failed to decode pod metrics: %w", err)
+		}
+		return res.Items, nil
+	}
+	return &podMetricsSampler{interval: interval, list: list}
+}
+
+// get returns the latest metrics of a pod, or nil if they're unavailable,
+// e.g. until the pod has been running for a while.
+func (s *podMetricsSampler) get(ctx context.Context, podName string) *podMetrics {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if time.Since(s.listed) >= s.interval {
+		s.listed = time.Now()
+		s.pods = nil
+
+		items, err := s.list(ctx)
+		if err != nil {
+			return nil
+		}
+		s.pods = make(map[string]*podMetrics, len(items))
+		for i := range items {
+			s.pods[items[i].Metadata.Name] = &items[i]
+		}
+	}
+	return s.pods[podName]
+}
+
+// probe samples the resource usage of a pod. Samples are skipped while its
+// metrics are unavailable.
+func (s *podMetricsSampler) probe(podName string) usageProbe {
+	var last time.Time
+
+	return func(ctx context.Context) (*UsageSample, error) {
+		pm := s.get(ctx, podName)
+		// the metrics API only refreshes at its own resolution.
+		if pm == nil || !pm.Timestamp.After(last) {
+			return nil, nil
+		}
+		last = pm.Timestamp
+
+		smpl := &UsageSample{Time: pm.Timestamp}
+		for _, ct := range pm.Containers {
+			if q, err := resource.ParseQuantity(ct.Usage["cpu"]); err == nil {
+				smpl.CPU += float64(q.MilliValue()) / 1000
+			}
+			if q, err := resource.ParseQuantity(ct.Usage["memory"]); err == nil {
+				smpl.Memory += uint64(q.Value())
+			}
+		}
+		return smpl, nil
+	}
 }
 
 // waitForPod waits until a given pod reaches the desired `phase` or the context is canceled
@@ -631,8 +1095,9 @@ func (c *ClusterK8sRunner) waitForPod(ctx context.Context, podName string, phase
 	}
 }
 
-// ensureCollectOutputsPod ensures that we have a collect-outputs pod running
-func (c *ClusterK8sRunner) ensureCollectOutputsPod(ctx context.Context, input *api.CollectionInput) error {
+// ensureCollectOutputsPod ensures that we have a collect-outputs pod running,
+// mounting the outputs volume claim.
+func (c *ClusterK8sRunner) ensureCollectOutputsPod(ctx context.Context, cfg *ClusterK8sRunnerConfig, claim string) error {
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
@@ -643,7 +1108,7 @@ func (c *ClusterK8sRunner) ensureCollectOutputsPod(ctx context.Context, input *a
 		return err
 	}
 	if len(res.Items) == 0 {
-		err = c.createCollectOutputsPod(ctx, input)
+		err = c.createCollectOutputsPod(ctx, cfg, claim)
 		if err != nil {
 			return err
 		}
@@ -658,11 +1123,26 @@ func (c *ClusterK8sRunner) ensureCollectOutputsPod(ctx context.Context, input *a
 	return nil
 }
 
-func (c *ClusterK8sRunner) getPodLogs(ow *rpc.OutputWriter, podName string) (string, error) {
+// listRunPods returns the plan pods of a run.
+func (c *ClusterK8sRunner) listRunPods(ctx context.Context, runID string) ([]v1.Pod, error) {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	res, err := client.CoreV1().Pods(c.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("testground.purpose=plan,testground.run_id=%s", runID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (c *ClusterK8sRunner) getPodLogs(ow *rpc.OutputWriter, podName string, container string) (string, error) {
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
 	podLogOpts := v1.PodLogOptions{
+		Container:  container,
 		LimitBytes: int64Ptr(10000000000), // 100mb
 	}
 
@@ -693,7 +1173,7 @@ func (c *ClusterK8sRunner) getPodLogs(ow *rpc.OutputWriter, podName string) (str
 	return buf.String(), nil
 }
 
-func (c *ClusterK8sRunner) watchRunPods(ctx context.Context, ow *rpc.OutputWriter, input *api.RunInput, result *Result, rp *runtime.RunParams) error {
+func (c *ClusterK8sRunner) watchRunPods(ctx context.Context, ow *rpc.OutputWriter, input *api.RunInput, result *Result, rp *runtime.RunParams, outputs k8sOutputsStorage, watch *sidecarWatch) error {
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
@@ -732,6 +1212,18 @@ func (c *ClusterK8sRunner) watchRunPods(ctx context.Context, ow *rpc.OutputWrite
 		}
 	}()
 
+	// with per-pod storages, pods keep running after their instance exits,
+	// until their outputs are uploaded.
+	var uploads *outputsUploader
+	if outputs.perPod() {
+		uploads = &outputsUploader{c: c, ow: ow, input: input, outputs: outputs}
+		defer uploads.wait()
+	}
+
+	if cfg.Workload == k8sWorkloadJobs {
+		return c.waitForJobs(ctx, ow, input, result, runTimeout, uploads, watch)
+	}
+
 	podsByState := make(map[string]*v1.PodList)
 	var countersMu sync.Mutex
 
@@ -788,19 +1280,19 @@ func (c *ClusterK8sRunner) watchRunPods(ctx context.Context, ow *rpc.OutputWrite
 		ow.Debugw("testplan pods state", "running_for", time.Since(start).Truncate(time.Second), "succeeded", counters["Succeeded"], "running", counters["Running"], "pending", counters["Pending"], "failed", counters["Failed"], "unknown", counters["Unknown"])
 
 		if counters["Failed"] > 0 {
-			for _, p := range podsByState["Failed"].Items {
-				if !strings.Contains(p.ObjectMeta.Name, input.RunID) {
-					continue
-				}
+			recordFailedPods(ow, podsByState["Failed"].Items, result)
+		}
 
-				for _, st := range p.Status.ContainerStatuses {
-					event := fmt.Sprintf("pod status <failed> obj<%s> reason<%s> started_at<%s> finished_at<%s> exitcode<%d>", st.Name, st.State.Terminated.Reason, st.State.Terminated.StartedAt, st.State.Terminated.FinishedAt, st.State.Terminated.ExitCode)
-					ow.Warnw("testplan received status", "status", event)
-					result.Journal.PodsStatuses[event] = struct{}{}
-				}
+		for _, state := range []string{"Running", "Succeeded", "Failed"} {
+			if counters[state] > 0 {
+				settleExitedPods(watch, podsByState[state].Items)
 			}
 		}
 
+		if uploads != nil && counters["Running"] > 0 {
+			uploads.upload(ctx, podsByState["Running"].Items)
+		}
+
 		if counters["Running"] == input.TotalInstances && !allRunningStage {
 			allRunningStage = true
 			ow.Infow("all testplan instances in `Running` state", "took", time.Since(start).Truncate(time.Second))
@@ -818,10 +1310,64 @@ func (c *ClusterK8sRunner) watchRunPods(ctx context.Context, ow *rpc.OutputWrite
 	}
 }
 
-func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string, input *api.RunInput, runenv runtime.RunParams, env []v1.EnvVar, g *api.RunGroup, i int, podResourceMemory resource.Quantity, podResourceCPU resource.Quantity) error {
+// settleExitedPods settles the instances of the pods that exited, which the
+// sidecar may not have seen running. Pods of instances keep running while
+// their outputs are uploaded, after their instance container terminated.
+func settleExitedPods(watch *sidecarWatch, pods []v1.Pod) {
+	for i := range pods {
+		p := &pods[i]
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed || instanceExited(p) {
+			watch.exited(podHostname(p))
+		}
+	}
+}
+
+// podHostname returns the hostname of a pod, that the sidecar reports its
+// instance under: its hostname, set for the pods of jobs, or else its name,
+// truncated as the kubelet does.
+func podHostname(pod *v1.Pod) string {
+	hostname := pod.Spec.Hostname
+	if hostname == "" {
+		hostname = pod.Name
+	}
+	if len(hostname) > 63 {
+		hostname = strings.TrimRight(hostname[:63], "-.")
+	}
+	return hostname
+}
+
+// recordFailedPods records the statuses of the containers of failed plan
+// pods in the journal of a run.
+func recordFailedPods(ow *rpc.OutputWriter, pods []v1.Pod, result *Result) {
+	for _, p := range pods {
+		for _, st := range p.Status.ContainerStatuses {
+			if st.State.Terminated == nil {
+				continue
+			}
+			event := fmt.Sprintf("pod status <failed> obj<%s> reason<%s> started_at<%s> finished_at<%s> exitcode<%d>", st.Name, st.State.Terminated.Reason, st.State.Terminated.StartedAt, st.State.Terminated.FinishedAt, st.State.Terminated.ExitCode)
+			ow.Warnw("testplan received status", "status", event)
+			result.Journal.PodsStatuses[event] = struct{}{}
+		}
+	}
+}
+
+func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string, input *api.RunInput, runenv runtime.RunParams, env []v1.EnvVar, g *api.RunGroup, i int, podResourceMemory resource.Quantity, podResourceCPU resource.Quantity, outputs k8sOutputsStorage) error {
+	podRequest, err := c.testplanPod(podName, input, runenv, env, g, i, podResourceMemory, podResourceCPU, outputs)
+	if err != nil {
+		return err
+	}
+
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
+	_, err = client.CoreV1().Pods(c.config.Namespace).Create(ctx, podRequest, metav1.CreateOptions{})
+	return err
+}
+
+// testplanPod returns the pod of instance i of a group, or the pod template
+// of the job of the group if i is -1, in which case podName is the name of
+// the job.
+func (c *ClusterK8sRunner) testplanPod(podName string, input *api.RunInput, runenv runtime.RunParams, env []v1.EnvVar, g *api.RunGroup, i int, podResourceMemory resource.Quantity, podResourceCPU resource.Quantity, outputs k8sOutputsStorage) (*v1.Pod, error) {
 	cfg := *input.RunnerConfig.(*ClusterK8sRunnerConfig)
 
 	var sysctls []v1.Sysctl
@@ -835,7 +1381,7 @@ func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string
 	for _, p := range cfg.ExposedPorts {
 		port, err := strconv.ParseInt(p, 10, 32)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		ports = append(ports, v1.ContainerPort{Name: fmt.Sprintf("port%d", cnt), ContainerPort: int32(port)})
@@ -843,7 +1389,7 @@ func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string
 	}
 
 	mountPropagationMode := v1.MountPropagationHostToContainer
-	sharedVolumeName := "efs-shared"
+	sharedVolumeName := outputsVolumeName
 
 	podRequest := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -854,18 +1400,15 @@ func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string
 				"testground.run_id":   input.RunID,
 				"testground.groupid":  g.ID,
 				"testground.purpose":  "plan",
+				groupIndexLabel:       strconv.Itoa(i),
 			},
 			Annotations: map[string]string{"cni": defaultK8sNetworkAnnotation, "k8s.v1.cni.cncf.io/networks": "weave"},
 		},
 		Spec: v1.PodSpec{
 			Volumes: []v1.Volume{
 				{
-					Name: sharedVolumeName,
-					VolumeSource: v1.VolumeSource{
-						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
-							ClaimName: "efs",
-						},
-					},
+					Name:         sharedVolumeName,
+					VolumeSource: outputs.volume(),
 				},
 			},
 			SecurityContext: &v1.PodSecurityContext{
@@ -877,7 +1420,7 @@ func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string
 					Name:            "wait-for-sidecar",
 					Image:           "busybox",
 					ImagePullPolicy: v1.PullIfNotPresent,
-					Args:            []string{"-c", "until nc -vz $HOST_IP 6060; do echo \"Waiting for local sidecar to listen to $HOST_IP:6060\"; sleep 2; done;"},
+					Args:            []string{"-c", "until wget -q -O /dev/null http://$HOST_IP:6060/health; do echo \"Waiting for local sidecar to be ready at $HOST_IP:6060\"; sleep 2; done;"},
 					Command:         []string{"sh"},
 					Env:             env,
 					Resources: v1.ResourceRequirements{
@@ -939,8 +1482,47 @@ func (c *ClusterK8sRunner) createTestplanPod(ctx context.Context, podName string
 		},
 	}
 
-	_, err := client.CoreV1().Pods(c.config.Namespace).Create(ctx, podRequest, metav1.CreateOptions{})
-	return err
+	// the outputs container holds the outputs until they're uploaded.
+	if outputs.perPod() {
+		podRequest.Spec.Containers = append(podRequest.Spec.Containers, v1.Container{
+			Name:            outputsContainerName,
+			Image:           "busybox",
+			ImagePullPolicy: v1.PullIfNotPresent,
+			Args:            []string{"-c", "until [ -f /tmp/collected ]; do sleep 1; done"},
+			Command:         []string{"sh"},
+			VolumeMounts: []v1.VolumeMount{
+				{
+					Name:      sharedVolumeName,
+					MountPath: "/outputs",
+				},
+			},
+			Resources: v1.ResourceRequirements{
+				Limits: v1.ResourceList{
+					v1.ResourceMemory: resource.MustParse("10Mi"),
+					v1.ResourceCPU:    resource.MustParse("10m"),
+				},
+			},
+		})
+	}
+
+	data := podTemplateData{
+		RunID:     input.RunID,
+		TestPlan:  input.TestPlan,
+		TestCase:  runenv.TestCase,
+		GroupID:   g.ID,
+		Namespace: c.config.Namespace,
+		PodName:   podName,
+		Container: podName,
+		Index:     i,
+	}
+	if i < 0 {
+		// pods of jobs are named by the job controller, and indexed by
+		// their completion index.
+		data.PodName = ""
+		delete(podRequest.Labels, groupIndexLabel)
+	}
+
+	return overlayPod(podRequest, &cfg, data)
 }
 
 func int64Ptr(i int64) *int64 { return &i }
@@ -956,78 +1538,288 @@ func (fw FakeWriterAt) WriteAt(p []byte, offset int64) (n int, err error) {
 
 // checkClusterResources returns whether we can fit the input groups in the current cluster
 func (c *ClusterK8sRunner) checkClusterResources(ow *rpc.OutputWriter, groups []*api.RunGroup, fallbackMemory resource.Quantity, fallbackCPU resource.Quantity) (bool, error) {
-	neededCPUs := 0.0
+	cpu, memory, err := c.allocatableResources(context.TODO())
+	if err != nil {
+		return false, err
+	}
 
-	defaultPodCPU, err := strconv.ParseFloat(fallbackCPU.AsDec().String(), 64)
+	rep, err := newCapacityReport("", groups, api.Resources{CPU: fallbackCPU.String(), Memory: fallbackMemory.String()}, cpu, memory)
 	if err != nil {
 		return false, err
 	}
 
+	// runs are only held back for lack of CPUs; memory requests are left
+	// for the scheduler to enforce.
+	if short, _ := rep.Shortfall(); short == 0 {
+		return true, nil
+	}
+
+	ow.Warnw("not enough resources on cluster", "available_cpus", rep.AvailableCPU, "needed_cpus", rep.RequestedCPU, "utilisation", utilisation)
+	return false, nil
+}
+
+// allocatableResources returns the CPUs and memory of the plan nodes of the
+// cluster that can be allocated to instances, net of their sidecars and of
+// the share left to other services.
+func (c *ClusterK8sRunner) allocatableResources(ctx context.Context) (float64, int64, error) {
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
-	res, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+	res, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{
 		LabelSelector: "testground.node.role.plan=true",
 	})
 	if err != nil {
-		return false, err
+		return 0, 0, err
 	}
 
-	nodes := len(res.Items)
+	var (
+		cpus   float64
+		memory int64
+	)
+	for _, it := range res.Items {
+		q := it.Status.Allocatable[v1.ResourceCPU]
+		cpus += float64(q.MilliValue()) / 1000
+		q = it.Status.Allocatable[v1.ResourceMemory]
+		memory += q.Value()
+	}
+	cpus -= float64(len(res.Items)) * sidecarCPUs
+	if cpus < 0 {
+		cpus = 0
+	}
+	return cpus * utilisation, int64(float64(memory) * utilisation), nil
+}
+
+// PlanCapacity compares the resources requested by the instances of a run
+// with the allocatable resources of the clusters its groups are placed in.
+func (c *ClusterK8sRunner) PlanCapacity(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) ([]*api.CapacityReport, error) {
+	if err := c.initPool(); err != nil {
+		return nil, fmt.Errorf("could not init pool: %w", err)
+	}
 
-	// all worker nodes are the same, so just take allocatable CPU from the first
-	item := res.Items[0].Status.Allocatable["cpu"]
-	nodeCPUs := item.ToDec().Value()
+	cfg := *input.RunnerConfig.(*ClusterK8sRunnerConfig)
+	defaults := api.Resources{CPU: cfg.TestplanPodCPU, Memory: cfg.TestplanPodMemory}
 
-	totalCPUs := nodes * int(nodeCPUs)
+	placement, err := c.place(&input.EnvConfig.K8s, &cfg, input.Groups)
+	if err != nil {
+		return nil, err
+	}
 
-	availableCPUs := float64(totalCPUs) - float64(nodes)*sidecarCPUs
+	var reports []*api.CapacityReport
+	for _, cluster := range placement.clusters {
+		part := placement.input(input, cluster)
+		if part.TotalInstances == 0 {
+			continue
+		}
 
-	for _, g := range groups {
-		var podCPU float64
-		if g.Resources.CPU != "" {
-			cpu, err := resource.ParseQuantity(g.Resources.CPU)
-			if err != nil {
-				return false, err
+		cpu, memory, err := cluster.allocatableResources(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get capacity of kubernetes context %q: %w", cluster.config.Context, err)
+		}
+
+		target := cluster.config.Namespace
+		if cluster.config.Context != "" {
+			target = cluster.config.Context + "/" + target
+		}
+		rep, err := newCapacityReport(target, part.Groups, defaults, cpu, memory)
+		if err != nil {
+			return nil, err
+		}
+		if !rep.Fits() && cfg.AutoscalerEnabled {
+			rep.Notes = append(rep.Notes, "the cluster autoscaler may add nodes to make room for the run")
+		}
+		reports = append(reports, rep)
+	}
+	return reports, nil
+}
+
+// TerminateAll terminates all pods for with the label testground.purpose: plan
+// This command will remove all plan pods in the cluster, and in the other
+// clusters and namespaces runs were deployed to since the daemon started.
+func (c *ClusterK8sRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
+	if err := c.initPool(); err != nil {
+		return fmt.Errorf("could not init pool: %w", err)
+	}
+
+	for _, cluster := range c.clusters() {
+		if err := cluster.deletePlanPods(ctx, "testground.purpose=plan"); err != nil {
+			ow.Errorw("could not terminate all pods", "context", cluster.config.Context, "namespace", cluster.config.Namespace, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// InspectRun reports the state of the plan pods of a run in progress, and
+// the events their instances have emitted.
+func (c *ClusterK8sRunner) InspectRun(ctx context.Context, runID string, ow *rpc.OutputWriter) (*api.RunInspection, error) {
+	events, err := c.events.snapshot(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.initPool(); err != nil {
+		return nil, fmt.Errorf("could not init pool: %w", err)
+	}
+
+	var pods []v1.Pod
+	for _, cluster := range c.runClusters(runID) {
+		res, err := cluster.listRunPods(ctx, runID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods of run %s: %w", runID, err)
+		}
+		pods = append(pods, res...)
+	}
+
+	res := &api.RunInspection{Events: events}
+	for _, pod := range pods {
+		idx, _ := instanceIndex(&pod)
+		st := api.InstanceStatus{
+			GroupID: pod.Labels["testground.groupid"],
+			Index:   idx,
+			ID:      pod.Name,
+			State:   api.InstanceStateCreating,
+		}
+
+		// the plan container comes first; init containers are reported
+		// as the pod still being created.
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != instanceContainer(&pod) {
+				continue
 			}
-			podCPU, err = strconv.ParseFloat(cpu.AsDec().String(), 64)
-			if err != nil {
-				return false, err
+			st.Restarts = int(cs.RestartCount)
+			switch {
+			case cs.State.Running != nil:
+				st.State = api.InstanceStateRunning
+			case cs.State.Terminated != nil:
+				st.State, st.ExitCode = api.InstanceStateExited, int(cs.State.Terminated.ExitCode)
 			}
-		} else {
-			podCPU = defaultPodCPU
+		}
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			st.State = api.InstanceStateExited
 		}
 
-		neededCPUs += podCPU * float64(g.Instances)
+		for _, ctr := range pod.Spec.Containers {
+			for _, p := range ctr.Ports {
+				st.Ports = append(st.Ports, fmt.Sprintf("%d/%s -> %s:%d", p.ContainerPort, strings.ToLower(string(p.Protocol)), pod.Status.PodIP, p.ContainerPort))
+			}
+		}
+		res.Instances = append(res.Instances, st)
 	}
 
-	if (availableCPUs * utilisation) > neededCPUs {
-		return true, nil
+	sortInstances(res.Instances)
+	return res, nil
+}
+
+// Attach executes a command in the pod of an instance of a run in progress,
+// through the exec subresource. Streams can't be interrupted once started;
+// the session ends when the command exits, or its input is closed.
+func (c *ClusterK8sRunner) Attach(ctx context.Context, runID string, opts *api.AttachOptions, ow *rpc.OutputWriter) (int, error) {
+	if err := c.initPool(); err != nil {
+		return 0, fmt.Errorf("could not init pool: %w", err)
 	}
 
-	ow.Warnw("not enough resources on cluster", "available_cpus", availableCPUs, "needed_cpus", neededCPUs, "utilisation", utilisation)
-	return false, nil
+	// pods of jobs are indexed by their completion index only.
+	var (
+		cluster            *ClusterK8sRunner
+		podName, container string
+	)
+	for _, rc := range c.runClusters(runID) {
+		pods, err := rc.listRunPods(ctx, runID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list pods of run %s: %w", runID, err)
+		}
+		for _, pod := range pods {
+			if idx, ok := instanceIndex(&pod); ok && pod.Labels["testground.groupid"] == opts.GroupID && idx == opts.Instance && pod.Status.Phase == v1.PodRunning {
+				cluster, podName, container = rc, pod.Name, instanceContainer(&pod)
+				break
+			}
+		}
+	}
+	if podName == "" {
+		return 0, fmt.Errorf("instance %d of group %s of run %s is not running", opts.Instance, opts.GroupID, runID)
+	}
+
+	cmd := opts.Command
+	if len(cmd) == 0 {
+		cmd = defaultAttachCommand
+	}
+
+	streams := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	if !opts.TTY {
+		streams.Stderr = opts.Stderr
+	}
+	if opts.TTY && opts.Width > 0 && opts.Height > 0 {
+		streams.TerminalSizeQueue = &terminalSize{&remotecommand.TerminalSize{Width: uint16(opts.Width), Height: uint16(opts.Height)}}
+	}
+
+	err := cluster.execInPod(podName, &v1.PodExecOptions{
+		Container: container,
+		Command:   cmd,
+		Stdin:     opts.Stdin != nil,
+		Stdout:    true,
+		Stderr:    !opts.TTY,
+		TTY:       opts.TTY,
+	}, streams)
+
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
 }
 
-// TerminateAll terminates all pods for with the label testground.purpose: plan
-// This command will remove all plan pods in the cluster.
-func (c *ClusterK8sRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
+// terminalSize is a remotecommand.TerminalSizeQueue yielding a single size;
+// terminals are not resized during a session.
+type terminalSize struct {
+	size *remotecommand.TerminalSize
+}
+
+func (t *terminalSize) Next() *remotecommand.TerminalSize {
+	size := t.size
+	t.size = nil
+	return size
+}
+
+// TerminateRun deletes the plan jobs and pods of a run, in the clusters it's
+// deployed to if it's in progress, or else in the default one.
+func (c *ClusterK8sRunner) TerminateRun(ctx context.Context, runID string, ow *rpc.OutputWriter) error {
 	if err := c.initPool(); err != nil {
 		return fmt.Errorf("could not init pool: %w", err)
 	}
 
+	ow.Infow("terminating run", "run_id", runID)
+
+	selector := fmt.Sprintf("testground.purpose=plan,testground.run_id=%s", runID)
+	for _, cluster := range c.runClusters(runID) {
+		if err := cluster.deletePlanPods(ctx, selector); err != nil {
+			ow.Errorw("could not terminate pods of run", "run_id", runID, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// deletePlanPods deletes the plan jobs and pods matching a label selector.
+func (c *ClusterK8sRunner) deletePlanPods(ctx context.Context, selector string) error {
+	// pods of jobs are deleted along with their job.
+	if err := c.deleteJobs(ctx, selector); err != nil {
+		return fmt.Errorf("could not delete jobs: %w", err)
+	}
+
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
-	planPods := metav1.ListOptions{
-		LabelSelector: "testground.purpose=plan",
-	}
-	err := client.CoreV1().Pods("default").DeleteCollection(ctx, metav1.DeleteOptions{}, planPods)
-	if err != nil {
-		ow.Errorw("could not terminate all pods", "err", err)
-		return err
+	opts := metav1.ListOptions{
+		LabelSelector: selector,
 	}
-	return nil
+	return client.CoreV1().Pods(c.config.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, opts)
 }
 
 func (c *ClusterK8sRunner) pushImagesToDockerRegistry(ctx context.Context, ow *rpc.OutputWriter, in *api.RunInput) error {
@@ -1045,6 +1837,7 @@ func (c *ClusterK8sRunner) pushImagesToDockerRegistry(ctx context.Context, ow *r
 
 	var ipo types.ImagePushOptions // Auth params for Docker client
 	var uri string                 // URI of Docker registry to push images to
+	var remote remoteImageFunc     // Lookup of pushed images, if supported
 
 	switch cfg.Provider {
 	case "aws":
@@ -1086,19 +1879,32 @@ func (c *ClusterK8sRunner) pushImagesToDockerRegistry(ctx context.Context, ow *r
 		// Setup docker registry repository
 		uri = in.EnvConfig.DockerHub.Repo + "/testground"
 
+	case "registry":
+		reg, err := registry.New(in.EnvConfig.Registry)
+		if err != nil {
+			return err
+		}
+
+		ipo = types.ImagePushOptions{
+			RegistryAuth: registry.EncodeAuth(reg.Auth()),
+		}
+
+		uri = reg.Repository(in.TestPlan)
+		remote = func(ctx context.Context, tag string) (string, error) {
+			return reg.ConfigDigest(ctx, in.TestPlan, tag)
+		}
+
 	default:
 		return fmt.Errorf("unknown provider: %s", cfg.Provider)
 	}
 
-	return c.pushToDockerRegistry(ctx, ow, cli, in, ipo, uri)
+	return c.pushToDockerRegistry(ctx, ow, cli, in, ipo, uri, remote)
 }
 
-func (c *ClusterK8sRunner) createCollectOutputsPod(ctx context.Context, input *api.CollectionInput) error {
+func (c *ClusterK8sRunner) createCollectOutputsPod(ctx context.Context, cfg *ClusterK8sRunnerConfig, claim string) error {
 	client := c.pool.Acquire()
 	defer c.pool.Release(client)
 
-	cfg := *input.RunnerConfig.(*ClusterK8sRunnerConfig)
-
 	collectOutputsCPU, err := resource.ParseQuantity(cfg.CollectOutputsPodCPU)
 	if err != nil {
 		return fmt.Errorf("couldn't parse default `collect` pod CPU request; make sure you have specified `collect_outputs_pod_cpu` in .env.toml; err: %w", err)
@@ -1110,7 +1916,6 @@ func (c *ClusterK8sRunner) createCollectOutputsPod(ctx context.Context, input *a
 	}
 
 	mountPropagationMode := v1.MountPropagationHostToContainer
-	sharedVolumeName := "efs-shared"
 
 	podRequest := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1123,10 +1928,10 @@ func (c *ClusterK8sRunner) createCollectOutputsPod(ctx context.Context, input *a
 		Spec: v1.PodSpec{
 			Volumes: []v1.Volume{
 				{
-					Name: sharedVolumeName,
+					Name: outputsVolumeName,
 					VolumeSource: v1.VolumeSource{
 						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
-							ClaimName: "efs",
+							ClaimName: claim,
 						},
 					},
 				},
@@ -1143,7 +1948,7 @@ func (c *ClusterK8sRunner) createCollectOutputsPod(ctx context.Context, input *a
 					Command: []string{"sh"},
 					VolumeMounts: []v1.VolumeMount{
 						{
-							Name:             sharedVolumeName,
+							Name:             outputsVolumeName,
 							MountPath:        "/outputs",
 							MountPropagation: &mountPropagationMode,
 						},
@@ -1222,6 +2027,8 @@ func (c *ClusterK8sRunner) collectOutcomes(ctx context.Context, result *Result,
 			case <-ctx.Done():
 				running = false
 			case e := <-eventsCh:
+				c.events.add(tpl.TestRun, e)
+
 				// for now we emit only outcome OK events, so no need for more checks
 				if e.SuccessEvent != nil {
 					se := e.SuccessEvent
@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/testground/testground/pkg/rpc"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// diagnosedLogLines is the number of last log lines of failed
+	// instances attached to their diagnosis.
+	diagnosedLogLines = 20
+
+	// diagnosedEvents is the maximum number of events attached to the
+	// diagnosis of a failed instance, the latest ones.
+	diagnosedEvents = 10
+
+	// maxDiagnosedLogs is the maximum number of failed instances whose
+	// logs and events are fetched, so that mass failures don't flood the
+	// API.
+	maxDiagnosedLogs = 50
+)
+
+// imagePullReasons are the reasons of the containers waiting for their
+// image.
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// diagnosePod classifies why the instance of a plan pod failed, or returns
+// nil if it didn't, or not yet. Pods stuck pending are diagnosed too, as
+// they never fail on their own.
+func diagnosePod(pod *v1.Pod) *InstanceFailure {
+	idx, _ := instanceIndex(pod)
+	f := &InstanceFailure{
+		GroupID: pod.Labels["testground.groupid"],
+		Index:   idx,
+		ID:      pod.Name,
+	}
+
+	if pod.Status.Reason == "Evicted" {
+		f.Reason, f.Message = FailureEvicted, pod.Status.Message
+		return f
+	}
+
+	for _, st := range pod.Status.InitContainerStatuses {
+		if w := st.State.Waiting; w != nil && imagePullReasons[w.Reason] {
+			f.Reason, f.Message = FailureImagePull, w.Message
+			return f
+		}
+		if t := st.State.Terminated; t != nil && t.ExitCode != 0 {
+			f.Reason, f.ExitCode = FailureInitContainer, int(t.ExitCode)
+			f.Message = fmt.Sprintf("init container %s: %s", st.Name, t.Reason)
+			if t.Reason == "OOMKilled" {
+				f.Reason = FailureOOMKilled
+			}
+			return f
+		}
+	}
+
+	container := instanceContainer(pod)
+	for _, st := range pod.Status.ContainerStatuses {
+		if st.Name != container {
+			continue
+		}
+		if w := st.State.Waiting; w != nil && imagePullReasons[w.Reason] {
+			f.Reason, f.Message = FailureImagePull, w.Message
+			return f
+		}
+		if t := st.State.Terminated; t != nil && t.ExitCode != 0 {
+			f.Reason, f.ExitCode, f.Message = FailureExited, int(t.ExitCode), t.Message
+			if t.Reason == "OOMKilled" {
+				f.Reason = FailureOOMKilled
+			}
+			return f
+		}
+	}
+
+	if pod.Status.Phase == v1.PodPending {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse && cond.Reason == v1.PodReasonUnschedulable {
+				f.Reason, f.Message = FailureUnschedulable, cond.Message
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// diagnoseRun diagnoses the failed instances of a run deployed to this
+// cluster, with the warning events of their pods and their last log lines.
+func (c *ClusterK8sRunner) diagnoseRun(ctx context.Context, ow *rpc.OutputWriter, runID string) []*InstanceFailure {
+	pods, err := c.listRunPods(ctx, runID)
+	if err != nil {
+		ow.Warnw("failed to list pods to diagnose", "err", err)
+		return nil
+	}
+
+	var (
+		failures []*InstanceFailure
+		failed   []*v1.Pod
+	)
+	for i := range pods {
+		if f := diagnosePod(&pods[i]); f != nil {
+			failures, failed = append(failures, f), append(failed, &pods[i])
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, 10)
+		n   int
+	)
+	for i, f := range failures {
+		if i == maxDiagnosedLogs {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(f *InstanceFailure) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f.Events = c.podEvents(ctx, ow, f.ID)
+			if n := len(f.Events); n > diagnosedEvents {
+				f.Events = f.Events[n-diagnosedEvents:]
+			}
+		}(f)
+	}
+
+	// instances that never started have no logs.
+	for i, f := range failures {
+		if n == maxDiagnosedLogs {
+			break
+		}
+		if f.Reason == FailureImagePull || f.Reason == FailureUnschedulable || f.Reason == FailureInitContainer {
+			continue
+		}
+		n++
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(f *InstanceFailure, pod *v1.Pod) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f.LastLogs = c.lastLogLines(ctx, pod)
+		}(f, failed[i])
+	}
+	wg.Wait()
+
+	return failures
+}
+
+// podEvents returns the warning events of a pod, as "reason: message"
+// lines.
+func (c *ClusterK8sRunner) podEvents(ctx context.Context, ow *rpc.OutputWriter, podName string) []string {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	res, err := client.CoreV1().Events(c.config.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "type=Warning,involvedObject.kind=Pod,involvedObject.name=" + podName,
+	})
+	if err != nil {
+		ow.Warnw("failed to list events to diagnose", "pod", podName, "err", err)
+		return nil
+	}
+
+	lines := make([]string, 0, len(res.Items))
+	for _, e := range res.Items {
+		line := fmt.Sprintf("%s: %s", e.Reason, e.Message)
+		if e.Count > 1 {
+			line += fmt.Sprintf(" (x%d)", e.Count)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// lastLogLines returns the last log lines of the instance of a pod.
+func (c *ClusterK8sRunner) lastLogLines(ctx context.Context, pod *v1.Pod) []string {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	tail := int64(diagnosedLogLines)
+	logs, err := client.CoreV1().Pods(c.config.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{
+		Container: instanceContainer(pod),
+		TailLines: &tail,
+	}).Stream(ctx)
+	if err != nil {
+		return nil
+	}
+	defer logs.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestDiagnosePod(t *testing.T) {
+	waiting := func(reason string) v1.ContainerState {
+		return v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason, Message: "back-off pulling image"}}
+	}
+	terminated := func(reason string, code int32) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: reason, ExitCode: code}}
+	}
+
+	var tests = []struct {
+		name     string
+		status   v1.PodStatus
+		reason   FailureReason
+		exitCode int
+	}{
+		{"running", v1.PodStatus{
+			Phase:             v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "tg-run-a-0", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}},
+		}, "", 0},
+		{"succeeded", v1.PodStatus{
+			Phase:             v1.PodSucceeded,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "tg-run-a-0", State: terminated("Completed", 0)}},
+		}, "", 0},
+		{"oom killed", v1.PodStatus{
+			Phase:             v1.PodFailed,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "tg-run-a-0", State: terminated("OOMKilled", 137)}},
+		}, FailureOOMKilled, 137},
+		{"exited", v1.PodStatus{
+			Phase:             v1.PodFailed,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "tg-run-a-0", State: terminated("Error", 2)}},
+		}, FailureExited, 2},
+		{"other container failed", v1.PodStatus{
+			Phase: v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "tg-run-a-0", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				{Name: "proxy", State: terminated("Error", 1)},
+			},
+		}, "", 0},
+		{"evicted", v1.PodStatus{Phase: v1.PodFailed, Reason: "Evicted", Message: "The node was low on resource: memory."}, FailureEvicted, 0},
+		{"image pull", v1.PodStatus{
+			Phase:             v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "tg-run-a-0", State: waiting("ImagePullBackOff")}},
+		}, FailureImagePull, 0},
+		{"init image pull", v1.PodStatus{
+			Phase:                 v1.PodPending,
+			InitContainerStatuses: []v1.ContainerStatus{{Name: "wait-for-sidecar", State: waiting("ErrImagePull")}},
+		}, FailureImagePull, 0},
+		{"init container", v1.PodStatus{
+			Phase:                 v1.PodFailed,
+			InitContainerStatuses: []v1.ContainerStatus{{Name: "mkdir-outputs", State: terminated("Error", 1)}},
+		}, FailureInitContainer, 1},
+		{"unschedulable", v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{{
+				Type:    v1.PodScheduled,
+				Status:  v1.ConditionFalse,
+				Reason:  v1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}},
+		}, FailureUnschedulable, 0},
+		{"pending", v1.PodStatus{Phase: v1.PodPending}, "", 0},
+	}
+
+	for _, tt := range tests {
+		pod := testPod()
+		pod.Labels[groupIndexLabel] = "4"
+		pod.Status = tt.status
+
+		f := diagnosePod(pod)
+		if tt.reason == "" {
+			if f != nil {
+				t.Errorf("%s: unexpected failure %s", tt.name, f)
+			}
+			continue
+		}
+		if f == nil {
+			t.Errorf("%s: expected failure %s", tt.name, tt.reason)
+			continue
+		}
+		if f.Reason != tt.reason || f.ExitCode != tt.exitCode || f.GroupID != "a" || f.Index != 4 || f.ID != pod.Name {
+			t.Errorf("%s: unexpected failure %+v", tt.name, f)
+		}
+	}
+}
+
+func TestFailureSummary(t *testing.T) {
+	r := &Result{Failures: []*InstanceFailure{
+		{GroupID: "b", Index: 1, Reason: FailureImagePull},
+		{GroupID: "a", Index: 0, Reason: FailureOOMKilled},
+		{GroupID: "a", Index: 3, Reason: FailureOOMKilled},
+		{GroupID: "c", Index: 0, Reason: FailureOOMKilled},
+	}}
+
+	want := "image_pull: 1 (b: 1); oom_killed: 3 (a: 2, c: 1)"
+	if got := r.FailureSummary(); got != want {
+		t.Errorf("got summary %q, want %q", got, want)
+	}
+
+	sortFailures(r.Failures)
+	if r.Failures[0].GroupID != "a" || r.Failures[1].Index != 3 || r.Failures[3].GroupID != "c" {
+		t.Errorf("unexpected order of failures: %v", r.Failures)
+	}
+
+	if (&Result{}).FailureSummary() != "" {
+		t.Errorf("expected empty summary without failures")
+	}
+}
@@ -0,0 +1,359 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/rpc"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// k8sWorkloadPods deploys every instance as a bare pod.
+	k8sWorkloadPods = "pods"
+	// k8sWorkloadJobs deploys every group as an Indexed Job.
+	k8sWorkloadJobs = "jobs"
+
+	// defaultJobTTLSecondsAfterFinished is the time finished jobs are kept
+	// for, long enough for the runner to fetch the logs of their pods.
+	defaultJobTTLSecondsAfterFinished = 3600
+
+	// jobCompletionIndexAnnotation is the annotation of the pods of Indexed
+	// Jobs holding their completion index.
+	jobCompletionIndexAnnotation = "batch.kubernetes.io/job-completion-index"
+
+	// jobPodsListInterval is the minimum interval between listings of the
+	// pods of a job, to resolve the names of the pods of its instances.
+	jobPodsListInterval = 10 * time.Second
+)
+
+// instanceContainer returns the name of the container of the instance of a
+// plan pod, which comes first.
+func instanceContainer(pod *v1.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return pod.Name
+	}
+	return pod.Spec.Containers[0].Name
+}
+
+// instanceIndex returns the index of the instance of a plan pod in its group:
+// its group index label, or else its completion index, for pods of jobs.
+func instanceIndex(pod *v1.Pod) (int, bool) {
+	v, ok := pod.Labels[groupIndexLabel]
+	if !ok {
+		v, ok = pod.Annotations[jobCompletionIndexAnnotation]
+	}
+	if !ok {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(v)
+	return idx, err == nil
+}
+
+// groupJobName returns the name of the job of a group.
+func groupJobName(jobName, runID, groupID string) string {
+	return fmt.Sprintf("%s-%s-%s", jobName, runID, groupID)
+}
+
+// groupJob returns the Indexed Job running the instances of a group, out of
+// the pod template of the group, starting parallelism instances at first.
+func groupJob(name string, pod *v1.Pod, instances, parallelism int, cfg *ClusterK8sRunnerConfig) *batchv1.Job {
+	var (
+		mode        = batchv1.IndexedCompletion
+		completions = int32(instances)
+		parallel    = int32(parallelism)
+		ttl         *int32
+	)
+	if !cfg.KeepService {
+		secs := int32(cfg.JobTTLSecondsAfterFinished)
+		if secs <= 0 {
+			secs = defaultJobTTLSecondsAfterFinished
+		}
+		ttl = &secs
+	}
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: pod.Labels,
+		},
+		Spec: batchv1.JobSpec{
+			CompletionMode:          &mode,
+			Completions:             &completions,
+			Parallelism:             &parallel,
+			TTLSecondsAfterFinished: ttl,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      pod.Labels,
+					Annotations: pod.Annotations,
+				},
+				Spec: pod.Spec,
+			},
+		},
+	}
+}
+
+// createGroupJob creates the job of a group. Failed instances must not be
+// retried, as their group would count them twice, which requires the
+// backoffLimitPerIndex of Kubernetes 1.29, unknown to our client: the job is
+// created from raw JSON, and deleted again if the cluster drops the field.
+func (c *ClusterK8sRunner) createGroupJob(ctx context.Context, job *batchv1.Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return err
+	}
+	obj["spec"].(map[string]interface{})["backoffLimitPerIndex"] = 0
+	if raw, err = json.Marshal(obj); err != nil {
+		return err
+	}
+
+	client := c.pool.Acquire()
+	res, err := client.BatchV1().RESTClient().Post().
+		Namespace(c.config.Namespace).
+		Resource("jobs").
+		Body(raw).
+		DoRaw(ctx)
+	c.pool.Release(client)
+	if err != nil {
+		return fmt.Errorf("failed to create job %s: %w", job.Name, err)
+	}
+
+	var created struct {
+		Spec struct {
+			BackoffLimitPerIndex *int32 `json:"backoffLimitPerIndex"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(res, &created); err != nil {
+		return err
+	}
+	if created.Spec.BackoffLimitPerIndex == nil {
+		_ = c.deleteJob(context.Background(), job.Name)
+		return fmt.Errorf("the jobs workload requires Kubernetes 1.29 or later (backoffLimitPerIndex)")
+	}
+	return nil
+}
+
+// scaleGroupJob lets parallelism instances of the job of a group run.
+func (c *ClusterK8sRunner) scaleGroupJob(ctx context.Context, name string, parallelism int) error {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	patch := fmt.Sprintf(`{"spec":{"parallelism":%d}}`, parallelism)
+	_, err := client.BatchV1().Jobs(c.config.Namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// deleteJob deletes a job, cascading to its pods.
+func (c *ClusterK8sRunner) deleteJob(ctx context.Context, name string) error {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	propagation := metav1.DeletePropagationBackground
+	return client.BatchV1().Jobs(c.config.Namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// deleteJobs deletes the jobs matching a label selector, cascading to their
+// pods.
+func (c *ClusterK8sRunner) deleteJobs(ctx context.Context, selector string) error {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	propagation := metav1.DeletePropagationBackground
+	return client.BatchV1().Jobs(c.config.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{PropagationPolicy: &propagation}, metav1.ListOptions{LabelSelector: selector})
+}
+
+// runGroupJob runs the instances of a group as an Indexed Job, paced by the
+// start policy of the group: the parallelism of the job is raised as its
+// instances may start.
+func (c *ClusterK8sRunner) runGroupJob(ctx context.Context, ow *rpc.OutputWriter, name string, pod *v1.Pod, g *api.RunGroup, cfg *ClusterK8sRunnerConfig, sched *startScheduler, usage *usageRecorder, usageCtx context.Context, metrics *podMetricsSampler) error {
+	if err := sched.wait(ctx, ow, g.ID, 0); err != nil {
+		return err
+	}
+
+	// without a rate, all instances may start at once.
+	parallelism := 1
+	if g.Start.Rate <= 0 {
+		parallelism = g.Instances
+	}
+
+	ow.Infow("creating job", "job", name, "group", g.ID, "instances", g.Instances)
+	if err := c.createGroupJob(ctx, groupJob(name, pod, g.Instances, parallelism, cfg)); err != nil {
+		return err
+	}
+
+	pods := &jobPods{c: c, job: name, metrics: metrics}
+	started := func(i int) {
+		sched.started(g.ID, i)
+		if usage != nil {
+			usage.track(usageCtx, g.ID, i, pods.usageProbe(i))
+		}
+	}
+	for i := 0; i < parallelism; i++ {
+		started(i)
+	}
+
+	for i := parallelism; i < g.Instances; i++ {
+		if err := sched.wait(ctx, ow, g.ID, i); err != nil {
+			return err
+		}
+		if err := c.scaleGroupJob(ctx, name, i+1); err != nil {
+			return fmt.Errorf("failed to scale job %s: %w", name, err)
+		}
+		started(i)
+	}
+	return nil
+}
+
+// jobPods resolves the pods of the instances of a job, listing them at most
+// once per jobPodsListInterval.
+type jobPods struct {
+	c       *ClusterK8sRunner
+	job     string
+	metrics *podMetricsSampler
+
+	lk     sync.Mutex
+	names  map[int]string
+	listed time.Time
+}
+
+// name returns the name of the pod of instance idx, or "" if it's unknown
+// yet.
+func (j *jobPods) name(ctx context.Context, idx int) string {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+
+	if name, ok := j.names[idx]; ok || time.Since(j.listed) < jobPodsListInterval {
+		return name
+	}
+	j.listed = time.Now()
+
+	client := j.c.pool.Acquire()
+	defer j.c.pool.Release(client)
+
+	res, err := client.CoreV1().Pods(j.c.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + j.job,
+	})
+	if err != nil {
+		return ""
+	}
+	j.names = make(map[int]string, len(res.Items))
+	for _, pod := range res.Items {
+		if i, ok := instanceIndex(&pod); ok {
+			j.names[i] = pod.Name
+		}
+	}
+	return j.names[idx]
+}
+
+// usageProbe samples the resource usage of the pod of instance idx, once
+// it's known.
+func (j *jobPods) usageProbe(idx int) usageProbe {
+	var probe usageProbe
+	return func(ctx context.Context) (*UsageSample, error) {
+		if probe == nil {
+			name := j.name(ctx, idx)
+			if name == "" {
+				return nil, nil
+			}
+			probe = j.metrics.probe(name)
+		}
+		return probe(ctx)
+	}
+}
+
+// waitForJobs waits until the jobs of a run have finished, through their
+// status, recording the statuses of failed pods, and settling the instances
+// that exited with the sidecar watch.
+func (c *ClusterK8sRunner) waitForJobs(ctx context.Context, ow *rpc.OutputWriter, input *api.RunInput, result *Result, runTimeout time.Duration, uploads *outputsUploader, watch *sidecarWatch) error {
+	client := c.pool.Acquire()
+	defer c.pool.Release(client)
+
+	selector := fmt.Sprintf("testground.purpose=plan,testground.run_id=%s", input.RunID)
+
+	start := time.Now()
+	failed, exited := 0, 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if time.Since(start) > runTimeout {
+			return fmt.Errorf("run timeout reached. make sure your plan execution completes within %s.", runTimeout)
+		}
+		time.Sleep(2000 * time.Millisecond)
+
+		jobs, err := client.BatchV1().Jobs(c.config.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			ow.Warnw("k8s client jobs list error", "err", err.Error())
+			continue
+		}
+
+		var active, succeeded, failedNow int
+		for _, job := range jobs.Items {
+			active += int(job.Status.Active)
+			succeeded += int(job.Status.Succeeded)
+			failedNow += int(job.Status.Failed)
+		}
+
+		ow.Debugw("testplan jobs state", "running_for", time.Since(start).Truncate(time.Second), "jobs", len(jobs.Items), "succeeded", succeeded, "active", active, "failed", failedNow)
+
+		if failedNow > failed {
+			failed = failedNow
+			pods, err := client.CoreV1().Pods(c.config.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: selector,
+				FieldSelector: "status.phase=Failed",
+			})
+			if err == nil {
+				recordFailedPods(ow, pods.Items, result)
+			}
+		}
+
+		if succeeded+failedNow > exited {
+			exited = succeeded + failedNow
+			pods, err := client.CoreV1().Pods(c.config.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: selector,
+				FieldSelector: "status.phase!=Pending,status.phase!=Running",
+			})
+			if err == nil {
+				settleExitedPods(watch, pods.Items)
+			}
+		}
+
+		if uploads != nil && active > 0 {
+			pods, err := client.CoreV1().Pods(c.config.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: selector,
+				FieldSelector: "status.phase=Running",
+			})
+			if err == nil {
+				settleExitedPods(watch, pods.Items)
+				uploads.upload(ctx, pods.Items)
+			}
+		}
+
+		if succeeded == input.TotalInstances {
+			ow.Infow("all testplan instances succeeded", "took", time.Since(start).Truncate(time.Second))
+			return nil
+		}
+
+		if succeeded+failedNow == input.TotalInstances {
+			ow.Warnw("all testplan instances succeeded or failed", "took", time.Since(start).Truncate(time.Second))
+			return nil
+		}
+	}
+}
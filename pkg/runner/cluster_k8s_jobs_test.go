@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInstanceIndex(t *testing.T) {
+	var tests = []struct {
+		labels      map[string]string
+		annotations map[string]string
+		idx         int
+		ok          bool
+	}{
+		{map[string]string{groupIndexLabel: "3"}, nil, 3, true},
+		{nil, map[string]string{jobCompletionIndexAnnotation: "7"}, 7, true},
+		{map[string]string{groupIndexLabel: "1"}, map[string]string{jobCompletionIndexAnnotation: "2"}, 1, true},
+		{map[string]string{groupIndexLabel: "x"}, nil, 0, false},
+		{nil, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations}}
+		idx, ok := instanceIndex(pod)
+		if ok != tt.ok || (ok && idx != tt.idx) {
+			t.Errorf("got %d, %t for %v %v; want %d, %t", idx, ok, tt.labels, tt.annotations, tt.idx, tt.ok)
+		}
+	}
+}
+
+func TestInstanceContainer(t *testing.T) {
+	pod := testPod()
+	pod.Name = "tg-run-a-0-x7f2k"
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: outputsContainerName})
+	if name := instanceContainer(pod); name != "tg-run-a-0" {
+		t.Errorf("got container %q, want %q", name, "tg-run-a-0")
+	}
+
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{Name: outputsContainerName, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+		{Name: "tg-run-a-0", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}},
+	}
+	if !instanceExited(pod) {
+		t.Errorf("instance of pod named by its job not reported exited once terminated")
+	}
+}
+
+func TestGroupJob(t *testing.T) {
+	pod := testPod()
+	delete(pod.Labels, groupIndexLabel)
+
+	job := groupJob("tg-run-a", pod, 10, 1, &ClusterK8sRunnerConfig{})
+	if *job.Spec.CompletionMode != batchv1.IndexedCompletion || *job.Spec.Completions != 10 || *job.Spec.Parallelism != 1 {
+		t.Errorf("unexpected job spec: %+v", job.Spec)
+	}
+	if ttl := job.Spec.TTLSecondsAfterFinished; ttl == nil || *ttl != defaultJobTTLSecondsAfterFinished {
+		t.Errorf("unexpected ttl: %v", ttl)
+	}
+	if job.Labels["testground.purpose"] != "plan" || job.Spec.Template.Labels["testground.groupid"] != "a" {
+		t.Errorf("unexpected labels: %v %v", job.Labels, job.Spec.Template.Labels)
+	}
+	if c := job.Spec.Template.Spec.Containers; len(c) != 1 || c[0].Image != "plan" {
+		t.Errorf("unexpected containers: %+v", c)
+	}
+
+	job = groupJob("tg-run-a", pod, 10, 10, &ClusterK8sRunnerConfig{JobTTLSecondsAfterFinished: 60})
+	if ttl := job.Spec.TTLSecondsAfterFinished; ttl == nil || *ttl != 60 {
+		t.Errorf("unexpected ttl: %v", ttl)
+	}
+
+	job = groupJob("tg-run-a", pod, 10, 10, &ClusterK8sRunnerConfig{KeepService: true})
+	if job.Spec.TTLSecondsAfterFinished != nil {
+		t.Errorf("expected no ttl when keeping the service, got %d", *job.Spec.TTLSecondsAfterFinished)
+	}
+}
+
+func TestSettleExitedPods(t *testing.T) {
+	long := "tg-run-c5kqf8a7ctcfrk2ib3ng-a-very-long-group-identifier-of-th-plan-12"
+
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "tg-run-a-0"}, Status: v1.PodStatus{Phase: v1.PodSucceeded}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "tg-run-a-1-x7f2k"}, Spec: v1.PodSpec{Hostname: "tg-run-a-1"}, Status: v1.PodStatus{Phase: v1.PodFailed}},
+		{ObjectMeta: metav1.ObjectMeta{Name: long}, Status: v1.PodStatus{Phase: v1.PodFailed}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "tg-run-a-2"}, Status: v1.PodStatus{Phase: v1.PodRunning}},
+	}
+
+	watch := &sidecarWatch{settled: make(map[string]bool), changed: make(chan struct{}, 1)}
+	settleExitedPods(watch, pods)
+
+	for _, hostname := range []string{"tg-run-a-0", "tg-run-a-1", long[:62]} {
+		if !watch.settled[hostname] {
+			t.Errorf("instance %q of exited pod not settled", hostname)
+		}
+	}
+	if watch.settled["tg-run-a-2"] {
+		t.Errorf("instance of running pod settled")
+	}
+}
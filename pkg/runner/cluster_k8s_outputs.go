@@ -0,0 +1,424 @@
+package runner
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+	"github.com/testground/testground/pkg/rpc"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// k8sOutputsPVC stores outputs in a volume shared by all pods.
+	k8sOutputsPVC = "pvc"
+	// k8sOutputsS3 stores outputs in an S3-compatible object storage.
+	k8sOutputsS3 = "s3"
+	// k8sOutputsPod stores outputs in the outputs directory of the daemon.
+	k8sOutputsPod = "pod"
+
+	// outputsVolumeName is the name of the volume instances write their
+	// outputs to, mounted at /outputs.
+	outputsVolumeName = "efs-shared"
+
+	// outputsContainerName is the name of the container holding the outputs
+	// of an instance in its pod until they're uploaded, with per-pod
+	// storages. It exits once /tmp/collected exists.
+	outputsContainerName = "outputs"
+)
+
+// K8sS3OutputsConfig configures the "s3" outputs storage of cluster:k8s.
+type K8sS3OutputsConfig struct {
+	// Endpoint is the URL of the object storage, e.g. http://minio:9000
+	// (default: that of AWS S3).
+	Endpoint string `toml:"endpoint"`
+	// Bucket is the bucket outputs are stored in, under <prefix>/<run id>.
+	Bucket string `toml:"bucket"`
+	Prefix string `toml:"prefix"`
+	// Region is the region of the bucket (default: that of the aws table of
+	// .env.toml, or us-east-1).
+	Region string `toml:"region"`
+	// AccessKeyID and SecretAccessKey are the credentials of the object
+	// storage (default: those of the aws table of .env.toml, or of the
+	// environment).
+	AccessKeyID     string `toml:"access_key_id"`
+	SecretAccessKey string `toml:"secret_access_key"`
+	// PathStyle addresses buckets by path rather than by host, as MinIO
+	// requires.
+	PathStyle bool `toml:"path_style"`
+}
+
+// k8sOutputsStorage is where the outputs of the instances of cluster:k8s runs
+// are stored, and collected from.
+type k8sOutputsStorage interface {
+	// volume returns the volume mounted at /outputs in the pods of instances.
+	volume() v1.VolumeSource
+
+	// perPod returns whether the outputs of every instance are uploaded by
+	// the runner once it exits, from the outputs container of its pod,
+	// rather than written to the storage by the instance.
+	perPod() bool
+
+	// store stores a tar archive of outputs of a plan, with paths of the form
+	// <run id>/<group id>/<instance>/<file>.
+	store(ctx context.Context, plan string, archive io.Reader) error
+
+	// collect writes a gzipped tar archive of the outputs of a run.
+	collect(ctx context.Context, input *api.CollectionInput, ow *rpc.OutputWriter) error
+}
+
+// outputsStorage returns the outputs storage configured in cfg.
+func (c *ClusterK8sRunner) outputsStorage(cfg *ClusterK8sRunnerConfig, env *config.EnvConfig) (k8sOutputsStorage, error) {
+	switch cfg.OutputsStorage {
+	case "", k8sOutputsPVC:
+		claim := cfg.OutputsPVC
+		if claim == "" {
+			claim = "efs"
+		}
+		return &pvcOutputs{c: c, cfg: cfg, claim: claim}, nil
+	case k8sOutputsPod:
+		return &podOutputs{dir: filepath.Join(env.Dirs().Outputs(), "cluster_k8s")}, nil
+	case k8sOutputsS3:
+		return newS3Outputs(&cfg.OutputsS3, &env.AWS)
+	default:
+		return nil, fmt.Errorf("unknown outputs storage: %s", cfg.OutputsStorage)
+	}
+}
+
+// pvcOutputs stores outputs in a persistent volume claim shared by all pods,
+// and by the collect-outputs pod, which the runner accesses it through. The
+// volume must be ReadWriteMany, e.g. on EFS.
+type pvcOutputs struct {
+	c     *ClusterK8sRunner
+	cfg   *ClusterK8sRunnerConfig
+	claim string
+}
+
+func (p *pvcOutputs) volume() v1.VolumeSource {
+	return v1.VolumeSource{
+		PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+			ClaimName: p.claim,
+		},
+	}
+}
+
+func (*pvcOutputs) perPod() bool {
+	return false
+}
+
+func (p *pvcOutputs) store(ctx context.Context, _ string, archive io.Reader) error {
+	if err := p.c.ensureCollectOutputsPod(ctx, p.cfg, p.claim); err != nil {
+		return err
+	}
+	return p.c.execInCollectOutputsPod([]string{"tar", "-x", "-f", "-", "-C", "/outputs"}, archive, nil)
+}
+
+func (p *pvcOutputs) collect(ctx context.Context, input *api.CollectionInput, ow *rpc.OutputWriter) error {
+	if err := p.c.ensureCollectOutputsPod(ctx, p.cfg, p.claim); err != nil {
+		return err
+	}
+
+	// tar, compress, and write to stdout.
+	outbuf := bufio.NewWriter(ow.BinaryWriter())
+	defer outbuf.Flush()
+
+	return p.c.execInCollectOutputsPod([]string{"tar", "-C", "/outputs", "-czf", "-", input.RunID}, nil, outbuf)
+}
+
+// podOutputs stores outputs in the outputs directory of the daemon, like the
+// local runners do.
+type podOutputs struct {
+	dir string
+}
+
+func (*podOutputs) volume() v1.VolumeSource {
+	return v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+}
+
+func (*podOutputs) perPod() bool {
+	return true
+}
+
+func (p *podOutputs) store(_ context.Context, plan string, archive io.Reader) error {
+	dir := filepath.Join(p.dir, plan)
+
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(name, dir+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in outputs archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(name, 0777)
+		case tar.TypeReg:
+			err = writeOutputsFile(name, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeOutputsFile(name string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (p *podOutputs) collect(ctx context.Context, input *api.CollectionInput, ow *rpc.OutputWriter) error {
+	return gzipRunOutputs(ctx, p.dir, input, ow)
+}
+
+// s3Outputs stores outputs in an S3-compatible object storage, e.g. MinIO.
+type s3Outputs struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func newS3Outputs(cfg *K8sS3OutputsConfig, awscfg *config.AWSConfig) (*s3Outputs, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("outputs_s3.bucket not set")
+	}
+
+	conf := aws.NewConfig().WithS3ForcePathStyle(cfg.PathStyle)
+	switch {
+	case cfg.Region != "":
+		conf = conf.WithRegion(cfg.Region)
+	case awscfg.Region != "":
+		conf = conf.WithRegion(awscfg.Region)
+	default:
+		conf = conf.WithRegion("us-east-1")
+	}
+	if cfg.Endpoint != "" {
+		conf = conf.WithEndpoint(cfg.Endpoint)
+	}
+	switch {
+	case cfg.AccessKeyID != "" && cfg.SecretAccessKey != "":
+		conf = conf.WithCredentials(credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, ""))
+	case awscfg.AccessKeyID != "" && awscfg.SecretAccessKey != "":
+		conf = conf.WithCredentials(credentials.NewStaticCredentials(awscfg.AccessKeyID, awscfg.SecretAccessKey, ""))
+	}
+
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Outputs{client: s3.New(sess), bucket: cfg.Bucket, prefix: strings.Trim(cfg.Prefix, "/")}, nil
+}
+
+func (*s3Outputs) volume() v1.VolumeSource {
+	return v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+}
+
+func (*s3Outputs) perPod() bool {
+	return true
+}
+
+func (s *s3Outputs) store(ctx context.Context, _ string, archive io.Reader) error {
+	uploader := s3manager.NewUploaderWithClient(s.client)
+
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(path.Join(s.prefix, path.Clean(hdr.Name))),
+			Body:   tr,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", hdr.Name, err)
+		}
+	}
+}
+
+func (s *s3Outputs) collect(ctx context.Context, input *api.CollectionInput, ow *rpc.OutputWriter) error {
+	var objects []*s3.Object
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(path.Join(s.prefix, input.RunID) + "/"),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		objects = append(objects, page.Contents...)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return fmt.Errorf("run ID %s not found with runner %s", input.RunID, input.RunnerID)
+	}
+
+	gz := gzip.NewWriter(ow.BinaryWriter())
+	defer gz.Close()
+
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for _, obj := range objects {
+		hdr := &tar.Header{
+			Name:    strings.TrimPrefix(strings.TrimPrefix(*obj.Key, s.prefix), "/"),
+			Mode:    0644,
+			Size:    *obj.Size,
+			ModTime: *obj.LastModified,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    obj.Key,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, out.Body)
+		_ = out.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instanceExited returns whether the container of the instance of a pod has
+// terminated.
+func instanceExited(pod *v1.Pod) bool {
+	for _, st := range pod.Status.ContainerStatuses {
+		if st.Name == instanceContainer(pod) {
+			return st.State.Terminated != nil
+		}
+	}
+	return false
+}
+
+// uploadPodOutputs uploads the outputs of the instance of a pod from its
+// outputs container to the storage, and then releases the container, so that
+// the pod terminates, even if the upload failed.
+func (c *ClusterK8sRunner) uploadPodOutputs(ctx context.Context, ow *rpc.OutputWriter, input *api.RunInput, pod *v1.Pod, outputs k8sOutputsStorage) {
+	idx, _ := instanceIndex(pod)
+	dir := path.Join(input.RunID, pod.Labels["testground.groupid"], strconv.Itoa(idx))
+
+	pr, pw := io.Pipe()
+	go func() {
+		err := c.execInPod(pod.Name, &v1.PodExecOptions{
+			Container: outputsContainerName,
+			Command:   []string{"tar", "-C", "/outputs", "-cf", "-", dir},
+			Stdout:    true,
+		}, remotecommand.StreamOptions{Stdout: pw})
+		_ = pw.CloseWithError(err)
+	}()
+
+	err := outputs.store(ctx, input.TestPlan, pr)
+	_ = pr.CloseWithError(err)
+	if err != nil {
+		ow.Warnw("failed to upload outputs of pod", "pod", pod.Name, "err", err)
+	} else {
+		ow.Debugw("uploaded outputs of pod", "pod", pod.Name)
+	}
+
+	err = c.execInPod(pod.Name, &v1.PodExecOptions{
+		Container: outputsContainerName,
+		Command:   []string{"touch", "/tmp/collected"},
+		Stdout:    true,
+	}, remotecommand.StreamOptions{Stdout: io.Discard})
+	if err != nil {
+		ow.Warnw("failed to release outputs container of pod", "pod", pod.Name, "err", err)
+	}
+}
+
+// maxConcurrentAPICalls limits the number of concurrent calls to the k8s
+// API of a run, e.g. to create pods or to upload their outputs.
+const maxConcurrentAPICalls = 30
+
+// outputsUploader uploads the outputs of the instances of running pods once
+// they exit, once per pod, maxConcurrentAPICalls pods at a time.
+type outputsUploader struct {
+	c       *ClusterK8sRunner
+	ow      *rpc.OutputWriter
+	input   *api.RunInput
+	outputs k8sOutputsStorage
+
+	// pods whose outputs are being, or have been, uploaded.
+	started map[string]struct{}
+	sem     chan struct{}
+	wg      sync.WaitGroup
+}
+
+// upload starts uploading the outputs of the pods whose instance exited.
+func (u *outputsUploader) upload(ctx context.Context, pods []v1.Pod) {
+	if u.started == nil {
+		u.started = make(map[string]struct{})
+		u.sem = make(chan struct{}, maxConcurrentAPICalls)
+	}
+	for _, p := range pods {
+		p := p
+		if _, ok := u.started[p.Name]; ok || !instanceExited(&p) {
+			continue
+		}
+		u.started[p.Name] = struct{}{}
+		u.wg.Add(1)
+		go func() {
+			defer u.wg.Done()
+
+			select {
+			case u.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-u.sem }()
+
+			u.c.uploadPodOutputs(ctx, u.ow, u.input, &p, u.outputs)
+		}()
+	}
+}
+
+// wait waits for the uploads in progress.
+func (u *outputsUploader) wait() {
+	u.wg.Wait()
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/testground/testground/pkg/config"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func outputsArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestPodOutputsStore(t *testing.T) {
+	dir := t.TempDir()
+	outputs := &podOutputs{dir: dir}
+
+	archive := outputsArchive(t, map[string]string{"run/group/0/run.out": "hello"})
+	if err := outputs.store(context.Background(), "plan", archive); err != nil {
+		t.Fatalf("failed to store outputs: %s", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "plan", "run", "group", "0", "run.out"))
+	if err != nil || string(b) != "hello" {
+		t.Errorf("unexpected stored outputs: %q, %v", b, err)
+	}
+
+	archive = outputsArchive(t, map[string]string{"../../escape": "boom"})
+	if err := outputs.store(context.Background(), "plan", archive); err == nil {
+		t.Errorf("expected error storing outputs outside of the outputs directory")
+	}
+}
+
+func TestOutputsStorage(t *testing.T) {
+	var tests = []struct {
+		cfg      ClusterK8sRunnerConfig
+		perPod   bool
+		hasError bool
+	}{
+		{ClusterK8sRunnerConfig{}, false, false},
+		{ClusterK8sRunnerConfig{OutputsStorage: "pvc", OutputsPVC: "nfs"}, false, false},
+		{ClusterK8sRunnerConfig{OutputsStorage: "pod"}, true, false},
+		{ClusterK8sRunnerConfig{OutputsStorage: "s3", OutputsS3: K8sS3OutputsConfig{Bucket: "outputs", Endpoint: "http://localhost:9000"}}, true, false},
+		{ClusterK8sRunnerConfig{OutputsStorage: "s3"}, false, true},
+		{ClusterK8sRunnerConfig{OutputsStorage: "ftp"}, false, true},
+	}
+
+	c := &ClusterK8sRunner{}
+	env := config.EnvConfig{}.WithHome(t.TempDir())
+	for _, tt := range tests {
+		outputs, err := c.outputsStorage(&tt.cfg, &env)
+		if err != nil {
+			if !tt.hasError {
+				t.Errorf("got error but didn't expect one: %s", err)
+			}
+			continue
+		}
+		if tt.hasError {
+			t.Errorf("expected error for %q", tt.cfg.OutputsStorage)
+			continue
+		}
+		if outputs.perPod() != tt.perPod {
+			t.Errorf("got perPod %t for %q, want %t", outputs.perPod(), tt.cfg.OutputsStorage, tt.perPod)
+		}
+	}
+
+	outputs, _ := c.outputsStorage(&ClusterK8sRunnerConfig{OutputsPVC: "nfs"}, &env)
+	if claim := outputs.volume().PersistentVolumeClaim; claim == nil || claim.ClaimName != "nfs" {
+		t.Errorf("unexpected volume of pvc outputs: %+v", outputs.volume())
+	}
+}
+
+func TestInstanceExited(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "tg-run-a-0"},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: outputsContainerName, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				{Name: "tg-run-a-0", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			},
+		},
+	}
+	if instanceExited(pod) {
+		t.Errorf("instance reported exited while running")
+	}
+
+	pod.Status.ContainerStatuses[1].State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}
+	if !instanceExited(pod) {
+		t.Errorf("instance not reported exited once terminated")
+	}
+}
@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// restConfig returns the client configuration of the context of k.
+func (k KubernetesConfig) restConfig() (*rest.Config, error) {
+	if k.Context == "" {
+		return clientcmd.BuildConfigFromFlags("", k.KubeConfigPath)
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = k.KubeConfigPath
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: k.Context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// resolveK8sTarget returns the cluster and namespace of a context and a
+// namespace, defaulted and checked against the allowlist of env, along with
+// the allowed context.
+func resolveK8sTarget(env *config.K8sConfig, context, namespace string) (KubernetesConfig, config.K8sContextConfig, error) {
+	if context == "" {
+		context = env.DefaultContext
+	}
+
+	var ctxcfg config.K8sContextConfig
+	if len(env.Contexts) > 0 {
+		var ok bool
+		if ctxcfg, ok = env.Contexts[context]; !ok {
+			allowed := make([]string, 0, len(env.Contexts))
+			for name := range env.Contexts {
+				allowed = append(allowed, name)
+			}
+			sort.Strings(allowed)
+			return KubernetesConfig{}, ctxcfg, fmt.Errorf("kubernetes context %q is not allowed; allowed contexts: %s", context, strings.Join(allowed, ", "))
+		}
+	} else if context != "" {
+		return KubernetesConfig{}, ctxcfg, fmt.Errorf("kubernetes context %q is not allowed; declare it in the [k8s.contexts] of the env config", context)
+	}
+
+	allowed := ctxcfg.Namespaces
+	if len(allowed) == 0 {
+		allowed = []string{"default"}
+	}
+	if namespace == "" {
+		namespace = allowed[0]
+	}
+	ok := false
+	for _, ns := range allowed {
+		ok = ok || ns == namespace
+	}
+	if !ok {
+		return KubernetesConfig{}, ctxcfg, fmt.Errorf("namespace %q is not allowed in kubernetes context %q; allowed namespaces: %s", namespace, context, strings.Join(allowed, ", "))
+	}
+
+	target := defaultKubernetesConfig()
+	if ctxcfg.Kubeconfig != "" {
+		target.KubeConfigPath = ctxcfg.Kubeconfig
+	}
+	target.Context = context
+	target.Namespace = namespace
+	return target, ctxcfg, nil
+}
+
+// on returns the runner deploying to target, which shares the clients of its
+// context with the runners of the other namespaces of the context.
+func (c *ClusterK8sRunner) on(target KubernetesConfig) (*ClusterK8sRunner, error) {
+	if target == c.config {
+		return c, nil
+	}
+
+	c.targetsLk.Lock()
+	defer c.targetsLk.Unlock()
+
+	if r, ok := c.targets[target]; ok {
+		return r, nil
+	}
+
+	cluster := KubernetesConfig{KubeConfigPath: target.KubeConfigPath, Context: target.Context}
+	p, ok := c.pools[cluster]
+	if !ok {
+		var err error
+		if p, err = newPool(20, target); err != nil {
+			return nil, err
+		}
+		if c.pools == nil {
+			c.pools = make(map[KubernetesConfig]*pool)
+		}
+		c.pools[cluster] = p
+	}
+
+	r := &ClusterK8sRunner{
+		initialized: true,
+		config:      target,
+		pool:        p,
+		imagesLRU:   c.imagesLRU,
+		syncClient:  c.syncClient,
+	}
+	if c.targets == nil {
+		c.targets = make(map[KubernetesConfig]*ClusterK8sRunner)
+	}
+	c.targets[target] = r
+	return r, nil
+}
+
+// clusters returns the runners of all the targets deployed to since the
+// daemon started, this one first.
+func (c *ClusterK8sRunner) clusters() []*ClusterK8sRunner {
+	c.targetsLk.Lock()
+	defer c.targetsLk.Unlock()
+
+	res := []*ClusterK8sRunner{c}
+	for _, r := range c.targets {
+		res = append(res, r)
+	}
+	return res
+}
+
+// runClusters returns the runners of the targets of a run in progress, or
+// this one if the run is unknown, e.g. once the daemon restarted.
+func (c *ClusterK8sRunner) runClusters(runID string) []*ClusterK8sRunner {
+	c.targetsLk.Lock()
+	defer c.targetsLk.Unlock()
+
+	if p, ok := c.runs[runID]; ok {
+		return p.clusters
+	}
+	return []*ClusterK8sRunner{c}
+}
+
+// k8sPlacement is where the groups of a run are deployed to.
+type k8sPlacement struct {
+	// clusters are the runners of the targets of the run, the one of the run
+	// first.
+	clusters []*ClusterK8sRunner
+	// groups are the runners of groups, by group ID.
+	groups map[string]*ClusterK8sRunner
+	// services are the contexts of groups, with the endpoints of the shared
+	// services, by group ID.
+	services map[string]config.K8sContextConfig
+	// infraNamespace is the namespace of the shared services of the daemon.
+	infraNamespace string
+}
+
+// place resolves the targets of the groups of a run: the context and
+// namespace of the run, unless their group is spread to another context.
+func (c *ClusterK8sRunner) place(env *config.K8sConfig, cfg *ClusterK8sRunnerConfig, groups []*api.RunGroup) (*k8sPlacement, error) {
+	p := &k8sPlacement{
+		groups:         make(map[string]*ClusterK8sRunner, len(groups)),
+		services:       make(map[string]config.K8sContextConfig, len(groups)),
+		infraNamespace: c.config.Namespace,
+	}
+
+	resolve := func(context string) (*ClusterK8sRunner, config.K8sContextConfig, error) {
+		target, ctxcfg, err := resolveK8sTarget(env, context, cfg.Namespace)
+		if err != nil {
+			return nil, ctxcfg, err
+		}
+		// outcomes are collected from the sync service of the daemon, which
+		// is only reachable at its default endpoint from the default context.
+		if target.Context != env.DefaultContext && ctxcfg.SyncServiceHost == "" {
+			return nil, ctxcfg, fmt.Errorf("kubernetes context %q must declare the sync_service_host of the sync service of the daemon, to report outcomes to it", target.Context)
+		}
+		r, err := c.on(target)
+		if err != nil {
+			return nil, ctxcfg, fmt.Errorf("could not init pool of kubernetes context %q: %w", target.Context, err)
+		}
+		for _, other := range p.clusters {
+			if other == r {
+				return r, ctxcfg, nil
+			}
+		}
+		p.clusters = append(p.clusters, r)
+		return r, ctxcfg, nil
+	}
+
+	if _, _, err := resolve(cfg.Context); err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		known[g.ID] = true
+		context, ok := cfg.GroupContexts[g.ID]
+		if !ok {
+			context = cfg.Context
+		}
+		r, ctxcfg, err := resolve(context)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", g.ID, err)
+		}
+		p.groups[g.ID], p.services[g.ID] = r, ctxcfg
+	}
+	for id := range cfg.GroupContexts {
+		if !known[id] {
+			return nil, fmt.Errorf("group_contexts refers to unknown group %s", id)
+		}
+	}
+	return p, nil
+}
+
+// input returns the part of a run deployed to cluster.
+func (p *k8sPlacement) input(input *api.RunInput, cluster *ClusterK8sRunner) *api.RunInput {
+	part := *input
+	part.Groups, part.TotalInstances = nil, 0
+	for _, g := range input.Groups {
+		if p.groups[g.ID] == cluster {
+			part.Groups = append(part.Groups, g)
+			part.TotalInstances += g.Instances
+		}
+	}
+	return &part
+}
+
+// serviceEnv returns the endpoints of the shared services for the instances
+// of a group: those declared by its context, or those of the infra namespace
+// of the daemon otherwise, by their fully qualified names so that they
+// resolve from any namespace.
+func (p *k8sPlacement) serviceEnv(groupID string) []v1.EnvVar {
+	var (
+		svc         = p.services[groupID]
+		redisHost   = p.serviceHost("testground-infra-redis")
+		syncHost    = p.serviceHost("testground-sync-service")
+		influxDBURL = fmt.Sprintf("http://%s:8086", p.serviceHost("influxdb"))
+	)
+	if svc.RedisHost != "" {
+		redisHost = svc.RedisHost
+	}
+	if svc.SyncServiceHost != "" {
+		syncHost = svc.SyncServiceHost
+	}
+	if svc.InfluxDBURL != "" {
+		influxDBURL = svc.InfluxDBURL
+	}
+	return []v1.EnvVar{
+		{Name: "REDIS_HOST", Value: redisHost},
+		{Name: "SYNC_SERVICE_HOST", Value: syncHost},
+		{Name: "INFLUXDB_URL", Value: influxDBURL},
+	}
+}
+
+// serviceHost returns the fully qualified name of a service of the infra
+// namespace.
+func (p *k8sPlacement) serviceHost(name string) string {
+	ns := p.infraNamespace
+	if ns == "" {
+		ns = "default"
+	}
+	return fmt.Sprintf("%s.%s.svc.cluster.local", name, ns)
+}
@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/config"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: east
+  cluster:
+    server: https://east.example.com
+- name: west
+  cluster:
+    server: https://west.example.com
+contexts:
+- name: east
+  context:
+    cluster: east
+- name: west
+  context:
+    cluster: west
+current-context: east
+`
+
+func TestResolveK8sTarget(t *testing.T) {
+	allowlist := &config.K8sConfig{
+		DefaultContext: "east",
+		Contexts: map[string]config.K8sContextConfig{
+			"east": {Namespaces: []string{"team-a", "team-b"}, Kubeconfig: "/etc/kube/east"},
+			"west": {},
+		},
+	}
+
+	var tests = []struct {
+		env       *config.K8sConfig
+		context   string
+		namespace string
+		want      KubernetesConfig
+		hasError  bool
+	}{
+		{&config.K8sConfig{}, "", "", KubernetesConfig{Namespace: "default"}, false},
+		{&config.K8sConfig{}, "", "default", KubernetesConfig{Namespace: "default"}, false},
+		{&config.K8sConfig{}, "", "team-a", KubernetesConfig{}, true},
+		{&config.K8sConfig{}, "east", "", KubernetesConfig{}, true},
+		{allowlist, "", "", KubernetesConfig{KubeConfigPath: "/etc/kube/east", Context: "east", Namespace: "team-a"}, false},
+		{allowlist, "east", "team-b", KubernetesConfig{KubeConfigPath: "/etc/kube/east", Context: "east", Namespace: "team-b"}, false},
+		{allowlist, "east", "default", KubernetesConfig{}, true},
+		{allowlist, "west", "", KubernetesConfig{Context: "west", Namespace: "default"}, false},
+		{allowlist, "north", "", KubernetesConfig{}, true},
+		{&config.K8sConfig{Contexts: allowlist.Contexts}, "", "", KubernetesConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		got, _, err := resolveK8sTarget(tt.env, tt.context, tt.namespace)
+		if err != nil {
+			if !tt.hasError {
+				t.Errorf("%q/%q: got error but didn't expect one: %s", tt.context, tt.namespace, err)
+			}
+			continue
+		}
+		if tt.hasError {
+			t.Errorf("%q/%q: expected error", tt.context, tt.namespace)
+			continue
+		}
+		if tt.want.KubeConfigPath == "" {
+			tt.want.KubeConfigPath = defaultKubernetesConfig().KubeConfigPath
+		}
+		if got != tt.want {
+			t.Errorf("%q/%q: got %+v, want %+v", tt.context, tt.namespace, got, tt.want)
+		}
+	}
+}
+
+func TestPlace(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(kubeconfig, []byte(testKubeconfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := &config.K8sConfig{
+		DefaultContext: "east",
+		Contexts: map[string]config.K8sContextConfig{
+			"east": {Namespaces: []string{"team-a"}, Kubeconfig: kubeconfig},
+			"west": {Namespaces: []string{"team-a"}, Kubeconfig: kubeconfig, SyncServiceHost: "sync.example.com"},
+		},
+	}
+	groups := []*api.RunGroup{{ID: "a", Instances: 2}, {ID: "b", Instances: 3}}
+
+	c := &ClusterK8sRunner{config: defaultKubernetesConfig()}
+	p, err := c.place(env, &ClusterK8sRunnerConfig{GroupContexts: map[string]string{"b": "west"}}, groups)
+	if err != nil {
+		t.Fatalf("failed to place groups: %s", err)
+	}
+	if len(p.clusters) != 2 || p.groups["a"] != p.clusters[0] || p.groups["b"] != p.clusters[1] {
+		t.Fatalf("unexpected placement: %+v", p)
+	}
+	if p.clusters[1].config.Context != "west" || p.clusters[1].config.Namespace != "team-a" {
+		t.Errorf("unexpected target of group b: %+v", p.clusters[1].config)
+	}
+	if part := p.input(&api.RunInput{Groups: groups, TotalInstances: 5}, p.clusters[1]); len(part.Groups) != 1 || part.TotalInstances != 3 {
+		t.Errorf("unexpected part of run: %+v", part)
+	}
+	if env := p.serviceEnv("b"); env[1].Value != "sync.example.com" {
+		t.Errorf("unexpected sync service of group b: %v", env)
+	}
+	if env := p.serviceEnv("a"); env[1].Value != "testground-sync-service.default.svc.cluster.local" || env[2].Value != "http://influxdb.default.svc.cluster.local:8086" {
+		t.Errorf("unexpected services of group a: %v", env)
+	}
+
+	// runners are shared by the runs deployed to the same target.
+	again, err := c.place(env, &ClusterK8sRunnerConfig{Context: "west"}, groups)
+	if err != nil {
+		t.Fatalf("failed to place groups: %s", err)
+	}
+	if len(again.clusters) != 1 || again.clusters[0] != p.clusters[1] {
+		t.Errorf("expected the runner of west to be reused")
+	}
+
+	if _, err := c.place(env, &ClusterK8sRunnerConfig{GroupContexts: map[string]string{"c": "west"}}, groups); err == nil {
+		t.Errorf("expected error placing an unknown group")
+	}
+	if _, err := c.place(env, &ClusterK8sRunnerConfig{GroupContexts: map[string]string{"b": "north"}}, groups); err == nil {
+		t.Errorf("expected error placing a group in a context that's not allowed")
+	}
+
+	// groups outside of the default context must reach the sync service of
+	// the daemon.
+	west := env.Contexts["west"]
+	west.SyncServiceHost = ""
+	env.Contexts["west"] = west
+	if _, err := c.place(env, &ClusterK8sRunnerConfig{GroupContexts: map[string]string{"b": "west"}}, groups); err == nil {
+		t.Errorf("expected error placing a group in a context without a sync service")
+	}
+}
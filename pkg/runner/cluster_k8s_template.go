@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// podTemplateData is the data pod templates are rendered with, e.g.
+// {{ .GroupID }}.
+type podTemplateData struct {
+	RunID     string
+	TestPlan  string
+	TestCase  string
+	GroupID   string
+	Namespace string
+
+	// PodName is the name of the pod, empty with jobs, whose pods are named
+	// by the job controller.
+	PodName string
+
+	// Container is the name of the container of the instance, to patch it
+	// by.
+	Container string
+
+	// Index is the index of the instance in its group, or -1 with jobs, whose
+	// instances share the pod template of their group.
+	Index int
+}
+
+// overlayPod returns the pod of an instance overlaid with the pod templates
+// of cfg, the one of all groups first, then the one of the group of the
+// instance. Overlays can't change the name and the labels the runner relies
+// on.
+func overlayPod(pod *v1.Pod, cfg *ClusterK8sRunnerConfig, data podTemplateData) (*v1.Pod, error) {
+	templates := []string{cfg.PodTemplate, cfg.GroupPodTemplates[data.GroupID]}
+
+	out := pod
+	for _, tpl := range templates {
+		if tpl == "" {
+			continue
+		}
+		var err error
+		if out, err = applyPodTemplate(out, tpl, data); err != nil {
+			return nil, fmt.Errorf("failed to apply pod template of group %s: %w", data.GroupID, err)
+		}
+	}
+	if out == pod {
+		return pod, nil
+	}
+
+	out.Name = pod.Name
+	if out.Labels == nil {
+		out.Labels = make(map[string]string, len(pod.Labels))
+	}
+	for k, v := range pod.Labels {
+		out.Labels[k] = v
+	}
+	return out, nil
+}
+
+// applyPodTemplate renders a pod template with data, and applies it to pod as
+// a strategic merge patch, in YAML or JSON. Lists are merged as kubectl does,
+// e.g. containers and volumes by name, tolerations are replaced.
+func applyPodTemplate(pod *v1.Pod, tpl string, data podTemplateData) (*v1.Pod, error) {
+	t, err := template.New("pod").Option("missingkey=error").Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	patch, err := yaml.ToJSON(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("pod template is neither YAML nor JSON: %w", err)
+	}
+
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, v1.Pod{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(v1.Pod)
+	if err := json.Unmarshal(patched, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "tg-run-a-0",
+			Labels: map[string]string{"testground.purpose": "plan", "testground.groupid": "a"},
+		},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{{Name: "efs-shared", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "efs"},
+			}}},
+			Containers:   []v1.Container{{Name: "tg-run-a-0", Image: "plan"}},
+			NodeSelector: map[string]string{"testground.node.role.plan": "true"},
+		},
+	}
+}
+
+func TestOverlayPod(t *testing.T) {
+	cfg := &ClusterK8sRunnerConfig{
+		PodTemplate: `
+metadata:
+  name: renamed
+  labels:
+    testground.purpose: other
+    team: p2p
+spec:
+  nodeSelector:
+    pool: "{{ .GroupID }}"
+  tolerations:
+  - key: dedicated
+    operator: Exists
+  runtimeClassName: gvisor
+  imagePullSecrets:
+  - name: harbor
+  containers:
+  - name: "{{ .Container }}"
+    securityContext:
+      runAsNonRoot: true
+  - name: proxy
+    image: envoy
+`,
+		GroupPodTemplates: map[string]string{
+			"a": `{"spec": {"volumes": [{"name": "efs-shared", "persistentVolumeClaim": null, "hostPath": {"path": "/mnt/outputs"}}]}}`,
+		},
+	}
+
+	pod, err := overlayPod(testPod(), cfg, podTemplateData{GroupID: "a", PodName: "tg-run-a-0", Container: "tg-run-a-0"})
+	if err != nil {
+		t.Fatalf("failed to overlay pod: %s", err)
+	}
+
+	if pod.Name != "tg-run-a-0" || pod.Labels["testground.purpose"] != "plan" || pod.Labels["team"] != "p2p" {
+		t.Errorf("unexpected name or labels: %s %v", pod.Name, pod.Labels)
+	}
+	if pod.Spec.NodeSelector["pool"] != "a" || pod.Spec.NodeSelector["testground.node.role.plan"] != "true" {
+		t.Errorf("unexpected node selector: %v", pod.Spec.NodeSelector)
+	}
+	if len(pod.Spec.Tolerations) != 1 || pod.Spec.RuntimeClassName == nil || *pod.Spec.RuntimeClassName != "gvisor" {
+		t.Errorf("unexpected tolerations or runtime class: %v %v", pod.Spec.Tolerations, pod.Spec.RuntimeClassName)
+	}
+	if len(pod.Spec.ImagePullSecrets) != 1 || pod.Spec.ImagePullSecrets[0].Name != "harbor" {
+		t.Errorf("unexpected image pull secrets: %v", pod.Spec.ImagePullSecrets)
+	}
+	if c := pod.Spec.Containers; len(c) != 2 || c[0].Image != "plan" || c[0].SecurityContext == nil || c[1].Name != "proxy" {
+		t.Errorf("unexpected containers: %+v", c)
+	}
+	if v := pod.Spec.Volumes; len(v) != 1 || v[0].HostPath == nil || v[0].PersistentVolumeClaim != nil {
+		t.Errorf("unexpected volumes: %+v", v)
+	}
+}
+
+func TestOverlayPodErrors(t *testing.T) {
+	var tests = []struct {
+		name     string
+		template string
+	}{
+		{"unknown field", "spec:\n  nodeSelector:\n    pool: {{ .Group }}\n"},
+		{"unparsable template", "spec: {{ .GroupID"},
+		{"not yaml", "spec: [nodeSelector"},
+	}
+
+	for _, tt := range tests {
+		cfg := &ClusterK8sRunnerConfig{PodTemplate: tt.template}
+		if _, err := overlayPod(testPod(), cfg, podTemplateData{GroupID: "a"}); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestOverlayPodWithoutTemplates(t *testing.T) {
+	pod := testPod()
+	out, err := overlayPod(pod, &ClusterK8sRunnerConfig{}, podTemplateData{GroupID: "a"})
+	if err != nil || out != pod {
+		t.Errorf("expected the pod unchanged, got %v, %v", out, err)
+	}
+}
@@ -3,23 +3,33 @@ package runner
 import (
 	"bufio"
 	"context"
-	"errors"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net"
+	"net/url"
+	"path"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/imdario/mergo"
 	"github.com/testground/sdk-go/ptypes"
 
 	"github.com/testground/sdk-go/runtime"
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/aws"
+	"github.com/testground/testground/pkg/config"
 	"github.com/testground/testground/pkg/conv"
+	"github.com/testground/testground/pkg/healthcheck"
 	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/task"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/client"
@@ -27,7 +37,26 @@ import (
 )
 
 var (
-	_ api.Runner = &ClusterSwarmRunner{}
+	_ api.Runner          = &ClusterSwarmRunner{}
+	_ api.RunTerminatable = &ClusterSwarmRunner{}
+	_ api.Healthchecker   = &ClusterSwarmRunner{}
+)
+
+const (
+	// swarmOutputsVolume is the volume test plans write their outputs to,
+	// under <run_id>/<group_id>/<task slot>.
+	swarmOutputsVolume = "testground-outputs"
+
+	// swarmHelperImage is the image of the services preparing and collecting
+	// the outputs of runs.
+	swarmHelperImage = "busybox"
+
+	// swarmPlanConstraint places tasks on the nodes dedicated to test plans.
+	swarmPlanConstraint = "node.labels.TGRole==worker"
+
+	// swarmDefaultMemory is the memory reserved for the instances of groups
+	// without memory resources.
+	swarmDefaultMemory = 60 * 1024 * 1024
 )
 
 // ClusterSwarmRunnerConfig is the configuration object of this runner. Boolean
@@ -41,7 +70,8 @@ type ClusterSwarmRunnerConfig struct {
 	Background bool `toml:"background"`
 
 	// DockerEndpoint is the URL of the docker swarm manager endpoint, e.g.
-	// "tcp://manager:2376"
+	// "tcp://manager:2376" (default: the docker daemon of the environment,
+	// e.g. a local single-node swarm).
 	DockerEndpoint string `toml:"docker_endpoint"`
 
 	// DockerTLS indicates whether client TLS is enabled.
@@ -62,23 +92,72 @@ type ClusterSwarmRunnerConfig struct {
 	// all logs have been piped. Only used when running in foreground mode
 	// (default is background mode).
 	KeepService bool `toml:"keep_service"`
+
+	// SyncServiceHost is the host the sync service of the swarm is published
+	// on, for the outcomes of runs to be collected (default: the host of
+	// DockerEndpoint, or 127.0.0.1 if it's a unix socket).
+	SyncServiceHost string `toml:"sync_service_host"`
+
+	// SyncServicePort is the port the sync service of the swarm is published
+	// on (default: 5050).
+	SyncServicePort string `toml:"sync_service_port"`
+
+	// OutcomesCollectionTimeout is the time we wait for the sync service to
+	// send us the test outcomes after all instances have finished (default:
+	// 45s).
+	OutcomesCollectionTimeout time.Duration `toml:"outcomes_collection_timeout"`
+
+	// OutputsVolumeDriver is the driver of the volume test plans write their
+	// outputs to. Unless the swarm has a single node, the driver must back
+	// the volume with storage shared by all nodes, e.g. the local driver with
+	// the options of an NFS mount (default: local).
+	OutputsVolumeDriver string `toml:"outputs_volume_driver"`
+
+	// OutputsVolumeOptions are the options of the outputs volume driver.
+	OutputsVolumeOptions map[string]string `toml:"outputs_volume_options"`
+}
+
+// defaultSwarmConfig is the default configuration. Incoming configurations
+// will be merged with this object.
+var defaultSwarmConfig = ClusterSwarmRunnerConfig{
+	SyncServicePort:           "5050",
+	OutcomesCollectionTimeout: 45 * time.Second,
+	OutputsVolumeDriver:       "local",
 }
 
 // ClusterSwarmRunner is a runner that creates a Docker service to launch as
 // many replicated instances of a container as the run job indicates.
-type ClusterSwarmRunner struct{}
+type ClusterSwarmRunner struct {
+	// lk guards clients, the clients of the swarm managers of the runs whose
+	// services may still exist, by run ID.
+	lk      sync.Mutex
+	clients map[string]*client.Client
+
+	// events tallies the sync events of the runs in progress.
+	events runEvents
+}
 
 // TODO runner option to keep containers alive instead of deleting them after
 // the test has run.
-func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) (*api.RunOutput, error) {
-	var (
-		log = ow.With("runner", "cluster:swarm", "run_id", input.RunID)
-		cfg = *input.RunnerConfig.(*ClusterSwarmRunnerConfig)
-	)
+func (r *ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) (*api.RunOutput, error) {
+	log := ow.With("runner", "cluster:swarm", "run_id", input.RunID)
+
+	cfg := defaultSwarmConfig
+	if err := mergo.Merge(&cfg, input.RunnerConfig, mergo.WithOverride); err != nil {
+		return nil, fmt.Errorf("error while merging configurations: %w", err)
+	}
+
+	// instances are replicas of a service per group, scheduled by swarm.
+	warnStartPolicies(log, input)
+
+	result := newResult(input)
+
+	r.events.begin(input.RunID)
+	defer r.events.end(input.RunID)
 
 	// global timeout of 1 minute for the scheduling.
-	ctx, cancelFn := context.WithTimeout(ctx, 1*time.Minute)
-	defer cancelFn()
+	schedCtx, cancelSched := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancelSched()
 
 	parent := fmt.Sprintf("tg-%s-%s-%s", input.TestPlan, input.TestCase, input.RunID)
 
@@ -90,34 +169,46 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		TestInstanceCount:  input.TotalInstances,
 		TestDisableMetrics: input.DisableMetrics,
 		TestSidecar:        true,
+		TestStartTime:      time.Now(),
 	}
 
-	// Create a docker client.
-	var opts []client.Opt
-	if cfg.DockerTLS {
-		opts = append(opts, client.WithTLSClientConfig(cfg.DockerTLSCACertPath, cfg.DockerTLSCertPath, cfg.DockerTLSKeyPath))
-	}
-
-	opts = append(opts, client.WithHost(cfg.DockerEndpoint), client.WithAPIVersionNegotiation())
-	cli, err := client.NewClientWithOpts(opts...)
+	cli, err := newSwarmClient(&cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// first check if redis is running.
-	svcs, err := cli.ServiceList(ctx, types.ServiceListOptions{
-		Filters: filters.NewArgs(filters.Arg("name", "testground-redis")),
-	})
+	// Track the client until the services of the run are removed, so that
+	// the run can be terminated.
+	r.lk.Lock()
+	if r.clients == nil {
+		r.clients = make(map[string]*client.Client)
+	}
+	r.clients[input.RunID] = cli
+	r.lk.Unlock()
+
+	if !cfg.Background && !cfg.KeepService {
+		defer func() {
+			r.lk.Lock()
+			delete(r.clients, input.RunID)
+			r.lk.Unlock()
+		}()
+	}
 
-	if err != nil {
-		return nil, err
-	} else if len(svcs) == 0 {
-		return nil, fmt.Errorf("testground-redis service doesn't exist in the swarm cluster; aborting")
+	// first check if redis and the sync service are running.
+	for _, name := range []string{"testground-redis", "testground-sync-service"} {
+		svcs, err := cli.ServiceList(schedCtx, types.ServiceListOptions{
+			Filters: filters.NewArgs(filters.Arg("name", name)),
+		})
+		if err != nil {
+			return nil, err
+		} else if len(svcs) == 0 {
+			return nil, fmt.Errorf("%s service doesn't exist in the swarm cluster; aborting", name)
+		}
 	}
 
 	// We can't create a network for every testplan on the same range,
 	// so we check how many networks we have and decide based on this number
-	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{
+	networks, err := cli.NetworkList(schedCtx, types.NetworkListOptions{
 		Filters: filters.NewArgs(filters.Arg("label", "testground.name=default")),
 	})
 	if err != nil {
@@ -156,7 +247,7 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		},
 	}
 
-	networkResp, err := cli.NetworkCreate(ctx, parent+"-default", networkSpec)
+	networkResp, err := cli.NetworkCreate(schedCtx, parent+"-default", networkSpec)
 	if err != nil {
 		return nil, err
 	}
@@ -181,17 +272,71 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		}
 	}()
 
-	ow.Infof("fetching an authorization token from AWS ECR")
+	// Create the outputs directories of the instances on every node, as the
+	// volume is local to each node unless its driver shares it.
+	log.Infow("preparing outputs directories", "volume", swarmOutputsVolume)
+
+	var mkdirs []string
+	for _, g := range input.Groups {
+		dir := path.Join("/outputs", input.RunID, g.ID)
+		mkdirs = append(mkdirs, fmt.Sprintf("for i in $(seq 1 %d); do mkdir -p '%s'/$i; done", g.Instances, dir))
+	}
 
-	// Get an authorization token from AWS ECR.
-	auth, err := aws.ECR.GetAuthToken(input.EnvConfig.AWS)
+	prepare, err := runSwarmJob(schedCtx, cli, &cfg, parent+"-outputs", input.RunID, true,
+		"sh", "-c", "set -e; "+strings.Join(mkdirs, "; "))
+	if prepare != "" {
+		if err := cli.ServiceRemove(context.Background(), prepare); err != nil {
+			log.Warnw("failed to remove the service preparing outputs", "service", prepare, "err", err)
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to prepare outputs directories: %w", err)
+	}
+
+	scopts := types.ServiceCreateOptions{
+		QueryRegistry: true,
 	}
 
-	ow.Infof("fetched an authorization token from AWS ECR")
+	// Images pushed to AWS ECR are pulled with the registry auth, which is
+	// propagated to all docker swarm nodes so they can fetch the image
+	// properly. Other images must be pullable by, or present on, the nodes.
+	if pushedToECR(input) {
+		ow.Infof("fetching an authorization token from AWS ECR")
+
+		// Get an authorization token from AWS ECR.
+		auth, err := aws.ECR.GetAuthToken(input.EnvConfig.AWS)
+		if err != nil {
+			return nil, err
+		}
+
+		ow.Infof("fetched an authorization token from AWS ECR")
+
+		scopts.EncodedRegistryAuth = aws.ECR.EncodeAuthToken(auth)
+	}
+
+	// Collect the outcomes of the instances, unless we are not waiting for
+	// them.
+	var (
+		outcomesCtx, cancelOutcomes = context.WithCancel(ctx)
+		outcomesDoneCh              chan bool
+	)
+	defer cancelOutcomes()
+
+	if !cfg.Background {
+		host, port := swarmSyncServiceAddr(&cfg)
+		syncClient, err := newSyncClient(ctx, host, port)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the sync service at %s:%s: %w", host, port, err)
+		}
+		defer syncClient.Close()
+
+		outcomesDoneCh, err = collectOutcomes(outcomesCtx, syncClient, result, &template, &r.events)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect outcomes: %w", err)
+		}
+	}
 
-	services := make(map[string]int, len(input.Groups))
+	services := make(map[string]*api.RunGroup, len(input.Groups))
 	for _, g := range input.Groups {
 		runenv := template
 		runenv.TestGroupID = g.ID
@@ -199,6 +344,9 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		runenv.TestInstanceParams = g.Parameters
 		runenv.TestCaptureProfiles = g.Profiles
 
+		// swarm expands the slot of every task in its environment.
+		runenv.TestOutputsPath = path.Join("/outputs", input.RunID, g.ID, "{{.Task.Slot}}")
+
 		// Serialize the runenv into env variables to pass to docker.
 		env := conv.ToOptionsSlice(runenv.ToEnvVars())
 
@@ -207,9 +355,25 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 			env = append(env, "LOG_LEVEL="+cfg.LogLevel)
 		}
 
+		if g.Resources.CPUSet != "" {
+			log.Warnw("cpusets are not supported by this runner; ignoring", "group", g.ID)
+		}
+		resources, err := swarmResources(g.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resources for group %s: %w", g.ID, err)
+		}
+
 		// Create the service.
 		log.Infow("creating service", "parent", parent, "group", g.ID, "image", g.ArtifactPath, "replicas", g.Instances)
 
+		labels := map[string]string{
+			"testground.plan":     input.TestPlan,
+			"testground.testcase": input.TestCase,
+			"testground.run_id":   input.RunID,
+			"testground.groupid":  g.ID,
+			"testground.purpose":  "plan",
+		}
+
 		cnt := (uint64)(runenv.TestGroupInstanceCount)
 		serviceSpec := swarm.ServiceSpec{
 			Networks: []swarm.NetworkAttachmentConfig{
@@ -221,61 +385,44 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 					Replicas: &cnt,
 				},
 			},
-			Annotations: swarm.Annotations{Name: parent},
+			Annotations: swarm.Annotations{
+				Name:   parent + "-" + g.ID,
+				Labels: labels,
+			},
 			TaskTemplate: swarm.TaskSpec{
 				ContainerSpec: &swarm.ContainerSpec{
-					Image: g.ArtifactPath,
-					Env:   env,
-					Labels: map[string]string{
-						"testground.plan":     input.TestPlan,
-						"testground.testcase": input.TestCase,
-						"testground.run_id":   input.RunID,
-						"testground.groupid":  g.ID,
-					},
+					Image:  g.ArtifactPath,
+					Env:    env,
+					Labels: labels,
+					Mounts: []mount.Mount{swarmOutputsMount(&cfg)},
 				},
 				RestartPolicy: &swarm.RestartPolicy{
 					Condition: swarm.RestartPolicyConditionNone,
 				},
-				Resources: &swarm.ResourceRequirements{
-					Reservations: &swarm.Resources{
-						MemoryBytes: 60 * 1024 * 1024,
-					},
-					Limits: &swarm.Resources{
-						MemoryBytes: 30 * 1024 * 1024,
-					},
-				},
+				Resources: resources,
 				Placement: &swarm.Placement{
 					MaxReplicas: 10000,
-					Constraints: []string{
-						"node.labels.TGRole==worker",
-					},
+					Constraints: []string{swarmPlanConstraint},
 				},
 			},
 		}
 
-		scopts := types.ServiceCreateOptions{
-			QueryRegistry: true,
-			// the registry auth will be propagated to all docker swarm nodes so
-			// they can fetch the image properly.
-			EncodedRegistryAuth: aws.ECR.EncodeAuthToken(auth),
-		}
-
 		ow.Infow("creating the service on docker swarm", "parent", parent, "group", g.ID, "image", g.ArtifactPath, "replicas", g.Instances)
 
 		// Now create the docker swarm service.
-		serviceResp, err := cli.ServiceCreate(ctx, serviceSpec, scopts)
+		serviceResp, err := cli.ServiceCreate(schedCtx, serviceSpec, scopts)
 		if err != nil {
 			return nil, err
 		}
 
 		ow.Infow("service created successfully", "id", serviceResp.ID)
 
-		services[serviceResp.ID] = g.Instances
+		services[serviceResp.ID] = g
 	}
 
 	// If we are running in background mode, return immediately.
 	if cfg.Background {
-		return &api.RunOutput{RunID: input.RunID}, nil
+		return &api.RunOutput{RunID: input.RunID, Result: result}, nil
 	}
 
 	// Docker multiplexes STDOUT and STDERR streams inside the single IO stream
@@ -286,8 +433,8 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 
 	// Tail all services until all instances are done, then remove the service
 	// if the flag has been set.
-	errgrp, ctx := errgroup.WithContext(ctx)
-	for service, count := range services {
+	errgrp, errctx := errgroup.WithContext(ctx)
+	for service, g := range services {
 		rc, err := cli.ServiceLogs(context.Background(), service, types.ContainerLogsOptions{
 			ShowStdout: true,
 			ShowStderr: true,
@@ -314,9 +461,15 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 				defer tick.Stop()
 				defer rc.Close()
 
-				for range tick.C {
+				for {
+					select {
+					case <-tick.C:
+					case <-errctx.Done():
+						return errctx.Err()
+					}
+
 					var finished int
-					tasks, err := cli.TaskList(ctx, types.TaskListOptions{
+					tasks, err := cli.TaskList(errctx, types.TaskListOptions{
 						Filters: filters.NewArgs(filters.Arg("service", service)),
 					})
 
@@ -334,28 +487,50 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 					}
 					ow.Infow("task status", "service", service, "status", status)
 					if finished == count {
-						break
+						return nil
 					}
 				}
-				return nil
 			}
-		}(service, count))
-
-		go func() {
-			err := errgrp.Wait()
-			_ = wpipe.CloseWithError(err)
-		}()
+		}(service, g.Instances))
 	}
 
+	go func() {
+		err := errgrp.Wait()
+		_ = wpipe.CloseWithError(err)
+	}()
+
 	scanner := bufio.NewScanner(rpipe)
 	for scanner.Scan() {
 		fmt.Println(scanner.Text())
 	}
 
-	if !cfg.KeepService {
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-		defer cancel()
+	// All instances are done; give the sync service some time to relay the
+	// outcomes of the last ones.
+	select {
+	case <-outcomesDoneCh:
+		log.Infow("all outcomes are complete")
+	case <-time.After(cfg.OutcomesCollectionTimeout):
+		log.Infow("we timeout'd waiting for outcomes")
+		cancelOutcomes()
+		<-outcomesDoneCh
+	case <-ctx.Done():
+		<-outcomesDoneCh
+	}
+
+	switch ctx.Err() {
+	case context.Canceled:
+		result.Outcome = task.OutcomeCanceled
+	case context.DeadlineExceeded:
+		log.Infow("run canceled after reaching the task timeout")
+		result.Outcome = task.OutcomeFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	recordTaskFailures(ctx, cli, services, result.Journal)
 
+	if !cfg.KeepService {
 		for service := range services {
 			ow.Infow("removing service", "service", service)
 
@@ -369,11 +544,173 @@ func (*ClusterSwarmRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc
 		log.Info("skipping removing the service due to user request")
 	}
 
-	return &api.RunOutput{RunID: input.RunID}, nil
+	return &api.RunOutput{RunID: input.RunID, Result: result}, nil
+}
+
+// recordTaskFailures adds the tasks of services that failed to the journal,
+// tagged by group and instance index.
+func recordTaskFailures(ctx context.Context, cli *client.Client, services map[string]*api.RunGroup, j *Journal) {
+	for service, g := range services {
+		tasks, err := cli.TaskList(ctx, types.TaskListOptions{
+			Filters: filters.NewArgs(filters.Arg("service", service)),
+		})
+		if err != nil {
+			continue
+		}
+		for _, t := range tasks {
+			if s := t.Status.State; s != swarm.TaskStateFailed && s != swarm.TaskStateRejected {
+				continue
+			}
+			msg := fmt.Sprintf("task %s: %s", t.Status.State, t.Status.Err)
+			if cs := t.Status.ContainerStatus; cs != nil {
+				msg += fmt.Sprintf(" (exit code %d)", cs.ExitCode)
+			}
+			j.Events[fmt.Sprintf("%s[%03d]", g.ID, t.Slot-1)] = msg
+		}
+	}
+}
+
+// TerminateRun removes the services and the data network of a run. Only runs
+// started by this process can be terminated, as the swarm manager is part of
+// the configuration of the run.
+func (r *ClusterSwarmRunner) TerminateRun(ctx context.Context, runID string, ow *rpc.OutputWriter) error {
+	r.lk.Lock()
+	cli, ok := r.clients[runID]
+	delete(r.clients, runID)
+	r.lk.Unlock()
+
+	// runs forget their client once they've removed their services.
+	if !ok {
+		ow.Warnw("run is unknown to this runner; if it's still deployed, remove its services from the swarm manager", "run_id", runID)
+		return nil
+	}
+
+	ow.Infow("terminating run", "run_id", runID)
+
+	byRun := filters.NewArgs(filters.Arg("label", "testground.run_id="+runID))
+
+	services, err := cli.ServiceList(ctx, types.ServiceListOptions{Filters: byRun})
+	if err != nil {
+		return fmt.Errorf("failed to list services of run %s: %w", runID, err)
+	}
+	for _, svc := range services {
+		ow.Infow("removing service", "service", svc.ID)
+		if err := cli.ServiceRemove(ctx, svc.ID); err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("failed to remove service %s: %w", svc.ID, err)
+		}
+	}
+
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{Filters: byRun})
+	if err != nil {
+		return fmt.Errorf("failed to list networks of run %s: %w", runID, err)
+	}
+	for _, n := range networks {
+		ow.Infow("removing network", "network", n.Name)
+		if err := cli.NetworkRemove(ctx, n.ID); err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("failed to remove network %s: %w", n.Name, err)
+		}
+	}
+	return nil
 }
 
+// CollectOutputs archives the outputs of a run through a service mounting the
+// outputs volume. The archive is relayed through the logs of the service,
+// encoded in base64, as the swarm manager doesn't give access to the
+// filesystems of tasks.
 func (*ClusterSwarmRunner) CollectOutputs(ctx context.Context, input *api.CollectionInput, ow *rpc.OutputWriter) error {
-	return errors.New("unimplemented")
+	log := ow.With("runner", "cluster:swarm", "run_id", input.RunID)
+
+	cfg := defaultSwarmConfig
+	if err := mergo.Merge(&cfg, input.RunnerConfig, mergo.WithOverride); err != nil {
+		return fmt.Errorf("error while merging configurations: %w", err)
+	}
+
+	cli, err := newSwarmClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Info("collecting outputs")
+
+	archive := "/tmp/" + input.RunID + ".tgz"
+	service, err := runSwarmJob(ctx, cli, &cfg, "tg-collect-outputs-"+input.RunID, input.RunID, false,
+		"sh", "-c", fmt.Sprintf("tar -C /outputs -czf '%s' '%s' && base64 '%s'", archive, input.RunID, archive))
+	if service != "" {
+		defer func() {
+			if err := cli.ServiceRemove(context.Background(), service); err != nil {
+				log.Warnw("failed to remove the service collecting outputs", "service", service, "err", err)
+			}
+		}()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to archive outputs: %w", err)
+	}
+
+	rc, err := cli.ServiceLogs(ctx, service, types.ContainerLogsOptions{ShowStdout: true})
+	if err != nil {
+		return fmt.Errorf("failed to read outputs archive: %w", err)
+	}
+	defer rc.Close()
+
+	rpipe, wpipe := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(wpipe, io.Discard, rc)
+		_ = wpipe.CloseWithError(err)
+	}()
+
+	outbuf := bufio.NewWriter(ow.BinaryWriter())
+	defer outbuf.Flush()
+
+	// the decoder skips the line breaks of the encoded archive.
+	if _, err := io.Copy(outbuf, base64.NewDecoder(base64.StdEncoding, rpipe)); err != nil {
+		_ = rpipe.CloseWithError(err)
+		return fmt.Errorf("failed to decode outputs archive: %w", err)
+	}
+	return nil
+}
+
+// Healthcheck checks that the services test plans rely on are running in the
+// swarm, and that the sync service is reachable for outcomes to be collected.
+func (*ClusterSwarmRunner) Healthcheck(ctx context.Context, engine api.Engine, ow *rpc.OutputWriter, fix bool) (*api.HealthcheckReport, error) {
+	var cc config.CoalescedConfig
+	if engine != nil {
+		cc = cc.Append(engine.EnvConfig().Runners["cluster:swarm"])
+	}
+	obj, err := cc.CoalesceIntoType(reflect.TypeOf(ClusterSwarmRunnerConfig{}))
+	if err != nil {
+		return nil, fmt.Errorf("error while coalescing configuration values: %w", err)
+	}
+
+	cfg := defaultSwarmConfig
+	if err := mergo.Merge(&cfg, obj, mergo.WithOverride); err != nil {
+		return nil, fmt.Errorf("error while merging configurations: %w", err)
+	}
+
+	cli, err := newSwarmClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	host, port := swarmSyncServiceAddr(&cfg)
+
+	hh := &healthcheck.Helper{}
+
+	hh.Enlist("redis service",
+		healthcheck.CheckSwarmService(ctx, cli, "testground-redis"),
+		healthcheck.RequiresManualFixing(),
+	)
+
+	hh.Enlist("sync service",
+		healthcheck.CheckSwarmService(ctx, cli, "testground-sync-service"),
+		healthcheck.RequiresManualFixing(),
+	)
+
+	hh.Enlist("sync service reachable",
+		healthcheck.DialableChecker("tcp", net.JoinHostPort(host, port)),
+		healthcheck.RequiresManualFixing(),
+	)
+
+	return hh.RunChecks(ctx, fix)
 }
 
 func (*ClusterSwarmRunner) ID() string {
@@ -403,3 +740,154 @@ func retry(attempts int, sleep time.Duration, f func() error) (err error) {
 	}
 	return fmt.Errorf("after %d attempts, last error: %s", attempts, err)
 }
+
+// newSwarmClient returns a client of the swarm manager of cfg.
+func newSwarmClient(cfg *ClusterSwarmRunnerConfig) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if cfg.DockerEndpoint != "" {
+		opts = append(opts, client.WithHost(cfg.DockerEndpoint))
+	}
+	if cfg.DockerTLS {
+		opts = append(opts, client.WithTLSClientConfig(cfg.DockerTLSCACertPath, cfg.DockerTLSCertPath, cfg.DockerTLSKeyPath))
+	}
+
+	opts = append(opts, client.WithAPIVersionNegotiation())
+	return client.NewClientWithOpts(opts...)
+}
+
+// swarmSyncServiceAddr returns the host and port the sync service of the
+// swarm of cfg is reachable at.
+func swarmSyncServiceAddr(cfg *ClusterSwarmRunnerConfig) (host, port string) {
+	host, port = cfg.SyncServiceHost, cfg.SyncServicePort
+	if host != "" {
+		return host, port
+	}
+	if u, err := url.Parse(cfg.DockerEndpoint); err == nil && u.Scheme != "unix" && u.Hostname() != "" {
+		return u.Hostname(), port
+	}
+	return "127.0.0.1", port
+}
+
+// swarmResources returns the resource requirements of the tasks of a group,
+// which are reserved on the nodes they are placed on, and enforced as limits.
+func swarmResources(res api.Resources) (*swarm.ResourceRequirements, error) {
+	l, err := parseResources(res)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved := l.Memory
+	if reserved == 0 {
+		reserved = swarmDefaultMemory
+	}
+
+	return &swarm.ResourceRequirements{
+		Reservations: &swarm.Resources{
+			NanoCPUs:    l.NanoCPUs,
+			MemoryBytes: reserved,
+		},
+		Limits: &swarm.Resources{
+			NanoCPUs:    l.NanoCPUs,
+			MemoryBytes: l.Memory,
+		},
+	}, nil
+}
+
+// swarmOutputsMount returns the mount of the outputs volume.
+func swarmOutputsMount(cfg *ClusterSwarmRunnerConfig) mount.Mount {
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: swarmOutputsVolume,
+		Target: "/outputs",
+		VolumeOptions: &mount.VolumeOptions{
+			DriverConfig: &mount.Driver{
+				Name:    cfg.OutputsVolumeDriver,
+				Options: cfg.OutputsVolumeOptions,
+			},
+		},
+	}
+}
+
+// runSwarmJob runs a command to completion in a service mounting the outputs
+// volume, in a single task or, if global, in a task on every node test plans
+// are placed on. It returns the ID of the service, if created, even if the
+// command failed; the caller is responsible for removing it.
+func runSwarmJob(ctx context.Context, cli *client.Client, cfg *ClusterSwarmRunnerConfig, name string, runID string, global bool, cmd ...string) (string, error) {
+	one := uint64(1)
+	mode := swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &one}}
+	if global {
+		mode = swarm.ServiceMode{Global: &swarm.GlobalService{}}
+	}
+
+	spec := swarm.ServiceSpec{
+		Annotations: swarm.Annotations{
+			Name: name,
+			Labels: map[string]string{
+				"testground.run_id":  runID,
+				"testground.purpose": "outputs",
+			},
+		},
+		Mode: mode,
+		TaskTemplate: swarm.TaskSpec{
+			ContainerSpec: &swarm.ContainerSpec{
+				Image:   swarmHelperImage,
+				Command: cmd,
+				Mounts:  []mount.Mount{swarmOutputsMount(cfg)},
+			},
+			RestartPolicy: &swarm.RestartPolicy{
+				Condition: swarm.RestartPolicyConditionNone,
+			},
+			Placement: &swarm.Placement{
+				Constraints: []string{swarmPlanConstraint},
+			},
+		},
+	}
+
+	resp, err := cli.ServiceCreate(ctx, spec, types.ServiceCreateOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	tick := time.NewTicker(1 * time.Second)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return resp.ID, ctx.Err()
+		}
+
+		tasks, err := cli.TaskList(ctx, types.TaskListOptions{
+			Filters: filters.NewArgs(filters.Arg("service", resp.ID)),
+		})
+		if err != nil {
+			return resp.ID, err
+		}
+
+		done := len(tasks) > 0
+		for _, t := range tasks {
+			switch t.Status.State {
+			case swarm.TaskStateComplete:
+			case swarm.TaskStateFailed, swarm.TaskStateRejected, swarm.TaskStateShutdown:
+				return resp.ID, fmt.Errorf("task %s of service %s %s: %s", t.ID, name, t.Status.State, t.Status.Err)
+			default:
+				done = false
+			}
+		}
+		if done {
+			return resp.ID, nil
+		}
+	}
+}
+
+// pushedToECR returns whether the artifacts of any group of input are images
+// in AWS ECR.
+func pushedToECR(input *api.RunInput) bool {
+	for _, g := range input.Groups {
+		if strings.Contains(g.ArtifactPath, ".dkr.ecr.") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/swarm"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+func TestSwarmResources(t *testing.T) {
+	var tests = []struct {
+		resources    api.Resources
+		reservations swarm.Resources
+		limits       swarm.Resources
+		hasError     bool
+	}{
+		{api.Resources{}, swarm.Resources{MemoryBytes: swarmDefaultMemory}, swarm.Resources{}, false},
+		{api.Resources{CPU: "500m"}, swarm.Resources{NanoCPUs: 5e8, MemoryBytes: swarmDefaultMemory}, swarm.Resources{NanoCPUs: 5e8}, false},
+		{api.Resources{CPU: "2", Memory: "512Mi"}, swarm.Resources{NanoCPUs: 2e9, MemoryBytes: 512 << 20}, swarm.Resources{NanoCPUs: 2e9, MemoryBytes: 512 << 20}, false},
+		{api.Resources{Memory: "lots"}, swarm.Resources{}, swarm.Resources{}, true},
+	}
+
+	for _, tt := range tests {
+		req, err := swarmResources(tt.resources)
+		if err != nil {
+			if !tt.hasError {
+				t.Errorf("got error but didn't expect one: %s", err)
+			}
+			continue
+		}
+		if tt.hasError {
+			t.Errorf("expected error for %+v", tt.resources)
+			continue
+		}
+		if !reflect.DeepEqual(*req.Reservations, tt.reservations) || !reflect.DeepEqual(*req.Limits, tt.limits) {
+			t.Errorf("got reservations %+v limits %+v, want %+v and %+v", *req.Reservations, *req.Limits, tt.reservations, tt.limits)
+		}
+	}
+}
+
+func TestSwarmSyncServiceAddr(t *testing.T) {
+	var tests = []struct {
+		cfg  ClusterSwarmRunnerConfig
+		host string
+		port string
+	}{
+		{ClusterSwarmRunnerConfig{SyncServicePort: "5050"}, "127.0.0.1", "5050"},
+		{ClusterSwarmRunnerConfig{DockerEndpoint: "unix:///var/run/docker.sock", SyncServicePort: "5050"}, "127.0.0.1", "5050"},
+		{ClusterSwarmRunnerConfig{DockerEndpoint: "tcp://manager:2376", SyncServicePort: "5050"}, "manager", "5050"},
+		{ClusterSwarmRunnerConfig{DockerEndpoint: "tcp://manager:2376", SyncServiceHost: "sync", SyncServicePort: "6060"}, "sync", "6060"},
+	}
+
+	for _, tt := range tests {
+		host, port := swarmSyncServiceAddr(&tt.cfg)
+		if host != tt.host || port != tt.port {
+			t.Errorf("got %s:%s for endpoint %q, want %s:%s", host, port, tt.cfg.DockerEndpoint, tt.host, tt.port)
+		}
+	}
+}
@@ -10,9 +10,16 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	ss "github.com/testground/sdk-go/sync"
 
 	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/logging"
 	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/syncclient"
 )
 
 // Use consistent IP address ranges for both the data and the control subnet.
@@ -39,6 +46,23 @@ func nextDataNetwork(lenNetworks int) (*net.IPNet, string, error) {
 	return subnet, gw, err
 }
 
+// freeDataNetwork returns the first data subnet, in the order of
+// nextDataNetwork, that is not in inUse.
+func freeDataNetwork(inUse map[string]bool) (*net.IPNet, string, error) {
+	for i := 0; ; i++ {
+		subnet, gw, err := nextDataNetwork(i)
+		if err != nil || !inUse[subnet.String()] {
+			return subnet, gw, err
+		}
+	}
+}
+
+// newSyncClient returns a generic sync client connected to the sync service
+// listening on host:port.
+func newSyncClient(ctx context.Context, host, port string) (*ss.DefaultClient, error) {
+	return syncclient.New(ctx, logging.S(), host, port)
+}
+
 func gzipRunOutputs(ctx context.Context, basedir string, input *api.CollectionInput, ow *rpc.OutputWriter) error {
 	pattern := filepath.Join(basedir, "*", input.RunID)
 
@@ -115,9 +139,51 @@ func gzipRunOutputs(ctx context.Context, basedir string, input *api.CollectionIn
 	return nil
 }
 
-func reviewResources(group *api.RunGroup, ow *rpc.OutputWriter) {
-	log := ow.With("group_id", group.ID)
-	if group.Resources.CPU != "" || group.Resources.Memory != "" {
-		log.Warnw("group has resources set. Note that resources requirement and limits are ignored by this runner.")
+// instanceLimits are the resource limits the local runners apply to every
+// instance of a group.
+type instanceLimits struct {
+	// NanoCPUs is the CPU quota, in units of 1e-9 CPUs. Zero means unlimited.
+	NanoCPUs int64
+	// Memory is the memory limit in bytes. Zero means unlimited.
+	Memory int64
+	// CPUSet is the set of CPUs instances are pinned to, in cpuset list
+	// format. Empty means any CPU.
+	CPUSet string
+}
+
+// empty returns whether no limit is set.
+func (l instanceLimits) empty() bool {
+	return l.NanoCPUs == 0 && l.Memory == 0 && l.CPUSet == ""
+}
+
+var cpusetRe = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// parseResources translates the resources of a group, expressed as
+// Kubernetes quantities like on cluster:k8s, into instance limits.
+func parseResources(r api.Resources) (instanceLimits, error) {
+	var l instanceLimits
+	if r.CPU != "" {
+		q, err := resource.ParseQuantity(r.CPU)
+		if err != nil {
+			return l, fmt.Errorf("invalid cpu resources %q: %w", r.CPU, err)
+		}
+		l.NanoCPUs = q.MilliValue() * 1e6
+	}
+	if r.Memory != "" {
+		q, err := resource.ParseQuantity(r.Memory)
+		if err != nil {
+			return l, fmt.Errorf("invalid memory resources %q: %w", r.Memory, err)
+		}
+		l.Memory = q.Value()
+	}
+	if r.CPUSet != "" {
+		if !cpusetRe.MatchString(r.CPUSet) {
+			return l, fmt.Errorf("invalid cpuset %q", r.CPUSet)
+		}
+		l.CPUSet = r.CPUSet
+	}
+	if l.NanoCPUs < 0 || l.Memory < 0 {
+		return l, fmt.Errorf("negative resources: %+v", r)
 	}
+	return l, nil
 }
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+// groupCapacity returns the resources requested by the instances of a group,
+// falling back to defaults for those the group leaves unset.
+func groupCapacity(g *api.RunGroup, defaults api.Resources) (api.GroupCapacity, error) {
+	r := g.Resources
+	if r.CPU == "" {
+		r.CPU = defaults.CPU
+	}
+	if r.Memory == "" {
+		r.Memory = defaults.Memory
+	}
+
+	limits, err := parseResources(r)
+	if err != nil {
+		return api.GroupCapacity{}, fmt.Errorf("group %s: %w", g.ID, err)
+	}
+	return api.GroupCapacity{
+		ID:        g.ID,
+		Instances: g.Instances,
+		CPU:       float64(limits.NanoCPUs) / 1e9,
+		Memory:    limits.Memory,
+	}, nil
+}
+
+// newCapacityReport compares the resources requested by the instances of
+// groups with the CPUs and memory a target can allocate to them.
+func newCapacityReport(target string, groups []*api.RunGroup, defaults api.Resources, cpu float64, memory int64) (*api.CapacityReport, error) {
+	rep := &api.CapacityReport{
+		Target:          target,
+		Groups:          make([]api.GroupCapacity, 0, len(groups)),
+		AvailableCPU:    cpu,
+		AvailableMemory: memory,
+	}
+
+	var unlimited []string
+	for _, g := range groups {
+		gc, err := groupCapacity(g, defaults)
+		if err != nil {
+			return nil, err
+		}
+		rep.Groups = append(rep.Groups, gc)
+		rep.RequestedCPU += gc.CPU * float64(gc.Instances)
+		rep.RequestedMemory += gc.Memory * int64(gc.Instances)
+		if gc.CPU == 0 || gc.Memory == 0 {
+			unlimited = append(unlimited, g.ID)
+		}
+	}
+
+	if len(unlimited) > 0 {
+		rep.Notes = append(rep.Notes, fmt.Sprintf("instances of groups %s don't request cpu or memory, which can't be accounted for", strings.Join(unlimited, ", ")))
+	}
+	return rep, nil
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+func TestNewCapacityReport(t *testing.T) {
+	groups := []*api.RunGroup{
+		{ID: "a", Instances: 10, Resources: api.Resources{CPU: "500m", Memory: "1Gi"}},
+		{ID: "b", Instances: 4},
+	}
+
+	var tests = []struct {
+		name      string
+		defaults  api.Resources
+		cpu       float64
+		memory    int64
+		wantCPU   float64
+		wantMem   int64
+		fits      bool
+		shortCPU  float64
+		shortMem  int64
+		unlimited bool
+	}{
+		{"defaults", api.Resources{CPU: "1", Memory: "512Mi"}, 16, 16 << 30, 9, 12 << 30, true, 0, 0, false},
+		{"short of cpu", api.Resources{CPU: "1", Memory: "512Mi"}, 8, 16 << 30, 9, 12 << 30, false, 1, 0, false},
+		{"short of memory", api.Resources{CPU: "1", Memory: "512Mi"}, 16, 4 << 30, 9, 12 << 30, false, 0, 8 << 30, false},
+		{"unlimited", api.Resources{}, 16, 16 << 30, 5, 10 << 30, true, 0, 0, true},
+		{"unknown memory", api.Resources{}, 4, -1, 5, 10 << 30, false, 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		rep, err := newCapacityReport("host", groups, tt.defaults, tt.cpu, tt.memory)
+		if err != nil {
+			t.Fatalf("%s: failed to report capacity: %s", tt.name, err)
+		}
+		if rep.RequestedCPU != tt.wantCPU || rep.RequestedMemory != tt.wantMem {
+			t.Errorf("%s: got requested %v CPUs and %d bytes, want %v and %d", tt.name, rep.RequestedCPU, rep.RequestedMemory, tt.wantCPU, tt.wantMem)
+		}
+		if rep.Fits() != tt.fits {
+			t.Errorf("%s: got fits %t, want %t", tt.name, rep.Fits(), tt.fits)
+		}
+		if cpu, mem := rep.Shortfall(); cpu != tt.shortCPU || mem != tt.shortMem {
+			t.Errorf("%s: got shortfall of %v CPUs and %d bytes, want %v and %d", tt.name, cpu, mem, tt.shortCPU, tt.shortMem)
+		}
+		if (len(rep.Notes) > 0) != tt.unlimited {
+			t.Errorf("%s: unexpected notes: %v", tt.name, rep.Notes)
+		}
+	}
+
+	if _, err := newCapacityReport("host", []*api.RunGroup{{ID: "c", Resources: api.Resources{CPU: "lots"}}}, api.Resources{}, 1, 1); err == nil {
+		t.Errorf("expected error for invalid resources")
+	}
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FailureReason classifies why an instance failed.
+type FailureReason string
+
+const (
+	// FailureOOMKilled is an instance killed for exceeding its memory limit.
+	FailureOOMKilled FailureReason = "oom_killed"
+	// FailureEvicted is an instance evicted from its node, e.g. under
+	// memory or disk pressure.
+	FailureEvicted FailureReason = "evicted"
+	// FailureImagePull is an instance whose image couldn't be pulled.
+	FailureImagePull FailureReason = "image_pull"
+	// FailureUnschedulable is an instance that couldn't be scheduled, e.g.
+	// for lack of resources.
+	FailureUnschedulable FailureReason = "unschedulable"
+	// FailureInitContainer is an instance whose init containers failed.
+	FailureInitContainer FailureReason = "init_container"
+	// FailureExited is an instance that exited with a non-zero code.
+	FailureExited FailureReason = "exited"
+)
+
+// InstanceFailure diagnoses the failure of an instance, with the events
+// and the last log lines relevant to it.
+type InstanceFailure struct {
+	GroupID  string        `json:"group_id" mapstructure:"group_id"`
+	Index    int           `json:"index" mapstructure:"index"`
+	ID       string        `json:"id" mapstructure:"id"`
+	Reason   FailureReason `json:"reason" mapstructure:"reason"`
+	Message  string        `json:"message,omitempty" mapstructure:"message"`
+	ExitCode int           `json:"exit_code,omitempty" mapstructure:"exit_code"`
+	Events   []string      `json:"events,omitempty" mapstructure:"events"`
+	LastLogs []string      `json:"last_logs,omitempty" mapstructure:"last_logs"`
+}
+
+func (f *InstanceFailure) String() string {
+	s := fmt.Sprintf("%s[%03d] %s", f.GroupID, f.Index, f.Reason)
+	if f.ExitCode != 0 {
+		s += fmt.Sprintf(" (exit code %d)", f.ExitCode)
+	}
+	if f.Message != "" {
+		s += ": " + f.Message
+	}
+	return s
+}
+
+// sortFailures sorts failures by group, and index within their group.
+func sortFailures(failures []*InstanceFailure) {
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].GroupID != failures[j].GroupID {
+			return failures[i].GroupID < failures[j].GroupID
+		}
+		return failures[i].Index < failures[j].Index
+	})
+}
+
+// FailureSummary counts the failed instances by reason and group, e.g.
+// "oom_killed: 2 (miner: 2); image_pull: 1 (relay: 1)", or returns "" if
+// none failed.
+func (r *Result) FailureSummary() string {
+	var (
+		reasons []FailureReason
+		counts  = make(map[FailureReason]map[string]int)
+	)
+	for _, f := range r.Failures {
+		if counts[f.Reason] == nil {
+			counts[f.Reason] = make(map[string]int)
+			reasons = append(reasons, f.Reason)
+		}
+		counts[f.Reason][f.GroupID]++
+	}
+
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		var (
+			groups = make([]string, 0, len(counts[reason]))
+			total  int
+		)
+		for g, n := range counts[reason] {
+			groups = append(groups, fmt.Sprintf("%s: %d", g, n))
+			total += n
+		}
+		sort.Strings(groups)
+		parts = append(parts, fmt.Sprintf("%s: %d (%s)", reason, total, strings.Join(groups, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/testground/sdk-go/runtime"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+// groupIndexLabel is the label of containers and pods holding the index of
+// their instance within its group.
+const groupIndexLabel = "testground.group_index"
+
+// defaultAttachCommand is executed in the instances attached to without a
+// command.
+var defaultAttachCommand = []string{"/bin/sh"}
+
+// sortInstances sorts instances by group, and index within their group.
+func sortInstances(instances []api.InstanceStatus) {
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].GroupID != instances[j].GroupID {
+			return instances[i].GroupID < instances[j].GroupID
+		}
+		return instances[i].Index < instances[j].Index
+	})
+}
+
+// runEvents tallies the sync events of the runs in progress of a runner, by
+// group, so that they can be inspected. The zero value is ready to use.
+type runEvents struct {
+	lk   sync.Mutex
+	runs map[string]map[string]*api.GroupEvents
+}
+
+// begin starts tallying the events of a run.
+func (re *runEvents) begin(runID string) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+
+	if re.runs == nil {
+		re.runs = make(map[string]map[string]*api.GroupEvents)
+	}
+	re.runs[runID] = make(map[string]*api.GroupEvents)
+}
+
+// end forgets the events of a run, once it's over.
+func (re *runEvents) end(runID string) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+
+	delete(re.runs, runID)
+}
+
+// add tallies an event of a run. Events other than start, success, failure
+// and crash events are ignored, as are events of runs that are not in
+// progress.
+func (re *runEvents) add(runID string, e *runtime.Event) {
+	var group, kind string
+	switch {
+	case e == nil:
+		return
+	case e.StartEvent != nil && e.StartEvent.Runenv != nil:
+		group, kind = e.StartEvent.Runenv.TestGroupID, "start"
+	case e.SuccessEvent != nil:
+		group, kind = e.SuccessEvent.TestGroupID, "success"
+	case e.FailureEvent != nil:
+		group, kind = e.FailureEvent.TestGroupID, "failure"
+	case e.CrashEvent != nil:
+		group, kind = e.CrashEvent.TestGroupID, "crash"
+	default:
+		return
+	}
+
+	re.lk.Lock()
+	defer re.lk.Unlock()
+
+	groups, ok := re.runs[runID]
+	if !ok {
+		return
+	}
+	ge, ok := groups[group]
+	if !ok {
+		ge = &api.GroupEvents{}
+		groups[group] = ge
+	}
+
+	switch kind {
+	case "start":
+		ge.Start++
+	case "success":
+		ge.Success++
+	case "failure":
+		ge.Failure++
+	case "crash":
+		ge.Crash++
+	}
+	ge.Last = kind
+}
+
+// snapshot returns a copy of the tallies of a run, or an error if the run
+// is not in progress.
+func (re *runEvents) snapshot(runID string) (map[string]*api.GroupEvents, error) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+
+	groups, ok := re.runs[runID]
+	if !ok {
+		return nil, fmt.Errorf("run %s is not in progress", runID)
+	}
+
+	res := make(map[string]*api.GroupEvents, len(groups))
+	for g, ge := range groups {
+		cpy := *ge
+		res[g] = &cpy
+	}
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testground/sdk-go/runtime"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+func TestRunEvents(t *testing.T) {
+	var re runEvents
+
+	_, err := re.snapshot("run")
+	require.Error(t, err)
+
+	re.begin("run")
+	re.add("run", &runtime.Event{StartEvent: &runtime.StartEvent{Runenv: &runtime.RunParams{TestGroupID: "a"}}})
+	re.add("run", &runtime.Event{StartEvent: &runtime.StartEvent{Runenv: &runtime.RunParams{TestGroupID: "a"}}})
+	re.add("run", &runtime.Event{SuccessEvent: &runtime.SuccessEvent{TestGroupID: "a"}})
+	re.add("run", &runtime.Event{CrashEvent: &runtime.CrashEvent{TestGroupID: "b"}})
+	re.add("run", &runtime.Event{MessageEvent: &runtime.MessageEvent{Message: "ignored"}})
+	re.add("run", nil)
+
+	// events of other runs are ignored.
+	re.add("other", &runtime.Event{SuccessEvent: &runtime.SuccessEvent{TestGroupID: "a"}})
+
+	events, err := re.snapshot("run")
+	require.NoError(t, err)
+	require.Equal(t, map[string]*api.GroupEvents{
+		"a": {Start: 2, Success: 1, Last: "success"},
+		"b": {Crash: 1, Last: "crash"},
+	}, events)
+
+	// snapshots are copies.
+	events["a"].Start = 10
+	events, _ = re.snapshot("run")
+	require.Equal(t, 2, events["a"].Start)
+
+	re.end("run")
+	_, err = re.snapshot("run")
+	require.Error(t, err)
+}
@@ -1,6 +1,11 @@
 package runner
 
 import (
+	"context"
+
+	"github.com/testground/sdk-go/runtime"
+	ss "github.com/testground/sdk-go/sync"
+
 	"github.com/testground/testground/pkg/api"
 	"github.com/testground/testground/pkg/task"
 )
@@ -9,6 +14,13 @@ type Result struct {
 	Outcome  task.Outcome             `json:"outcome"`
 	Outcomes map[string]*GroupOutcome `json:"outcomes"`
 	Journal  *Journal                 `json:"journal"`
+
+	// Usage summarizes the resource usage of every group, if sampled.
+	Usage map[string]*GroupUsage `json:"usage,omitempty"`
+
+	// Failures diagnoses the instances that failed, if the runner can tell
+	// why.
+	Failures []*InstanceFailure `json:"failures,omitempty"`
 }
 
 func newResult(input *api.RunInput) *Result {
@@ -23,8 +35,9 @@ func newResult(input *api.RunInput) *Result {
 
 	for _, g := range input.Groups {
 		result.Outcomes[g.ID] = &GroupOutcome{
-			Total: g.Instances,
-			Ok:    0,
+			Total:       g.Instances,
+			Ok:          0,
+			Coordinates: g.Coordinates,
 		}
 	}
 
@@ -57,3 +70,48 @@ func (r *Result) updateOutcome() {
 	}
 	r.Outcome = task.OutcomeSuccess
 }
+
+// collectOutcomes listens to the sync service and collects the outcome for every test instance.
+// It stops when all instances have submitted a result or the context was canceled.
+// The events received are tallied in events.
+func collectOutcomes(ctx context.Context, client *ss.DefaultClient, result *Result, tpl *runtime.RunParams, events *runEvents) (chan bool, error) {
+	eventsCh, err := client.SubscribeEvents(ctx, tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: eventually we'll keep a trace of each test instance status.
+	// Right now, if a container sends multiple events, it will mess up the outcomes.
+	// We have to pass its group id to the container, so that it can send us back messages
+	// with its own id.
+	expectingOutcomes := result.countTotalInstances()
+	done := make(chan bool)
+
+	go func() {
+		running := true
+		for running && expectingOutcomes > 0 {
+			select {
+			case <-ctx.Done():
+				running = false
+			case e := <-eventsCh:
+				events.add(tpl.TestRun, e)
+				if e.SuccessEvent != nil {
+					result.addOutcome(e.SuccessEvent.TestGroupID, task.OutcomeSuccess)
+					expectingOutcomes -= 1
+				} else if e.FailureEvent != nil {
+					result.addOutcome(e.FailureEvent.TestGroupID, task.OutcomeFailure)
+					expectingOutcomes -= 1
+				} else if e.CrashEvent != nil {
+					result.addOutcome(e.CrashEvent.TestGroupID, task.OutcomeFailure)
+					expectingOutcomes -= 1
+				}
+				// else: skip
+			}
+		}
+
+		result.updateOutcome()
+		done <- true
+	}()
+
+	return done, nil
+}
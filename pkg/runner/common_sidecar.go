@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/testground/sdk-go/runtime"
+	ss "github.com/testground/sdk-go/sync"
+
+	"github.com/testground/testground/pkg/sidecar"
+)
+
+// sidecarWatch follows the instance events the sidecar publishes for a run,
+// so that the run fails fast when the sidecar can't manage its instances,
+// instead of hanging on the "network-initialized" barrier.
+type sidecarWatch struct {
+	fail func(error)
+
+	// settled are the hostnames of the instances managed by the sidecar, or
+	// that exited.
+	lk      sync.Mutex
+	settled map[string]bool
+	changed chan struct{}
+}
+
+// watchSidecar starts following the instance events of a run. fail is
+// called with the first failure to manage an instance.
+func watchSidecar(ctx context.Context, client ss.Client, tpl *runtime.RunParams, fail func(error)) (*sidecarWatch, error) {
+	w := &sidecarWatch{
+		fail:    fail,
+		settled: make(map[string]bool),
+		changed: make(chan struct{}, 1),
+	}
+
+	ch := make(chan *sidecar.InstanceEvent, 16)
+	if _, err := client.Subscribe(ss.WithRunParams(ctx, tpl), sidecar.InstanceEventsTopic, ch); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to sidecar events: %w", err)
+	}
+
+	go func() {
+		var once sync.Once
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-ch:
+				if err := w.add(e); err != nil {
+					once.Do(func() { w.fail(err) })
+				}
+			}
+		}
+	}()
+	return w, nil
+}
+
+// add accounts for an instance event, and returns an error if the sidecar
+// failed to manage the instance.
+func (w *sidecarWatch) add(e *sidecar.InstanceEvent) error {
+	switch e.State {
+	case sidecar.InstanceFailed:
+		return fmt.Errorf("sidecar failed to manage instance %s of group %s: %s", e.Hostname, e.GroupID, e.Error)
+	case sidecar.InstanceManaged:
+		w.settle(e.Hostname)
+	}
+	return nil
+}
+
+// exited settles an instance that exited, which the sidecar may not have
+// seen running.
+func (w *sidecarWatch) exited(hostname string) {
+	w.settle(hostname)
+}
+
+func (w *sidecarWatch) settle(hostname string) {
+	w.lk.Lock()
+	w.settled[hostname] = true
+	w.lk.Unlock()
+
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (w *sidecarWatch) count() int {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	return len(w.settled)
+}
+
+// awaitManaged waits for the sidecar to manage total instances, unless they
+// exited, and returns an error if it didn't within timeout.
+func (w *sidecarWatch) awaitManaged(ctx context.Context, total int, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		if n := w.count(); n >= total {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.changed:
+		case <-deadline.C:
+			return fmt.Errorf("sidecar managed %d of %d instances within %s; check that it's running and look for errors in its logs", w.count(), total, timeout)
+		}
+	}
+}
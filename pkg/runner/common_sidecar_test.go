@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testground/sdk-go/runtime"
+	ss "github.com/testground/sdk-go/sync"
+
+	"github.com/testground/testground/pkg/sidecar"
+)
+
+func TestWatchSidecar(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		client = ss.NewInmemClient()
+		tpl    = &runtime.RunParams{TestRun: "run"}
+		failed = make(chan error, 2)
+	)
+	w, err := watchSidecar(ctx, client, tpl, func(err error) { failed <- err })
+	if err != nil {
+		t.Fatalf("failed to watch sidecar: %s", err)
+	}
+
+	publish := func(e *sidecar.InstanceEvent) {
+		if _, err := client.Publish(ss.WithRunParams(ctx, tpl), sidecar.InstanceEventsTopic, e); err != nil {
+			t.Fatalf("failed to publish: %s", err)
+		}
+	}
+
+	publish(&sidecar.InstanceEvent{Hostname: "a0", GroupID: "a", State: sidecar.InstanceManaging})
+	publish(&sidecar.InstanceEvent{Hostname: "a0", GroupID: "a", State: sidecar.InstanceManaged})
+	w.exited("a1")
+
+	if err := w.awaitManaged(ctx, 2, time.Second); err != nil {
+		t.Errorf("expected managed and exited instances to be settled: %s", err)
+	}
+	if err := w.awaitManaged(ctx, 3, 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "2 of 3") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+
+	publish(&sidecar.InstanceEvent{Hostname: "b0", GroupID: "b", State: sidecar.InstanceFailed, Error: "no netns"})
+	publish(&sidecar.InstanceEvent{Hostname: "b1", GroupID: "b", State: sidecar.InstanceFailed, Error: "no netns"})
+
+	select {
+	case err := <-failed:
+		if !strings.Contains(err.Error(), "instance b0 of group b: no netns") {
+			t.Errorf("unexpected failure: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the failure to be reported")
+	}
+
+	select {
+	case err := <-failed:
+		t.Errorf("expected only the first failure to be reported, got %s", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
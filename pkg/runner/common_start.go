@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/testground/sdk-go/runtime"
+	ss "github.com/testground/sdk-go/sync"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+// barrierFunc waits until a sync state has been signalled target times.
+type barrierFunc func(ctx context.Context, state string, target int) error
+
+// syncBarrier returns a barrierFunc waiting on the states of the run
+// described by tpl.
+func syncBarrier(client ss.Client, tpl *runtime.RunParams) barrierFunc {
+	return func(ctx context.Context, state string, target int) error {
+		b, err := client.Barrier(ss.WithRunParams(ctx, tpl), ss.State(state), target)
+		if err != nil {
+			return err
+		}
+		select {
+		case err := <-b.C:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// startScheduler paces the start of the instances of a run according to the
+// start policies of their groups, and records when they actually started.
+type startScheduler struct {
+	begin   time.Time
+	barrier barrierFunc
+	groups  map[string]*groupStart
+
+	lk     sync.Mutex
+	starts map[string]time.Time
+}
+
+// groupStart tracks when a group may start.
+type groupStart struct {
+	policy api.StartPolicy
+	delay  time.Duration
+	after  int // the number of instances of the group waited for.
+
+	once sync.Once
+	at   time.Time
+	err  error
+}
+
+// newStartScheduler returns a scheduler for the groups of input, which waits
+// on sync states with barrier.
+func newStartScheduler(input *api.RunInput, barrier barrierFunc) (*startScheduler, error) {
+	s := &startScheduler{
+		begin:   time.Now(),
+		barrier: barrier,
+		groups:  make(map[string]*groupStart, len(input.Groups)),
+		starts:  make(map[string]time.Time, input.TotalInstances),
+	}
+
+	instances := make(map[string]int, len(input.Groups))
+	for _, g := range input.Groups {
+		instances[g.ID] = g.Instances
+	}
+
+	for _, g := range input.Groups {
+		delay, err := g.Start.DelayDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid start delay for group %s: %w", g.ID, err)
+		}
+		s.groups[g.ID] = &groupStart{
+			policy: g.Start,
+			delay:  delay,
+			after:  instances[g.Start.AfterGroup],
+		}
+	}
+	return s, nil
+}
+
+// wait blocks until instance idx of a group may start: once the group may
+// start, and its instances with lower indices have been given their share
+// of the start rate.
+func (s *startScheduler) wait(ctx context.Context, ow *rpc.OutputWriter, groupID string, idx int) error {
+	g, ok := s.groups[groupID]
+	if !ok {
+		return nil
+	}
+
+	g.once.Do(func() {
+		g.at = s.begin
+		if p := g.policy; p.AfterGroup != "" {
+			ow.Infow("group waiting for state", "group", groupID, "after_group", p.AfterGroup, "state", p.AfterState, "target", g.after)
+			if g.err = s.barrier(ctx, p.AfterState, g.after); g.err != nil {
+				g.err = fmt.Errorf("group %s failed waiting for state %s: %w", groupID, p.AfterState, g.err)
+				return
+			}
+			g.at = time.Now()
+		}
+		g.at = g.at.Add(g.delay)
+	})
+	if g.err != nil {
+		return g.err
+	}
+
+	at := g.at
+	if r := g.policy.Rate; r > 0 {
+		at = at.Add(time.Duration(float64(idx) / r * float64(time.Second)))
+	}
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// started records the start of instance idx of a group.
+func (s *startScheduler) started(groupID string, idx int) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.starts[fmt.Sprintf("%s[%03d]", groupID, idx)] = time.Now()
+}
+
+// record adds the start times of the instances to the journal.
+func (s *startScheduler) record(j *Journal) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if j.Starts == nil {
+		j.Starts = make(map[string]time.Time, len(s.starts))
+	}
+	for tag, t := range s.starts {
+		j.Starts[tag] = t
+	}
+}
+
+// startOrder returns the groups of input ordered so that groups come after
+// the groups they wait for, and in their original order otherwise. Runners
+// that start instances one after the other start them in this order, so that
+// groups don't wait for instances that haven't been started yet.
+func startOrder(input *api.RunInput) []*api.RunGroup {
+	after := make(map[string]string, len(input.Groups))
+	for _, g := range input.Groups {
+		after[g.ID] = g.Start.AfterGroup
+	}
+
+	// the depth of a group is the length of the chain of groups it waits
+	// for, which is acyclic once the composition is validated.
+	depth := func(id string) int {
+		d := 0
+		for next := after[id]; next != "" && d < len(after); next = after[next] {
+			d++
+		}
+		return d
+	}
+
+	groups := make([]*api.RunGroup, len(input.Groups))
+	copy(groups, input.Groups)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return depth(groups[i].ID) < depth(groups[j].ID)
+	})
+	return groups
+}
+
+// warnStartPolicies warns that the start policies of the groups of input
+// are ignored, by runners that don't support them.
+func warnStartPolicies(ow *rpc.OutputWriter, input *api.RunInput) {
+	for _, g := range input.Groups {
+		if g.Start != (api.StartPolicy{}) {
+			ow.Warnw("start policies are not supported by this runner; ignoring", "group", g.ID)
+		}
+	}
+}
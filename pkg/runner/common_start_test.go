@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+func TestStartSchedulerPacesGroups(t *testing.T) {
+	input := &api.RunInput{
+		TotalInstances: 4,
+		Groups: []*api.RunGroup{
+			{ID: "free", Instances: 1},
+			{ID: "ramped", Instances: 3, Start: api.StartPolicy{Delay: "50ms", Rate: 20}},
+		},
+	}
+
+	sched, err := newStartScheduler(input, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	start := time.Now()
+
+	require.NoError(t, sched.wait(ctx, rpc.Discard(), "free", 0))
+	require.Less(t, int64(time.Since(start)), int64(40*time.Millisecond))
+
+	// the third instance starts after the delay, and two intervals of the
+	// rate: 50ms + 2 * 50ms.
+	require.NoError(t, sched.wait(ctx, rpc.Discard(), "ramped", 2))
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(150*time.Millisecond))
+
+	sched.started("ramped", 2)
+	j := &Journal{}
+	sched.record(j)
+	require.Contains(t, j.Starts, "ramped[002]")
+}
+
+func TestStartSchedulerWaitsForGroup(t *testing.T) {
+	input := &api.RunInput{
+		TotalInstances: 3,
+		Groups: []*api.RunGroup{
+			{ID: "bootstrap", Instances: 2},
+			{ID: "joiners", Instances: 1, Start: api.StartPolicy{AfterGroup: "bootstrap", AfterState: "ready"}},
+		},
+	}
+
+	release := make(chan struct{})
+	var calls int
+	barrier := func(ctx context.Context, state string, target int) error {
+		calls++
+		require.Equal(t, "ready", state)
+		require.Equal(t, 2, target)
+		<-release
+		return nil
+	}
+
+	sched, err := newStartScheduler(input, barrier)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- sched.wait(context.Background(), rpc.Discard(), "joiners", 0) }()
+
+	select {
+	case <-done:
+		t.Fatal("group started before the state was signalled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+
+	// the barrier is only waited for once per group.
+	require.NoError(t, sched.wait(context.Background(), rpc.Discard(), "joiners", 0))
+	require.Equal(t, 1, calls)
+}
+
+func TestStartSchedulerBarrierFailure(t *testing.T) {
+	input := &api.RunInput{
+		TotalInstances: 2,
+		Groups: []*api.RunGroup{
+			{ID: "a", Instances: 1},
+			{ID: "b", Instances: 1, Start: api.StartPolicy{AfterGroup: "a", AfterState: "ready"}},
+		},
+	}
+
+	sched, err := newStartScheduler(input, func(context.Context, string, int) error {
+		return errors.New("sync service unreachable")
+	})
+	require.NoError(t, err)
+	require.Error(t, sched.wait(context.Background(), rpc.Discard(), "b", 0))
+}
+
+func TestStartOrder(t *testing.T) {
+	input := &api.RunInput{
+		Groups: []*api.RunGroup{
+			{ID: "clients", Start: api.StartPolicy{AfterGroup: "relays", AfterState: "ready"}},
+			{ID: "relays", Start: api.StartPolicy{AfterGroup: "bootstrap", AfterState: "ready"}},
+			{ID: "observers"},
+			{ID: "bootstrap"},
+		},
+	}
+
+	var ids []string
+	for _, g := range startOrder(input) {
+		ids = append(ids, g.ID)
+	}
+	require.Equal(t, []string{"observers", "bootstrap", "relays", "clients"}, ids)
+
+	// the groups of the input are left as they are.
+	require.Equal(t, "clients", input.Groups[0].ID)
+}
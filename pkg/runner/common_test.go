@@ -2,6 +2,8 @@ package runner
 
 import (
 	"testing"
+
+	"github.com/testground/testground/pkg/api"
 )
 
 func TestNextDataNetwork(t *testing.T) {
@@ -33,3 +35,63 @@ func TestNextDataNetwork(t *testing.T) {
 		}
 	}
 }
+
+func TestParseResources(t *testing.T) {
+	var tests = []struct {
+		resources api.Resources
+		limits    instanceLimits
+		hasError  bool
+	}{
+		{api.Resources{}, instanceLimits{}, false},
+		{api.Resources{CPU: "500m"}, instanceLimits{NanoCPUs: 5e8}, false},
+		{api.Resources{CPU: "2", Memory: "512Mi"}, instanceLimits{NanoCPUs: 2e9, Memory: 512 << 20}, false},
+		{api.Resources{Memory: "1G", CPUSet: "0-3,6"}, instanceLimits{Memory: 1e9, CPUSet: "0-3,6"}, false},
+		{api.Resources{CPU: "lots"}, instanceLimits{}, true},
+		{api.Resources{Memory: "-1Mi"}, instanceLimits{}, true},
+		{api.Resources{CPUSet: "0-"}, instanceLimits{}, true},
+	}
+
+	for _, tt := range tests {
+		limits, err := parseResources(tt.resources)
+		if err != nil {
+			if !tt.hasError {
+				t.Errorf("got error but didn't expect one: %s", err)
+			}
+			continue
+		}
+		if tt.hasError {
+			t.Errorf("expected an error for %+v", tt.resources)
+		}
+		if limits != tt.limits {
+			t.Errorf("got limits %+v, want %+v", limits, tt.limits)
+		}
+	}
+}
+
+func TestFreeDataNetwork(t *testing.T) {
+	var tests = []struct {
+		inUse  []string
+		subnet string
+	}{
+		{nil, "16.0.0.0/16"},
+		{[]string{"16.0.0.0/16", "16.1.0.0/16"}, "16.2.0.0/16"},
+		// subnets released by earlier runs are reused.
+		{[]string{"16.0.0.0/16", "16.2.0.0/16"}, "16.1.0.0/16"},
+		{[]string{"192.18.0.0/16"}, "16.0.0.0/16"},
+	}
+
+	for _, tt := range tests {
+		inUse := make(map[string]bool, len(tt.inUse))
+		for _, s := range tt.inUse {
+			inUse[s] = true
+		}
+		subnet, _, err := freeDataNetwork(inUse)
+		if err != nil {
+			t.Errorf("got error but didn't expect one: %s", err)
+			continue
+		}
+		if subnet.String() != tt.subnet {
+			t.Errorf("got subnet %s, want %s", subnet, tt.subnet)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/metrics"
+)
+
+// UsageFile is the name of the file, in the outputs directory of every
+// instance, holding the time series of its resource usage, one JSON sample
+// per line.
+const UsageFile = "usage.jsonl"
+
+// UsageMeasurement is the measurement resource usage samples are reported
+// under, tagged by run, group and instance.
+const UsageMeasurement = "testground.usage"
+
+// UsageSample is a measurement of the resources used by an instance.
+type UsageSample struct {
+	Time time.Time `json:"time"`
+
+	// CPU is the number of CPUs in use, averaged since the previous sample.
+	CPU float64 `json:"cpu"`
+
+	// Memory is the memory in use, in bytes.
+	Memory uint64 `json:"memory"`
+
+	// DiskRead, DiskWrite, NetRx and NetTx are cumulative byte counters.
+	// They are zero when the runner is unable to measure them.
+	DiskRead  uint64 `json:"disk_read"`
+	DiskWrite uint64 `json:"disk_write"`
+	NetRx     uint64 `json:"net_rx"`
+	NetTx     uint64 `json:"net_tx"`
+}
+
+// GroupUsage summarizes the resource usage of the instances of a group.
+// Peaks and averages are per instance; I/O counters are totals across the
+// instances of the group.
+type GroupUsage struct {
+	PeakCPU    float64 `json:"peak_cpu" mapstructure:"peak_cpu"`
+	AvgCPU     float64 `json:"avg_cpu" mapstructure:"avg_cpu"`
+	PeakMemory uint64  `json:"peak_memory" mapstructure:"peak_memory"`
+	AvgMemory  uint64  `json:"avg_memory" mapstructure:"avg_memory"`
+	DiskRead   uint64  `json:"disk_read" mapstructure:"disk_read"`
+	DiskWrite  uint64  `json:"disk_write" mapstructure:"disk_write"`
+	NetRx      uint64  `json:"net_rx" mapstructure:"net_rx"`
+	NetTx      uint64  `json:"net_tx" mapstructure:"net_tx"`
+	Samples    int     `json:"samples" mapstructure:"samples"`
+}
+
+func (g *GroupUsage) String() string {
+	return fmt.Sprintf("cpu: avg %.2f peak %.2f, memory: avg %s peak %s",
+		g.AvgCPU, g.PeakCPU, units.BytesSize(float64(g.AvgMemory)), units.BytesSize(float64(g.PeakMemory)))
+}
+
+// usageProbe measures the resource usage of an instance. A nil sample skips
+// the measurement, e.g. while the instance is starting; an error stops the
+// sampling of the instance, e.g. once it no longer exists.
+type usageProbe func(ctx context.Context) (*UsageSample, error)
+
+// usageRecorder periodically samples the resource usage of instances,
+// reports samples as metrics, and retains them to write the time series of
+// every instance, and summarize them by group, once the run is over.
+type usageRecorder struct {
+	interval time.Duration
+	tags     map[string]string
+	metrics  *metrics.Writer
+
+	wg     sync.WaitGroup
+	lk     sync.Mutex
+	series map[string]map[int][]UsageSample
+}
+
+// newUsageRecorder returns a recorder sampling every interval. Samples are
+// also written as metrics, unless they are disabled for the run or the
+// metrics database can't be reached.
+func newUsageRecorder(input *api.RunInput, interval time.Duration) *usageRecorder {
+	r := &usageRecorder{
+		interval: interval,
+		tags: map[string]string{
+			"run":  input.RunID,
+			"plan": input.TestPlan,
+			"case": input.TestCase,
+		},
+		series: make(map[string]map[int][]UsageSample),
+	}
+
+	if !input.DisableMetrics {
+		w, err := metrics.NewWriter(&input.EnvConfig)
+		if err != nil {
+			logging.S().Warnw("failed to create metrics writer; usage will not be reported as metrics", "err", err)
+		} else {
+			r.metrics = w
+		}
+	}
+	return r
+}
+
+// track samples an instance until ctx is done, or the probe fails.
+func (r *usageRecorder) track(ctx context.Context, groupID string, idx int, probe usageProbe) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			s, err := probe(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.S().Debugw("stopped sampling resource usage", "group", groupID, "instance", idx, "err", err)
+				}
+				return
+			}
+			if s != nil {
+				r.record(groupID, idx, s)
+			}
+		}
+	}()
+}
+
+func (r *usageRecorder) record(groupID string, idx int, s *UsageSample) {
+	r.lk.Lock()
+	if r.series[groupID] == nil {
+		r.series[groupID] = make(map[int][]UsageSample)
+	}
+	r.series[groupID][idx] = append(r.series[groupID][idx], *s)
+	w := r.metrics
+	r.lk.Unlock()
+
+	if w == nil {
+		return
+	}
+
+	tags := make(map[string]string, len(r.tags)+2)
+	for k, v := range r.tags {
+		tags[k] = v
+	}
+	tags["group_id"] = groupID
+	tags["instance"] = strconv.Itoa(idx)
+
+	fields := map[string]interface{}{
+		"cpu":        s.CPU,
+		"memory":     int64(s.Memory),
+		"disk_read":  int64(s.DiskRead),
+		"disk_write": int64(s.DiskWrite),
+		"net_rx":     int64(s.NetRx),
+		"net_tx":     int64(s.NetTx),
+	}
+	if err := w.Write(UsageMeasurement, tags, fields, s.Time); err != nil {
+		logging.S().Warnw("failed to write usage metrics; disabling", "err", err)
+		r.lk.Lock()
+		r.metrics = nil
+		r.lk.Unlock()
+	}
+}
+
+// wait waits for the sampling of all instances to stop; the contexts passed
+// to track must be done.
+func (r *usageRecorder) wait() {
+	r.wg.Wait()
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.metrics != nil {
+		_ = r.metrics.Close()
+		r.metrics = nil
+	}
+}
+
+// writeSeries writes the samples of an instance to w, one JSON document per
+// line.
+func (r *usageRecorder) writeSeries(groupID string, idx int, w io.Writer) error {
+	r.lk.Lock()
+	samples := r.series[groupID][idx]
+	r.lk.Unlock()
+
+	enc := json.NewEncoder(w)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFiles writes the time series of every sampled instance to the
+// UsageFile in the directory returned by dir.
+func (r *usageRecorder) writeFiles(dir func(groupID string, idx int) string) error {
+	r.lk.Lock()
+	instances := make(map[string][]int, len(r.series))
+	for g, series := range r.series {
+		for idx := range series {
+			instances[g] = append(instances[g], idx)
+		}
+	}
+	r.lk.Unlock()
+
+	for g, idxs := range instances {
+		for _, idx := range idxs {
+			f, err := os.Create(filepath.Join(dir(g, idx), UsageFile))
+			if err != nil {
+				return err
+			}
+			err = r.writeSeries(g, idx, f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// summarize adds the usage summary of every sampled group to result.
+func (r *usageRecorder) summarize(result *Result) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	for g, series := range r.series {
+		u := &GroupUsage{}
+		var cpu float64
+		var mem uint64
+		for _, samples := range series {
+			for _, s := range samples {
+				cpu += s.CPU
+				mem += s.Memory
+				if s.CPU > u.PeakCPU {
+					u.PeakCPU = s.CPU
+				}
+				if s.Memory > u.PeakMemory {
+					u.PeakMemory = s.Memory
+				}
+			}
+			u.Samples += len(samples)
+
+			// counters are cumulative; the last sample holds the totals.
+			if n := len(samples); n > 0 {
+				last := samples[n-1]
+				u.DiskRead += last.DiskRead
+				u.DiskWrite += last.DiskWrite
+				u.NetRx += last.NetRx
+				u.NetTx += last.NetTx
+			}
+		}
+		if u.Samples == 0 {
+			continue
+		}
+		u.AvgCPU = cpu / float64(u.Samples)
+		u.AvgMemory = mem / uint64(u.Samples)
+
+		if result.Usage == nil {
+			result.Usage = make(map[string]*GroupUsage)
+		}
+		result.Usage[g] = u
+	}
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+func TestUsageRecorderSummarize(t *testing.T) {
+	input := &api.RunInput{
+		RunID:          "run",
+		DisableMetrics: true,
+		Groups:         []*api.RunGroup{{ID: "a", Instances: 2}, {ID: "b", Instances: 1}},
+	}
+	r := newUsageRecorder(input, time.Second)
+
+	now := time.Now()
+	r.record("a", 0, &UsageSample{Time: now, CPU: 1, Memory: 100, NetRx: 10})
+	r.record("a", 0, &UsageSample{Time: now, CPU: 3, Memory: 300, NetRx: 30})
+	r.record("a", 1, &UsageSample{Time: now, CPU: 2, Memory: 200, NetRx: 5})
+	r.wait()
+
+	result := newResult(input)
+	r.summarize(result)
+
+	// groups without samples are not summarized.
+	require.Len(t, result.Usage, 1)
+
+	u := result.Usage["a"]
+	require.Equal(t, 3, u.Samples)
+	require.Equal(t, 3.0, u.PeakCPU)
+	require.Equal(t, 2.0, u.AvgCPU)
+	require.Equal(t, uint64(300), u.PeakMemory)
+	require.Equal(t, uint64(200), u.AvgMemory)
+	require.Equal(t, uint64(35), u.NetRx)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.writeSeries("a", 0, &buf))
+
+	var n int
+	for dec := json.NewDecoder(&buf); dec.More(); n++ {
+		var s UsageSample
+		require.NoError(t, dec.Decode(&s))
+	}
+	require.Equal(t, 2, n)
+}
+
+func TestDockerUsageSample(t *testing.T) {
+	var stats types.StatsJSON
+	stats.Read = time.Now()
+	stats.CPUStats.CPUUsage.TotalUsage = 3e9
+	stats.CPUStats.SystemUsage = 20e9
+	stats.CPUStats.OnlineCPUs = 4
+	stats.PreCPUStats.CPUUsage.TotalUsage = 1e9
+	stats.PreCPUStats.SystemUsage = 10e9
+	stats.MemoryStats.Usage = 1000
+	stats.MemoryStats.Stats = map[string]uint64{"inactive_file": 400}
+	stats.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Op: "Read", Value: 7},
+		{Op: "Write", Value: 11},
+	}
+	stats.Networks = map[string]types.NetworkStats{
+		"eth0": {RxBytes: 1, TxBytes: 2},
+		"eth1": {RxBytes: 3, TxBytes: 4},
+	}
+
+	s := dockerUsageSample(&stats)
+	require.InDelta(t, 0.8, s.CPU, 1e-9)
+	require.Equal(t, uint64(600), s.Memory)
+	require.Equal(t, uint64(7), s.DiskRead)
+	require.Equal(t, uint64(11), s.DiskWrite)
+	require.Equal(t, uint64(4), s.NetRx)
+	require.Equal(t, uint64(6), s.NetTx)
+}
+
+func TestPodMetricsSampler(t *testing.T) {
+	raw := `{"items": [
+		{"metadata": {"name": "tg-a-0"}, "timestamp": "2021-01-01T00:00:00Z", "containers": [{"name": "plan", "usage": {"cpu": "250m", "memory": "1Mi"}}, {"name": "sidecar", "usage": {"cpu": "250m", "memory": "1Mi"}}]},
+		{"metadata": {"name": "tg-a-1"}, "timestamp": "2021-01-01T00:00:00Z", "containers": [{"name": "plan", "usage": {"cpu": "1", "memory": "1Ki"}}]}
+	]}`
+	var res struct {
+		Items []podMetrics `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(raw), &res))
+
+	lists := 0
+	s := &podMetricsSampler{
+		interval: time.Hour,
+		list: func(ctx context.Context) ([]podMetrics, error) {
+			lists++
+			return res.Items, nil
+		},
+	}
+
+	ctx := context.Background()
+	a, b, c := s.probe("tg-a-0"), s.probe("tg-a-1"), s.probe("tg-a-2")
+
+	smpl, err := a(ctx)
+	require.NoError(t, err)
+	require.InDelta(t, 0.5, smpl.CPU, 1e-9)
+	require.Equal(t, uint64(2<<20), smpl.Memory)
+
+	smpl, err = b(ctx)
+	require.NoError(t, err)
+	require.InDelta(t, 1, smpl.CPU, 1e-9)
+	require.Equal(t, uint64(1024), smpl.Memory)
+
+	// pods without metrics yet, and metrics that haven't been refreshed, are
+	// skipped.
+	smpl, err = c(ctx)
+	require.NoError(t, err)
+	require.Nil(t, smpl)
+	smpl, err = a(ctx)
+	require.NoError(t, err)
+	require.Nil(t, smpl)
+
+	// all instances are sampled from a single list per interval.
+	require.Equal(t, 1, lists)
+}
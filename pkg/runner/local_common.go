@@ -15,7 +15,20 @@ import (
 	"github.com/docker/go-connections/nat"
 )
 
-func localCommonHealthcheck(ctx context.Context, hh *healthcheck.Helper, cli *client.Client, ow *rpc.OutputWriter, controlNetworkID string, workdir string) {
+// infraResources returns the resources of infrastructure containers. Rootless
+// engines can't raise the nofile limit above that of the user, which is kept.
+func infraResources(rootless bool) container.Resources {
+	if rootless {
+		return container.Resources{}
+	}
+	return container.Resources{
+		Ulimits: []*units.Ulimit{
+			{Name: "nofile", Hard: InfraMaxFilesUlimit, Soft: InfraMaxFilesUlimit},
+		},
+	}
+}
+
+func localCommonHealthcheck(ctx context.Context, hh *healthcheck.Helper, cli *client.Client, ow *rpc.OutputWriter, controlNetworkID string, workdir string, rootless bool) {
 	hh.Enlist("local-outputs-dir",
 		healthcheck.CheckDirectoryExists(workdir),
 		healthcheck.CreateDirectory(workdir),
@@ -58,11 +71,7 @@ func localCommonHealthcheck(ctx context.Context, hh *healthcheck.Helper, cli *cl
 				// NOTE: we expose this port for compatibility with older sdk versions.
 				PortBindings: exposed,
 				NetworkMode:  container.NetworkMode(controlNetworkID),
-				Resources: container.Resources{
-					Ulimits: []*units.Ulimit{
-						{Name: "nofile", Hard: InfraMaxFilesUlimit, Soft: InfraMaxFilesUlimit},
-					},
-				},
+				Resources:    infraResources(rootless),
 				Sysctls: map[string]string{
 					"net.core.somaxconn": "150000",
 				},
@@ -88,11 +97,7 @@ func localCommonHealthcheck(ctx context.Context, hh *healthcheck.Helper, cli *cl
 			HostConfig: &container.HostConfig{
 				PortBindings: exposed,
 				NetworkMode:  container.NetworkMode(controlNetworkID),
-				Resources: container.Resources{
-					Ulimits: []*units.Ulimit{
-						{Name: "nofile", Hard: InfraMaxFilesUlimit, Soft: InfraMaxFilesUlimit},
-					},
-				},
+				Resources:    infraResources(rootless),
 				Sysctls: map[string]string{
 					"net.core.somaxconn": "150000",
 				},
@@ -2,6 +2,8 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,7 +20,6 @@ import (
 	"github.com/testground/testground/pkg/logging"
 
 	"github.com/docker/go-connections/nat"
-	"github.com/docker/go-units"
 	"github.com/testground/sdk-go/ptypes"
 
 	"github.com/testground/sdk-go/runtime"
@@ -46,9 +48,13 @@ import (
 const InfraMaxFilesUlimit int64 = 1048576
 
 var (
-	_ api.Runner        = (*LocalDockerRunner)(nil)
-	_ api.Healthchecker = (*LocalDockerRunner)(nil)
-	_ api.Terminatable  = (*LocalDockerRunner)(nil)
+	_ api.Runner          = (*LocalDockerRunner)(nil)
+	_ api.Healthchecker   = (*LocalDockerRunner)(nil)
+	_ api.Terminatable    = (*LocalDockerRunner)(nil)
+	_ api.RunTerminatable = (*LocalDockerRunner)(nil)
+	_ api.Inspectable     = (*LocalDockerRunner)(nil)
+	_ api.Attachable      = (*LocalDockerRunner)(nil)
+	_ api.CapacityPlanner = (*LocalDockerRunner)(nil)
 )
 
 // LocalDockerRunnerConfig is the configuration object of this runner. Boolean
@@ -76,6 +82,22 @@ type LocalDockerRunnerConfig struct {
 	OutcomesCollectionTimeout time.Duration `toml:"outcomes_collection_timeout"`
 
 	AdditionalHosts []string `toml:"additional_hosts"`
+
+	// UsageSamplingInterval is the interval at which the resource usage of
+	// containers is sampled (default: 5s).
+	UsageSamplingInterval time.Duration `toml:"usage_sampling_interval"`
+	// DisableUsageSampling disables the sampling of resource usage (default:
+	// false).
+	DisableUsageSampling bool `toml:"disable_usage_sampling"`
+
+	// IsolatedSyncService provisions a redis and a sync service for the
+	// exclusive use of this run, instead of using the shared ones, and tears
+	// them down once the run is over (default: false).
+	IsolatedSyncService bool `toml:"isolated_sync_service"`
+
+	// SidecarReadyTimeout is the time the sidecar is given to manage all the
+	// instances once they started, before the run fails (default: 2m).
+	SidecarReadyTimeout time.Duration `toml:"sidecar_ready_timeout"`
 }
 
 type testContainerInstance struct {
@@ -92,6 +114,8 @@ var defaultConfig = LocalDockerRunnerConfig{
 	Background:                false,
 	Ulimits:                   []string{"nofile=1048576:1048576"},
 	OutcomesCollectionTimeout: time.Second * 45,
+	UsageSamplingInterval:     time.Second * 5,
+	SidecarReadyTimeout:       time.Minute * 2,
 }
 
 // LocalDockerRunner is a runner that manually stands up as many docker
@@ -107,10 +131,22 @@ var defaultConfig = LocalDockerRunnerConfig{
 type LocalDockerRunner struct {
 	lk sync.RWMutex
 
+	// engine is the docker-compatible engine containers are run in; empty
+	// for docker.
+	engine string
+
 	controlNetworkID string
 	outputsDir       string
 
 	syncClient *ss.DefaultClient
+
+	// infraLk guards runInfra, the infrastructure provisioned for the
+	// exclusive use of runs in progress, by run ID.
+	infraLk  sync.Mutex
+	runInfra map[string]*runInfra
+
+	// events tallies the sync events of the runs in progress.
+	events runEvents
 }
 
 func (r *LocalDockerRunner) Healthcheck(ctx context.Context, engine api.Engine, ow *rpc.OutputWriter, fix bool) (*api.HealthcheckReport, error) {
@@ -118,18 +154,21 @@ func (r *LocalDockerRunner) Healthcheck(ctx context.Context, engine api.Engine,
 	defer r.lk.Unlock()
 
 	// Create a docker client.
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := docker.NewClient(r.engine)
 	if err != nil {
 		return nil, err
 	}
 
-	r.outputsDir = filepath.Join(engine.EnvConfig().Dirs().Outputs(), "local_docker")
+	// unreachable engines are reported by the checks below.
+	rootless, _ := docker.IsRootless(ctx, cli)
+
+	r.outputsDir = filepath.Join(engine.EnvConfig().Dirs().Outputs(), strings.ReplaceAll(r.ID(), ":", "_"))
 	r.controlNetworkID = "testground-control"
 
 	hh := &healthcheck.Helper{}
 
 	// enlist healthchecks which are common between local:docker and local:exec
-	localCommonHealthcheck(ctx, hh, cli, ow, r.controlNetworkID, r.outputsDir)
+	localCommonHealthcheck(ctx, hh, cli, ow, r.controlNetworkID, r.outputsDir, rootless)
 
 	dockerSock := "/var/run/docker.sock"
 	if host := cli.DaemonHost(); strings.HasPrefix(host, "unix://") {
@@ -139,7 +178,7 @@ func (r *LocalDockerRunner) Healthcheck(ctx context.Context, engine api.Engine,
 	}
 
 	additionalHosts := "ADDITIONAL_HOSTS="
-	envHosts, hasHosts := engine.EnvConfig().Runners["local:docker"]["additional_hosts"].([]string)
+	envHosts, hasHosts := engine.EnvConfig().Runners[r.ID()]["additional_hosts"].([]string)
 	if hasHosts {
 		additionalHosts += strings.Join(envHosts, ",")
 	}
@@ -169,11 +208,7 @@ func (r *LocalDockerRunner) Healthcheck(ctx context.Context, engine api.Engine,
 				Source: dockerSock,
 				Target: "/var/run/docker.sock",
 			}},
-			Resources: container.Resources{
-				Ulimits: []*units.Ulimit{
-					{Name: "nofile", Hard: InfraMaxFilesUlimit, Soft: InfraMaxFilesUlimit},
-				},
-			},
+			Resources: infraResources(rootless),
 			RestartPolicy: container.RestartPolicy{
 				Name: "unless-stopped",
 			},
@@ -199,60 +234,9 @@ func (r *LocalDockerRunner) setupSyncClient() error {
 		return nil
 	}
 
-	err := os.Setenv(ss.EnvServiceHost, "127.0.0.1")
-	if err != nil {
-		return err
-	}
-
-	r.syncClient, err = ss.NewGenericClient(context.Background(), logging.S())
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// collectOutcomes listens to the sync service and collects the outcome for every test instance.
-// It stops when all instances have submitted a result or the context was canceled.
-func (r *LocalDockerRunner) collectOutcomes(ctx context.Context, result *Result, tpl *runtime.RunParams) (chan bool, error) {
-	eventsCh, err := r.syncClient.SubscribeEvents(ctx, tpl)
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO: eventually we'll keep a trace of each test instance status.
-	// Right now, if a container sends multiple events, it will mess up the outcomes.
-	// We have to pass its group id to the container, so that it can send us back messages
-	// with its own id.
-	expectingOutcomes := result.countTotalInstances()
-	done := make(chan bool)
-
-	go func() {
-		running := true
-		for running && expectingOutcomes > 0 {
-			select {
-			case <-ctx.Done():
-				running = false
-			case e := <-eventsCh:
-				if e.SuccessEvent != nil {
-					result.addOutcome(e.SuccessEvent.TestGroupID, task.OutcomeSuccess)
-					expectingOutcomes -= 1
-				} else if e.FailureEvent != nil {
-					result.addOutcome(e.FailureEvent.TestGroupID, task.OutcomeFailure)
-					expectingOutcomes -= 1
-				} else if e.CrashEvent != nil {
-					result.addOutcome(e.CrashEvent.TestGroupID, task.OutcomeFailure)
-					expectingOutcomes -= 1
-				}
-				// else: skip
-			}
-		}
-
-		result.updateOutcome()
-		done <- true
-	}()
-
-	return done, nil
+	var err error
+	r.syncClient, err = newSyncClient(context.Background(), "127.0.0.1", "5050")
+	return err
 }
 
 func (r *LocalDockerRunner) prepareOutputDirectory(instance_id int, runenv *runtime.RunParams) (string, error) {
@@ -278,7 +262,7 @@ func (r *LocalDockerRunner) prepareTemporaryDirectory(instance_id int, runenv *r
 }
 
 func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) (runoutput *api.RunOutput, err error) {
-	log := ow.With("runner", "local:docker", "run_id", input.RunID)
+	log := ow.With("runner", r.ID(), "run_id", input.RunID)
 
 	result := newResult(input)
 	runoutput = &api.RunOutput{
@@ -303,12 +287,29 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 		}
 	}()
 
-	err = r.setupSyncClient()
-	if err != nil {
-		log.Error(err)
+	r.events.begin(input.RunID)
+	defer r.events.end(input.RunID)
+
+	// Prepare the Runner Configuration.
+	cfg := defaultConfig
+	if r.engine == docker.EnginePodman {
+		// podman mostly runs rootless, where the nofile limit can't be raised
+		// above that of the user.
+		cfg.Ulimits = nil
+	}
+	if err = mergo.Merge(&cfg, input.RunnerConfig, mergo.WithOverride); err != nil {
+		err = fmt.Errorf("error while merging configurations: %w", err)
 		return
 	}
 
+	if !cfg.IsolatedSyncService {
+		err = r.setupSyncClient()
+		if err != nil {
+			log.Error(err)
+			return
+		}
+	}
+
 	// Grab a read lock. This will allow many runs to run simultaneously, but
 	// they will be exclusive of state-altering healthchecks.
 	// TODO: I'm not sure this is true anymore.
@@ -318,11 +319,31 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 	// ## Prepare Execution Context
 
 	// Create a docker client.
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := docker.NewClient(r.engine)
 	if err != nil {
 		return
 	}
 
+	// Provision the sync service of this run, if isolated.
+	var (
+		syncClient = r.syncClient
+		infra      *runInfra
+	)
+	if cfg.IsolatedSyncService {
+		infra, err = r.provisionRunInfra(ctx, cli, ow, input)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		syncClient = infra.syncClient
+
+		defer func() {
+			if err := r.teardownRunInfra(cli, log, infra); err != nil {
+				log.Errorw("failed to tear down run infrastructure", "err", err)
+			}
+		}()
+	}
+
 	// Create a data network.
 	dataNetworkID, subnet, err := newDataNetwork(ctx, cli, ow, input, "default")
 	if err != nil {
@@ -343,13 +364,6 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 		TestSubnet:         &ptypes.IPNet{IPNet: *subnet},
 	}
 
-	// Prepare the Runner Configuration.
-	cfg := defaultConfig
-	if err = mergo.Merge(&cfg, input.RunnerConfig, mergo.WithOverride); err != nil {
-		err = fmt.Errorf("error while merging configurations: %w", err)
-		return
-	}
-
 	// Prepare the ports mapping.
 	ports := make(nat.PortSet)
 	for _, p := range cfg.ExposedPorts {
@@ -359,7 +373,11 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 	// Prepare environment variables.
 	sharedEnv := make([]string, 0, 3)
 	sharedEnv = append(sharedEnv, "INFLUXDB_URL=http://testground-influxdb:8086")
-	sharedEnv = append(sharedEnv, "REDIS_HOST=testground-redis")
+	if infra != nil {
+		sharedEnv = append(sharedEnv, infra.env()...)
+	} else {
+		sharedEnv = append(sharedEnv, "REDIS_HOST=testground-redis")
+	}
 	// Inject exposed ports.
 	sharedEnv = append(sharedEnv, conv.ToOptionsSlice(cfg.ExposedPorts.ToEnvVars())...)
 	// Set the log level if provided in cfg.
@@ -381,7 +399,10 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 	}()
 
 	for _, g := range input.Groups {
-		reviewResources(g, ow)
+		limits, err := parseResources(g.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply resources of group %s: %w", g.ID, err)
+		}
 
 		runenv := template
 		runenv.TestGroupInstanceCount = g.Instances
@@ -423,8 +444,12 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 					"testground.testcase": runenv.TestCase,
 					"testground.run_id":   runenv.TestRun,
 					"testground.group_id": runenv.TestGroupID,
+					groupIndexLabel:       strconv.Itoa(i),
 				},
 			}
+			if infra != nil {
+				ccfg.Labels[syncServiceLabel] = infra.syncHost
+			}
 
 			hcfg := &container.HostConfig{
 				NetworkMode:     container.NetworkMode("testground-control"),
@@ -438,12 +463,17 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 					Source: tmpdir,
 					Target: runenv.TestTempPath,
 				}},
+				Resources: container.Resources{
+					NanoCPUs:   limits.NanoCPUs,
+					Memory:     limits.Memory,
+					CpusetCpus: limits.CPUSet,
+				},
 			}
 
 			if len(cfg.Ulimits) > 0 {
 				ulimits, err := conv.ToUlimits(cfg.Ulimits)
 				if err == nil {
-					hcfg.Resources = container.Resources{Ulimits: ulimits}
+					hcfg.Resources.Ulimits = ulimits
 				} else {
 					ow.Warnf("invalid ulimit will be ignored %v", err)
 				}
@@ -477,11 +507,11 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 			for _, c := range containers {
 				ids = append(ids, c.containerID)
 			}
-			if err := docker.DeleteContainers(cli, log, ids); err != nil {
-				log.Errorw("failed to delete containers", "err", err)
-			}
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
+			if err := docker.DeleteContainers(ctx, cli, log, ids); err != nil {
+				log.Errorw("failed to delete containers", "err", err)
+			}
 			if err := cli.NetworkRemove(ctx, dataNetworkID); err != nil {
 				log.Errorw("removing network", "network", dataNetworkID, "error", err)
 			}
@@ -506,13 +536,64 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 	}()
 
 	// First we collect every container outcomes.
-	outcomesCollectIsCompleteCh, err := r.collectOutcomes(runCtx, result, &template)
+	outcomesCollectIsCompleteCh, err := collectOutcomes(runCtx, syncClient, result, &template, &r.events)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	// The run fails fast if the sidecar can't manage its instances.
+	sidecarErr := make(chan error, 1)
+	failSidecar := func(err error) {
+		log.Errorw("sidecar failed", "err", err)
+		select {
+		case sidecarErr <- err:
+		default:
+		}
+		cancelRun()
+	}
+	defer func() {
+		select {
+		case err = <-sidecarErr:
+		default:
+		}
+	}()
+
+	watch, err := watchSidecar(runCtx, syncClient, &template, failSidecar)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	// sample the resource usage of containers until the run is over.
+	usageCtx, stopUsage := context.WithCancel(runCtx)
+	defer stopUsage()
+
+	var usage *usageRecorder
+	if !cfg.DisableUsageSampling {
+		usage = newUsageRecorder(input, cfg.UsageSamplingInterval)
+		defer func() {
+			stopUsage()
+			usage.wait()
+			err := usage.writeFiles(func(groupID string, idx int) string {
+				return filepath.Join(r.outputsDir, input.TestPlan, input.RunID, groupID, strconv.Itoa(idx))
+			})
+			if err != nil {
+				log.Warnw("failed to write resource usage", "err", err)
+			}
+			usage.summarize(result)
+		}()
+	}
+
+	// Second we start the containers, as paced by the start policies of
+	// their groups.
+	sched, err := newStartScheduler(input, syncBarrier(syncClient, &template))
 	if err != nil {
 		log.Error(err)
 		return
 	}
+	defer sched.record(result.Journal)
 
-	// Second we start the containers
 	log.Infow("starting containers", "count", len(containers))
 	var (
 		startGroup, startGroupCtx = errgroup.WithContext(runCtx)
@@ -524,6 +605,10 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 	for _, c := range containers {
 		c := c
 		f := func() error {
+			if err := sched.wait(startGroupCtx, log, c.groupID, c.groupIdx); err != nil {
+				return err
+			}
+
 			ratelimit <- struct{}{}
 			defer func() { <-ratelimit }()
 
@@ -532,6 +617,10 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 			err := cli.ContainerStart(startGroupCtx, c.containerID, types.ContainerStartOptions{})
 			if err == nil {
 				log.Debugw("started container", "id", c.containerID, "group", c.groupID, "group_index", c.groupIdx)
+				sched.started(c.groupID, c.groupIdx)
+				if usage != nil {
+					usage.track(usageCtx, c.groupID, c.groupIdx, dockerUsageProbe(cli, c.containerID))
+				}
 				select {
 				case <-startGroupCtx.Done():
 				default:
@@ -621,6 +710,12 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 		return
 	}
 
+	go func() {
+		if err := watch.awaitManaged(runCtx, len(containers), cfg.SidecarReadyTimeout); err != nil && runCtx.Err() == nil {
+			failSidecar(err)
+		}
+	}()
+
 	// Finally, we're going to follow our containers until they are done
 
 	for _, c := range containers {
@@ -639,6 +734,8 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 				return nil
 			case status := <-statusCh:
 				log.Infow("container exited", "id", c.containerID, "group", c.groupID, "group_index", c.groupIdx, "status", status.StatusCode)
+				// the hostname of containers is the first 12 characters of their ID.
+				watch.exited(c.containerID[:12])
 				return nil
 			case <-runGroupCtx.Done(): // race with the group
 				log.Infow("container group exited", "err", runGroupCtx.Err())
@@ -692,21 +789,29 @@ func (r *LocalDockerRunner) Run(ctx context.Context, input *api.RunInput, ow *rp
 	return
 }
 
+// dataNetworksLk serializes the allocation of data subnets, so that
+// concurrent runs never pick the same one.
+var dataNetworksLk sync.Mutex
+
 func newDataNetwork(ctx context.Context, cli *client.Client, rw *rpc.OutputWriter, env *api.RunInput, name string) (id string, subnet *net.IPNet, err error) {
-	// Find a free network.
-	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{
-		Filters: filters.NewArgs(
-			filters.Arg(
-				"label",
-				"testground.name=default",
-			),
-		),
-	})
+	dataNetworksLk.Lock()
+	defer dataNetworksLk.Unlock()
+
+	// Find a free subnet, skipping those of networks that have been left
+	// behind by previous runs, or that belong to runs in progress.
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{})
 	if err != nil {
 		return "", nil, err
 	}
 
-	subnet, gateway, err := nextDataNetwork(len(networks))
+	inUse := make(map[string]bool)
+	for _, n := range networks {
+		for _, c := range n.IPAM.Config {
+			inUse[c.Subnet] = true
+		}
+	}
+
+	subnet, gateway, err := freeDataNetwork(inUse)
 	if err != nil {
 		return "", nil, err
 	}
@@ -739,6 +844,69 @@ func (r *LocalDockerRunner) CollectOutputs(ctx context.Context, input *api.Colle
 	return gzipRunOutputs(ctx, dir, input, ow)
 }
 
+// dockerUsageProbe samples the resource usage of a container through the
+// docker stats API.
+func dockerUsageProbe(cli *client.Client, containerID string) usageProbe {
+	return func(ctx context.Context) (*UsageSample, error) {
+		res, err := cli.ContainerStats(ctx, containerID, false)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		var stats types.StatsJSON
+		if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+			return nil, err
+		}
+		// stopped containers report empty stats.
+		if stats.Read.IsZero() {
+			return nil, errors.New("container is not running")
+		}
+		return dockerUsageSample(&stats), nil
+	}
+}
+
+// dockerUsageSample converts container stats into a usage sample, following
+// the computations of the docker stats command.
+func dockerUsageSample(stats *types.StatsJSON) *UsageSample {
+	s := &UsageSample{Time: stats.Read}
+
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		s.CPU = cpuDelta / systemDelta * cpus
+	}
+
+	// page cache is not accounted as used memory; it's reported as
+	// inactive_file on cgroup v2, and as cache on cgroup v1.
+	s.Memory = stats.MemoryStats.Usage
+	cache, ok := stats.MemoryStats.Stats["inactive_file"]
+	if !ok {
+		cache = stats.MemoryStats.Stats["cache"]
+	}
+	if cache < s.Memory {
+		s.Memory -= cache
+	}
+
+	for _, e := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			s.DiskRead += e.Value
+		case "write":
+			s.DiskWrite += e.Value
+		}
+	}
+	for _, n := range stats.Networks {
+		s.NetRx += n.RxBytes
+		s.NetTx += n.TxBytes
+	}
+	return s
+}
+
 // attachContainerToNetwork attaches the provided container to the specified
 // network.
 func attachContainerToNetwork(ctx context.Context, cli *client.Client, containerID string, networkID string) error {
@@ -754,7 +922,10 @@ func detachContainerFromNetwork(ctx context.Context, cli *client.Client, contain
 	return cli.NetworkDisconnect(ctx, networkID, containerID, true)
 }
 
-func (*LocalDockerRunner) ID() string {
+func (r *LocalDockerRunner) ID() string {
+	if r.engine == docker.EnginePodman {
+		return "local:podman"
+	}
 	return "local:docker"
 }
 
@@ -766,21 +937,248 @@ func (*LocalDockerRunner) CompatibleBuilders() []string {
 	return []string{"docker:go", "docker:node", "docker:generic"}
 }
 
+// InspectRun reports the state of the containers of a run in progress.
+func (r *LocalDockerRunner) InspectRun(ctx context.Context, runID string, ow *rpc.OutputWriter) (*api.RunInspection, error) {
+	events, err := r.events.snapshot(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := docker.NewClient(r.engine)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", "testground.run_id="+runID),
+			filters.Arg("label", "testground.purpose=plan"),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers of run %s: %w", runID, err)
+	}
+
+	res := &api.RunInspection{Events: events}
+	for _, c := range containers {
+		ci, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %w", c.ID, err)
+		}
+
+		idx, _ := strconv.Atoi(c.Labels[groupIndexLabel])
+		st := api.InstanceStatus{
+			GroupID:  c.Labels["testground.group_id"],
+			Index:    idx,
+			ID:       c.ID[:12],
+			ExitCode: ci.State.ExitCode,
+			Restarts: ci.RestartCount,
+		}
+		switch {
+		case ci.State.Running || ci.State.Restarting || ci.State.Paused:
+			st.State = api.InstanceStateRunning
+		case ci.State.Status == "created":
+			st.State = api.InstanceStateCreating
+		default:
+			st.State = api.InstanceStateExited
+		}
+		if ci.NetworkSettings != nil {
+			for port, bindings := range ci.NetworkSettings.Ports {
+				for _, b := range bindings {
+					st.Ports = append(st.Ports, fmt.Sprintf("%s -> %s:%s", port, b.HostIP, b.HostPort))
+				}
+			}
+			sort.Strings(st.Ports)
+		}
+		res.Instances = append(res.Instances, st)
+	}
+
+	sortInstances(res.Instances)
+	return res, nil
+}
+
+// PlanCapacity compares the resources requested by the instances of a run
+// with the CPUs and memory of the host of the engine.
+func (r *LocalDockerRunner) PlanCapacity(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) ([]*api.CapacityReport, error) {
+	cli, err := docker.NewClient(r.engine)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of the engine: %w", err)
+	}
+
+	rep, err := newCapacityReport(info.Name, input.Groups, api.Resources{}, float64(info.NCPU), info.MemTotal)
+	if err != nil {
+		return nil, err
+	}
+	return []*api.CapacityReport{rep}, nil
+}
+
+// Attach executes a command in the container of an instance of a run in
+// progress, through the docker exec API.
+func (r *LocalDockerRunner) Attach(ctx context.Context, runID string, opts *api.AttachOptions, ow *rpc.OutputWriter) (int, error) {
+	cli, err := docker.NewClient(r.engine)
+	if err != nil {
+		return 0, err
+	}
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", "testground.run_id="+runID),
+			filters.Arg("label", "testground.purpose=plan"),
+			filters.Arg("label", "testground.group_id="+opts.GroupID),
+			filters.Arg("label", groupIndexLabel+"="+strconv.Itoa(opts.Instance)),
+		),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list containers of run %s: %w", runID, err)
+	}
+	if len(containers) == 0 {
+		return 0, fmt.Errorf("instance %d of group %s of run %s is not running", opts.Instance, opts.GroupID, runID)
+	}
+
+	cmd := opts.Command
+	if len(cmd) == 0 {
+		cmd = defaultAttachCommand
+	}
+
+	exec, err := cli.ContainerExecCreate(ctx, containers[0].ID, types.ExecConfig{
+		Tty:          opts.TTY,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	hr, err := cli.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{Tty: opts.TTY})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer hr.Close()
+
+	// the hijacked connection outlives ctx otherwise.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			hr.Close()
+		case <-done:
+		}
+	}()
+
+	if opts.TTY && opts.Width > 0 && opts.Height > 0 {
+		err := cli.ContainerExecResize(ctx, exec.ID, types.ResizeOptions{Width: opts.Width, Height: opts.Height})
+		if err != nil {
+			ow.Warnw("failed to resize terminal", "err", err)
+		}
+	}
+
+	if opts.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(hr.Conn, opts.Stdin)
+			_ = hr.CloseWrite()
+		}()
+	}
+
+	if opts.TTY {
+		_, err = io.Copy(opts.Stdout, hr.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(opts.Stdout, opts.Stderr, hr.Reader)
+	}
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to relay exec streams: %w", err)
+	}
+
+	// the exec may still be reported as running for a moment after its
+	// streams are closed.
+	for i := 0; ; i++ {
+		ins, err := cli.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect exec: %w", err)
+		}
+		if !ins.Running || i == 10 {
+			return ins.ExitCode, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TerminateRun deletes the containers and networks of a run, including the
+// infrastructure provisioned for it if its sync service is isolated. Its
+// outputs are retained.
+func (r *LocalDockerRunner) TerminateRun(ctx context.Context, runID string, ow *rpc.OutputWriter) error {
+	ow.Infow("terminate run requested", "runner", r.ID(), "run_id", runID)
+
+	cli, err := docker.NewClient(r.engine)
+	if err != nil {
+		return err
+	}
+
+	r.infraLk.Lock()
+	infra := r.runInfra[runID]
+	delete(r.runInfra, runID)
+	r.infraLk.Unlock()
+
+	if infra != nil && infra.syncClient != nil {
+		_ = infra.syncClient.Close()
+	}
+
+	byRun := filters.NewArgs(filters.Arg("label", "testground.run_id="+runID))
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: byRun})
+	if err != nil {
+		return fmt.Errorf("failed to list containers of run %s: %w", runID, err)
+	}
+
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	if err := docker.DeleteContainers(ctx, cli, ow, ids); err != nil {
+		return fmt.Errorf("failed to delete containers of run %s: %w", runID, err)
+	}
+
+	// networks can only be removed once no container is attached to them.
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{Filters: byRun})
+	if err != nil {
+		return fmt.Errorf("failed to list networks of run %s: %w", runID, err)
+	}
+	for _, n := range networks {
+		ow.Infow("removing network", "network", n.Name)
+		if err := cli.NetworkRemove(ctx, n.ID); err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("failed to remove network %s: %w", n.Name, err)
+		}
+	}
+	return nil
+}
+
 // This method deletes the testground containers.
 // It does *not* delete any downloaded images or networks.
 // I'll leave a friendly message for how to do a more complete cleanup.
-func (*LocalDockerRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
-	ow.Info("terminate local:docker requested")
+func (r *LocalDockerRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
+	ow.Infof("terminate %s requested", r.ID())
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := docker.NewClient(r.engine)
 	if err != nil {
 		return err
 	}
 
-	// Build two separate queries: one for infrastructure containers, another
-	// for test plan containers. The former, we match by container name. The
-	// latter, we match by the `testground.purpose` label, which we apply to all
-	// plan containers managed by testground label.
+	// Build separate queries: one for infrastructure containers, others for
+	// test plan containers and for the infrastructure of isolated runs. The
+	// former, we match by container name. The latter, we match by the
+	// `testground.purpose` label, which we apply to all plan and run
+	// infrastructure containers managed by testground.
 
 	// Build query for runner infrastructure containers.
 	infraOpts := types.ContainerListOptions{}
@@ -796,6 +1194,11 @@ func (*LocalDockerRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter
 	planOpts.Filters = filters.NewArgs()
 	planOpts.Filters.Add("label", "testground.purpose=plan")
 
+	// Build query for the infrastructure of isolated runs.
+	runInfraOpts := types.ContainerListOptions{All: true}
+	runInfraOpts.Filters = filters.NewArgs()
+	runInfraOpts.Filters.Add("label", "testground.purpose="+infraPurpose)
+
 	infracontainers, err := cli.ContainerList(ctx, infraOpts)
 	if err != nil {
 		return fmt.Errorf("failed to list infrastructure containers: %w", err)
@@ -804,16 +1207,23 @@ func (*LocalDockerRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter
 	if err != nil {
 		return fmt.Errorf("failed to list test plan containers: %w", err)
 	}
+	runinfracontainers, err := cli.ContainerList(ctx, runInfraOpts)
+	if err != nil {
+		return fmt.Errorf("failed to list run infrastructure containers: %w", err)
+	}
 
-	containers := make([]string, 0, len(infracontainers)+len(plancontainers))
+	containers := make([]string, 0, len(infracontainers)+len(plancontainers)+len(runinfracontainers))
 	for _, container := range infracontainers {
 		containers = append(containers, container.ID)
 	}
 	for _, container := range plancontainers {
 		containers = append(containers, container.ID)
 	}
+	for _, container := range runinfracontainers {
+		containers = append(containers, container.ID)
+	}
 
-	err = docker.DeleteContainers(cli, ow, containers)
+	err = docker.DeleteContainers(ctx, cli, ow, containers)
 	if err != nil {
 		return fmt.Errorf("failed to list testground containers: %w", err)
 	}
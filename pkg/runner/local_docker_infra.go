@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	ss "github.com/testground/sdk-go/sync"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/docker"
+	"github.com/testground/testground/pkg/rpc"
+)
+
+const (
+	// infraPurpose is the value of the testground.purpose label of the
+	// infrastructure containers provisioned for a single run.
+	infraPurpose = "infra"
+
+	// syncServiceLabel is the label of plan containers carrying the host of
+	// the sync service of their run, when it's isolated. The sidecar uses it
+	// to reach that sync service instead of the shared one; it must match
+	// sidecar.SyncServiceLabel.
+	syncServiceLabel = "testground.sync_service"
+
+	// syncServiceDialTimeout bounds the wait for a freshly started sync
+	// service to accept connections.
+	syncServiceDialTimeout = 30 * time.Second
+)
+
+// runInfra is the infrastructure provisioned for the exclusive use of a run,
+// i.e. a redis and a sync service on the control network. Containers are
+// labelled with the run ID, so that they can be found even if the runner
+// loses track of them.
+type runInfra struct {
+	runID      string
+	redisHost  string
+	syncHost   string
+	containers []string
+	syncClient *ss.DefaultClient
+}
+
+// env returns the environment variables pointing plan containers to this
+// infrastructure.
+func (i *runInfra) env() []string {
+	return []string{
+		"REDIS_HOST=" + i.redisHost,
+		ss.EnvServiceHost + "=" + i.syncHost,
+	}
+}
+
+// provisionRunInfra starts a redis and a sync service dedicated to the run,
+// and connects a sync client to the latter. The infrastructure is tracked by
+// the runner until it's torn down.
+func (r *LocalDockerRunner) provisionRunInfra(ctx context.Context, cli *client.Client, ow *rpc.OutputWriter, input *api.RunInput) (infra *runInfra, err error) {
+	infra = &runInfra{
+		runID:     input.RunID,
+		redisHost: "tg-redis-" + input.RunID,
+		syncHost:  "tg-sync-service-" + input.RunID,
+	}
+
+	defer func() {
+		if err != nil {
+			if terr := r.teardownRunInfra(cli, ow, infra); terr != nil {
+				ow.Warnw("failed to tear down run infrastructure", "run_id", input.RunID, "err", terr)
+			}
+		}
+	}()
+
+	labels := map[string]string{
+		"testground.purpose": infraPurpose,
+		"testground.plan":    input.TestPlan,
+		"testground.run_id":  input.RunID,
+	}
+	rootless, err := docker.IsRootless(ctx, cli)
+	if err != nil {
+		return infra, err
+	}
+	resources := infraResources(rootless)
+	sysctls := map[string]string{
+		"net.core.somaxconn": "150000",
+	}
+
+	redis, _, err := docker.EnsureContainerStarted(ctx, ow, cli, &docker.EnsureContainerOpts{
+		ContainerName: infra.redisHost,
+		ContainerConfig: &container.Config{
+			Image:  "library/redis",
+			Cmd:    []string{"--save", "", "--appendonly", "no", "--maxclients", "120000", "--stop-writes-on-bgsave-error", "no"},
+			Labels: labels,
+		},
+		HostConfig: &container.HostConfig{
+			NetworkMode: container.NetworkMode("testground-control"),
+			Resources:   resources,
+			Sysctls:     sysctls,
+		},
+	})
+	if err != nil {
+		return infra, fmt.Errorf("failed to start redis: %w", err)
+	}
+	infra.containers = append(infra.containers, redis.ID)
+
+	// the sync service is published on an ephemeral port of the loopback
+	// interface, for the runner to collect outcomes.
+	port := nat.Port("5050/tcp")
+	syncsvc, _, err := docker.EnsureContainerStarted(ctx, ow, cli, &docker.EnsureContainerOpts{
+		ContainerName: infra.syncHost,
+		ContainerConfig: &container.Config{
+			Image:        "iptestground/sync-service:edge",
+			Entrypoint:   []string{"/service"},
+			Env:          []string{"REDIS_HOST=" + infra.redisHost},
+			ExposedPorts: nat.PortSet{port: struct{}{}},
+			Labels:       labels,
+		},
+		HostConfig: &container.HostConfig{
+			PortBindings: nat.PortMap{port: []nat.PortBinding{{HostIP: "127.0.0.1"}}},
+			NetworkMode:  container.NetworkMode("testground-control"),
+			Resources:    resources,
+			Sysctls:      sysctls,
+		},
+	})
+	if err != nil {
+		return infra, fmt.Errorf("failed to start sync service: %w", err)
+	}
+	infra.containers = append(infra.containers, syncsvc.ID)
+
+	bindings := syncsvc.NetworkSettings.Ports[port]
+	if len(bindings) == 0 {
+		return infra, fmt.Errorf("sync service %s has no published port", infra.syncHost)
+	}
+
+	// the sync service takes a moment to listen after its container starts.
+	dialCtx, cancel := context.WithTimeout(ctx, syncServiceDialTimeout)
+	defer cancel()
+	for {
+		infra.syncClient, err = newSyncClient(context.Background(), "127.0.0.1", bindings[0].HostPort)
+		if err == nil {
+			break
+		}
+		select {
+		case <-dialCtx.Done():
+			return infra, fmt.Errorf("failed to connect to sync service: %w", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	r.infraLk.Lock()
+	if r.runInfra == nil {
+		r.runInfra = make(map[string]*runInfra)
+	}
+	r.runInfra[input.RunID] = infra
+	r.infraLk.Unlock()
+
+	ow.Infow("provisioned isolated sync service", "run_id", input.RunID, "host", infra.syncHost)
+	return infra, nil
+}
+
+// teardownRunInfra closes the sync client, and removes the containers of the
+// infrastructure of a run.
+func (r *LocalDockerRunner) teardownRunInfra(cli *client.Client, ow *rpc.OutputWriter, infra *runInfra) error {
+	r.infraLk.Lock()
+	delete(r.runInfra, infra.runID)
+	r.infraLk.Unlock()
+
+	if infra.syncClient != nil {
+		_ = infra.syncClient.Close()
+	}
+	if len(infra.containers) == 0 {
+		return nil
+	}
+
+	// the context of the run is usually over by now.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return docker.DeleteContainers(ctx, cli, ow, infra.containers)
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	goruntime "runtime"
 	"strconv"
 	"sync"
 	"time"
@@ -22,10 +23,14 @@ import (
 	"github.com/testground/testground/pkg/docker"
 	"github.com/testground/testground/pkg/healthcheck"
 	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/task"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/imdario/mergo"
+
+	ss "github.com/testground/sdk-go/sync"
 )
 
 var (
@@ -33,18 +38,178 @@ var (
 )
 
 var (
-	_ api.Runner        = (*LocalExecutableRunner)(nil)
-	_ api.Healthchecker = (*LocalExecutableRunner)(nil)
+	_ api.Runner          = (*LocalExecutableRunner)(nil)
+	_ api.Healthchecker   = (*LocalExecutableRunner)(nil)
+	_ api.Terminatable    = (*LocalExecutableRunner)(nil)
+	_ api.RunTerminatable = (*LocalExecutableRunner)(nil)
+	_ api.Inspectable     = (*LocalExecutableRunner)(nil)
+	_ api.CapacityPlanner = (*LocalExecutableRunner)(nil)
 )
 
 type LocalExecutableRunner struct {
 	lk sync.RWMutex
 
 	outputsDir string
+
+	syncClient *ss.DefaultClient
+
+	// runsLk guards runs, the runs in progress by run ID.
+	runsLk sync.Mutex
+	runs   map[string]*execRun
+
+	// events tallies the sync events of the runs in progress.
+	events runEvents
+}
+
+// execRun tracks the instances of a run in progress, so that it can be
+// inspected and terminated.
+type execRun struct {
+	cancel context.CancelFunc
+
+	lk        sync.Mutex
+	instances []*execInstance
+}
+
+func (er *execRun) add(in *execInstance) {
+	er.lk.Lock()
+	er.instances = append(er.instances, in)
+	er.lk.Unlock()
+}
+
+// reaped records the exit code of an instance that has been waited for.
+func (er *execRun) reaped(in *execInstance, code int) {
+	er.lk.Lock()
+	in.reaped, in.exitCode = true, code
+	er.lk.Unlock()
+}
+
+// kill cancels the run, and kills its processes right away rather than
+// waiting for the run to notice.
+func (er *execRun) kill() {
+	er.cancel()
+
+	er.lk.Lock()
+	defer er.lk.Unlock()
+	for _, in := range er.instances {
+		_ = in.cmd.Process.Kill()
+	}
+}
+
+// inspect reports the state of the instances of the run. Instances are
+// exited once their process has been reaped, or has become a zombie.
+func (er *execRun) inspect() []api.InstanceStatus {
+	er.lk.Lock()
+	defer er.lk.Unlock()
+
+	res := make([]api.InstanceStatus, 0, len(er.instances))
+	for _, in := range er.instances {
+		pid := in.cmd.Process.Pid
+		st := api.InstanceStatus{
+			GroupID: in.groupID,
+			Index:   in.idx,
+			ID:      strconv.Itoa(pid),
+			State:   api.InstanceStateRunning,
+		}
+		switch {
+		case in.reaped:
+			st.State, st.ExitCode = api.InstanceStateExited, in.exitCode
+		case procExited(pid):
+			st.State = api.InstanceStateExited
+		}
+		res = append(res, st)
+	}
+	sortInstances(res)
+	return res
 }
 
 // LocalExecutableRunnerCfg is the configuration struct for this runner.
-type LocalExecutableRunnerCfg struct{}
+type LocalExecutableRunnerCfg struct {
+	// OutcomesCollectionTimeout is the time we wait for the sync service to
+	// send us the test outcomes after all instances have exited.
+	OutcomesCollectionTimeout time.Duration `toml:"outcomes_collection_timeout"`
+
+	// CgroupParent is the cgroup, relative to the root of the cgroup v2
+	// hierarchy, under which the cgroups enforcing group resources are
+	// created (default: testground).
+	CgroupParent string `toml:"cgroup_parent"`
+	// EnforceResources fails runs whose groups set resources that can't be
+	// enforced, e.g. without cgroup v2, rather than ignoring their resources
+	// with a warning (default: false).
+	EnforceResources bool `toml:"enforce_resources"`
+
+	// UsageSamplingInterval is the interval at which the resource usage of
+	// instances is sampled (default: 5s).
+	UsageSamplingInterval time.Duration `toml:"usage_sampling_interval"`
+	// DisableUsageSampling disables the sampling of resource usage (default:
+	// false).
+	DisableUsageSampling bool `toml:"disable_usage_sampling"`
+}
+
+// defaultExecConfig is the default configuration. Incoming configurations
+// will be merged with this object.
+var defaultExecConfig = LocalExecutableRunnerCfg{
+	OutcomesCollectionTimeout: time.Second * 45,
+	CgroupParent:              "testground",
+	UsageSamplingInterval:     time.Second * 5,
+}
+
+// execInstance is a test plan process started by this runner.
+type execInstance struct {
+	cmd     *exec.Cmd
+	groupID string
+	idx     int
+	tag     string
+
+	// reaped and exitCode are guarded by the lock of the execRun.
+	reaped   bool
+	exitCode int
+}
+
+// execOutcomes tallies the outcomes of a local:exec run. Outcomes are
+// reported through the sync service, and instances that exit without
+// reporting one are accounted for by their exit code.
+type execOutcomes struct {
+	lk     sync.Mutex
+	result *Result
+
+	// reported counts the outcomes received per group.
+	reported map[string]int
+	// closed is set once the tally is final; later events are ignored.
+	closed bool
+}
+
+func (o *execOutcomes) add(groupID string, outcome task.Outcome) {
+	o.lk.Lock()
+	defer o.lk.Unlock()
+
+	if _, ok := o.result.Outcomes[groupID]; o.closed || !ok {
+		return
+	}
+	o.result.addOutcome(groupID, outcome)
+	o.reported[groupID]++
+}
+
+// finalize accounts for the instances that didn't report an outcome, by
+// their exit codes. exited maps groups to their number of instances that
+// exited with status 0. Instances that reported an outcome are assumed to be
+// among those, so that a silent success is never credited in place of a
+// silent crash.
+func (o *execOutcomes) finalize(exited map[string]int) {
+	o.lk.Lock()
+	defer o.lk.Unlock()
+
+	o.closed = true
+	for id, g := range o.result.Outcomes {
+		missing := g.Total - o.reported[id]
+		if unreported := exited[id] - o.reported[id]; missing > 0 && unreported > 0 {
+			if unreported > missing {
+				unreported = missing
+			}
+			g.Ok += unreported
+		}
+	}
+	o.result.updateOutcome()
+}
 
 func (r *LocalExecutableRunner) Healthcheck(ctx context.Context, engine api.Engine, ow *rpc.OutputWriter, fix bool) (*api.HealthcheckReport, error) {
 	r.lk.Lock()
@@ -64,8 +229,11 @@ func (r *LocalExecutableRunner) Healthcheck(ctx context.Context, engine api.Engi
 		healthcheck.RequiresManualFixing(),
 	)
 
+	// unreachable engines are reported by the checks below.
+	rootless, _ := docker.IsRootless(ctx, cli)
+
 	// setup infra which is common between local:docker and local:exec
-	localCommonHealthcheck(ctx, hh, cli, ow, "testground-control", r.outputsDir)
+	localCommonHealthcheck(ctx, hh, cli, ow, "testground-control", r.outputsDir, rootless)
 
 	// RunChecks will fill the report and return any errors.
 	return hh.RunChecks(ctx, fix)
@@ -75,7 +243,97 @@ func (r *LocalExecutableRunner) Close() error {
 	return nil
 }
 
-func (r *LocalExecutableRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) (*api.RunOutput, error) {
+// setupSyncClient sets up the sync client if it is not set up already.
+func (r *LocalExecutableRunner) setupSyncClient() error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if r.syncClient != nil {
+		return nil
+	}
+
+	var err error
+	r.syncClient, err = newSyncClient(context.Background(), "127.0.0.1", "5050")
+	return err
+}
+
+// collectOutcomes listens to the sync service and tallies the outcome of
+// every test instance. The returned channel is closed when all instances
+// have reported an outcome, or the context is canceled.
+func (r *LocalExecutableRunner) collectOutcomes(ctx context.Context, outcomes *execOutcomes, tpl *runtime.RunParams) (chan struct{}, error) {
+	eventsCh, err := r.syncClient.SubscribeEvents(ctx, tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	expecting := outcomes.result.countTotalInstances()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for expecting > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-eventsCh:
+				r.events.add(tpl.TestRun, e)
+				switch {
+				case e.SuccessEvent != nil:
+					outcomes.add(e.SuccessEvent.TestGroupID, task.OutcomeSuccess)
+				case e.FailureEvent != nil:
+					outcomes.add(e.FailureEvent.TestGroupID, task.OutcomeFailure)
+				case e.CrashEvent != nil:
+					outcomes.add(e.CrashEvent.TestGroupID, task.OutcomeFailure)
+				default:
+					continue
+				}
+				expecting--
+			}
+		}
+	}()
+
+	return done, nil
+}
+
+func (r *LocalExecutableRunner) Run(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) (runoutput *api.RunOutput, err error) {
+	log := ow.With("runner", "local:exec", "run_id", input.RunID)
+
+	result := newResult(input)
+	runoutput = &api.RunOutput{
+		RunID:  input.RunID,
+		Result: result,
+	}
+
+	defer func() {
+		if err != nil && result.Outcome == task.OutcomeUnknown {
+			log.Infow("run failed", "err", err)
+			result.Outcome = task.OutcomeFailure
+		}
+		if ctx.Err() == context.Canceled {
+			log.Infow("run canceled")
+			result.Outcome = task.OutcomeCanceled
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Infow("run canceled after reaching the task timeout")
+			result.Outcome = task.OutcomeFailure
+		}
+	}()
+
+	// Prepare the Runner Configuration.
+	cfg := defaultExecConfig
+	if err = mergo.Merge(&cfg, input.RunnerConfig, mergo.WithOverride); err != nil {
+		err = fmt.Errorf("error while merging configurations: %w", err)
+		return
+	}
+
+	// Outcomes are collected through the sync service if it's reachable;
+	// exit codes are relied upon otherwise.
+	syncErr := r.setupSyncClient()
+	if syncErr != nil {
+		log.Warnw("sync service unreachable; outcomes will be derived from exit codes", "err", syncErr)
+	}
+
 	r.lk.RLock()
 	defer r.lk.RUnlock()
 
@@ -90,34 +348,127 @@ func (r *LocalExecutableRunner) Run(ctx context.Context, input *api.RunInput, ow
 		TestSubnet:         &ptypes.IPNet{IPNet: *localSubnet},
 	}
 
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	run := &execRun{cancel: cancelRun}
+	r.runsLk.Lock()
+	if r.runs == nil {
+		r.runs = make(map[string]*execRun)
+	}
+	r.runs[input.RunID] = run
+	r.runsLk.Unlock()
+
+	r.events.begin(input.RunID)
+	defer func() {
+		r.events.end(input.RunID)
+
+		r.runsLk.Lock()
+		delete(r.runs, input.RunID)
+		r.runsLk.Unlock()
+	}()
+
+	outcomes := &execOutcomes{result: result, reported: make(map[string]int)}
+
+	var outcomesCh chan struct{}
+	if syncErr == nil {
+		if outcomesCh, err = r.collectOutcomes(runCtx, outcomes, &template); err != nil {
+			log.Warnw("failed to subscribe to outcomes; outcomes will be derived from exit codes", "err", err)
+			err = nil
+		}
+	}
+
+	// cgroups enforce the resources of groups, if any; they are removed
+	// once all instances have been reaped. Unless enforcement is required,
+	// resources are ignored where cgroups can't be set up.
+	var (
+		cgroups    *execCgroups
+		cgroupsErr error
+	)
+	defer func() {
+		if cgroups != nil {
+			if err := cgroups.remove(); err != nil {
+				log.Warnw("failed to remove cgroups", "err", err)
+			}
+		}
+	}()
+
+	// sample the resource usage of instances until they're reaped.
+	var usage *usageRecorder
+	usageCtx, stopUsage := context.WithCancel(runCtx)
+	defer stopUsage()
+	if !cfg.DisableUsageSampling {
+		usage = newUsageRecorder(input, cfg.UsageSamplingInterval)
+	}
+
+	outputsDir := func(groupID string, idx int) string {
+		return filepath.Join(r.outputsDir, input.TestPlan, input.RunID, groupID, strconv.Itoa(idx))
+	}
+
 	// Spawn as many instances as the input parameters require.
 	pretty := NewPrettyPrinter(ow)
-	commands := make([]*exec.Cmd, 0, input.TotalInstances)
+	instances := make([]*execInstance, 0, input.TotalInstances)
 	defer func() {
-		for _, cmd := range commands {
-			_ = cmd.Process.Kill()
-		}
-		for _, cmd := range commands {
-			_ = cmd.Wait()
+		for _, in := range instances {
+			if in.cmd.ProcessState == nil {
+				_ = in.cmd.Process.Kill()
+				_ = in.cmd.Wait()
+			}
 		}
-		_ = pretty.Wait()
 	}()
 
 	var (
 		total   int
 		tmpdirs []string
 	)
-	for _, g := range input.Groups {
-		reviewResources(g, ow)
+
+	defer func() {
+		// remove all temporary directories.
+		for _, tmpdir := range tmpdirs {
+			_ = os.RemoveAll(tmpdir)
+		}
+	}()
+
+	// instances are started one after the other, as paced by the start
+	// policies of their groups.
+	barrier := func(context.Context, string, int) error {
+		return fmt.Errorf("sync service unreachable: %w", syncErr)
+	}
+	if syncErr == nil {
+		barrier = syncBarrier(r.syncClient, &template)
+	}
+	sched, err := newStartScheduler(input, barrier)
+	if err != nil {
+		return
+	}
+	defer sched.record(result.Journal)
+
+	for _, g := range startOrder(input) {
+		var limits instanceLimits
+		if limits, err = parseResources(g.Resources); err != nil {
+			err = fmt.Errorf("failed to apply resources of group %s: %w", g.ID, err)
+			return
+		}
+		if !limits.empty() && cgroups == nil && cgroupsErr == nil {
+			if cgroups, cgroupsErr = newExecCgroups(cfg.CgroupParent, input.RunID); cgroupsErr != nil && cfg.EnforceResources {
+				err = fmt.Errorf("failed to set up cgroups to apply resources of group %s: %w", g.ID, cgroupsErr)
+				return
+			}
+		}
+		if !limits.empty() && cgroupsErr != nil {
+			log.Warnw("group has resources set, but they can't be enforced by this runner; ignoring them", "group_id", g.ID, "err", cgroupsErr)
+			limits = instanceLimits{}
+		}
 
 		for i := 0; i < g.Instances; i++ {
 			total++
 			tag := fmt.Sprintf("%s[%03d]", g.ID, i)
 
-			odir := filepath.Join(r.outputsDir, input.TestPlan, input.RunID, g.ID, strconv.Itoa(i))
+			odir := outputsDir(g.ID, i)
 			if err := os.MkdirAll(odir, 0777); err != nil {
 				err = fmt.Errorf("failed to create outputs dir %s: %w", odir, err)
 				pretty.FailStart(tag, err)
+				result.Journal.Events[tag] = err.Error()
 				continue
 			}
 
@@ -125,11 +476,16 @@ func (r *LocalExecutableRunner) Run(ctx context.Context, input *api.RunInput, ow
 			if err != nil {
 				err = fmt.Errorf("failed to create temp dir: %s: %w", tmpdir, err)
 				pretty.FailStart(tag, err)
+				result.Journal.Events[tag] = err.Error()
 				continue
 			}
 
 			tmpdirs = append(tmpdirs, tmpdir)
 
+			if err := sched.wait(runCtx, log, g.ID, i); err != nil {
+				return runoutput, err
+			}
+
 			runenv := template
 			runenv.TestGroupID = g.ID
 			runenv.TestGroupInstanceCount = g.Instances
@@ -148,33 +504,92 @@ func (r *LocalExecutableRunner) Run(ctx context.Context, input *api.RunInput, ow
 
 			ow.Infow("starting test case instance", "plan", input.TestPlan, "group", g.ID, "number", i, "total", total)
 
-			cmd := exec.CommandContext(ctx, g.ArtifactPath)
+			cmd := exec.CommandContext(runCtx, g.ArtifactPath)
 			stdout, _ := cmd.StdoutPipe()
 			stderr, _ := cmd.StderrPipe()
 			cmd.Env = env
 
 			if err := cmd.Start(); err != nil {
 				pretty.FailStart(tag, err)
+				result.Journal.Events[tag] = fmt.Sprintf("failed to start: %s", err)
 				continue
 			}
 
-			commands = append(commands, cmd)
+			sched.started(g.ID, i)
+
+			in := &execInstance{cmd: cmd, groupID: g.ID, idx: i, tag: tag}
+			instances = append(instances, in)
+			run.add(in)
+
+			// NOTE: the instance runs unconstrained until it's moved into its
+			// cgroup, right after starting.
+			if !limits.empty() {
+				if err := cgroups.add(fmt.Sprintf("%s-%d", g.ID, i), limits, cmd.Process.Pid); err != nil {
+					return runoutput, fmt.Errorf("failed to apply resources to instance %s: %w", tag, err)
+				}
+			}
+
+			if usage != nil {
+				usage.track(usageCtx, g.ID, i, procUsageProbe(cmd.Process.Pid))
+			}
 
 			// instance tag in output: << group[zero_padded_i] >>, e.g. << miner[003] >>
 			pretty.Manage(tag, stdout, stderr)
 		}
 	}
 
+	// wait for the outputs of all instances to be consumed before reaping
+	// them; failures are accounted for in the outcomes.
 	if err := <-pretty.Wait(); err != nil {
-		return nil, err
+		log.Infow("instances failed", "err", err)
+	}
+
+	exited := make(map[string]int, len(input.Groups))
+	for _, in := range instances {
+		switch err := in.cmd.Wait(); {
+		case err == nil:
+			exited[in.groupID]++
+			result.Journal.Events[in.tag] = "exited with status 0"
+		case in.cmd.ProcessState != nil:
+			result.Journal.Events[in.tag] = fmt.Sprintf("exited with status %d", in.cmd.ProcessState.ExitCode())
+		default:
+			result.Journal.Events[in.tag] = fmt.Sprintf("failed: %s", err)
+		}
+		if in.cmd.ProcessState != nil {
+			run.reaped(in, in.cmd.ProcessState.ExitCode())
+		}
 	}
 
-	// remove all temporary directories.
-	for _, tmpdir := range tmpdirs {
-		_ = os.RemoveAll(tmpdir)
+	if usage != nil {
+		stopUsage()
+		usage.wait()
+		if err := usage.writeFiles(outputsDir); err != nil {
+			log.Warnw("failed to write resource usage", "err", err)
+		}
+		usage.summarize(result)
+	}
+
+	if runCtx.Err() != nil {
+		err = runCtx.Err()
+		return
 	}
 
-	return &api.RunOutput{RunID: input.RunID}, nil
+	// give the sync service some time to deliver the outcomes of instances
+	// that exited.
+	if outcomesCh != nil {
+		select {
+		case <-outcomesCh:
+			log.Infow("all outcomes are complete")
+		case <-time.After(cfg.OutcomesCollectionTimeout):
+			log.Infow("timed out waiting for outcomes; falling back to exit codes")
+		case <-runCtx.Done():
+			err = runCtx.Err()
+			return
+		}
+	}
+
+	outcomes.finalize(exited)
+	return
 }
 
 func (r *LocalExecutableRunner) CollectOutputs(ctx context.Context, input *api.CollectionInput, ow *rpc.OutputWriter) error {
@@ -197,18 +612,61 @@ func (*LocalExecutableRunner) CompatibleBuilders() []string {
 	return []string{"exec:go"}
 }
 
-func (*LocalExecutableRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
-	// TODO: we're only stopping infrastructure/dependency containers.
-	//  We are not kill the test plan processes started by this runner.
-	//  It's possible that it's entirely unnecessary to do so, because we use
-	//  exec.CommandContext, associating the request context.
-	//  So assuming the user has cancelled the request context, those processes
-	//  should die consequently. However, it's possible that the termination
-	//  call is received while a run is inflight.
-	//  To cater for that, and also to play it safe, this method should find all
-	//  children processes of the daemon, and send them a SIGKILL.
+// InspectRun reports the state of the processes of a run in progress.
+func (r *LocalExecutableRunner) InspectRun(ctx context.Context, runID string, ow *rpc.OutputWriter) (*api.RunInspection, error) {
+	events, err := r.events.snapshot(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.runsLk.Lock()
+	run, ok := r.runs[runID]
+	r.runsLk.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("run %s is not in progress", runID)
+	}
+	return &api.RunInspection{Instances: run.inspect(), Events: events}, nil
+}
+
+// PlanCapacity compares the resources requested by the instances of a run
+// with the CPUs and memory of the host.
+func (r *LocalExecutableRunner) PlanCapacity(ctx context.Context, input *api.RunInput, ow *rpc.OutputWriter) ([]*api.CapacityReport, error) {
+	rep, err := newCapacityReport("localhost", input.Groups, api.Resources{}, float64(goruntime.NumCPU()), hostMemory())
+	if err != nil {
+		return nil, err
+	}
+	return []*api.CapacityReport{rep}, nil
+}
+
+// TerminateRun kills the processes of a run in progress. Its outputs are
+// retained.
+func (r *LocalExecutableRunner) TerminateRun(ctx context.Context, runID string, ow *rpc.OutputWriter) error {
+	r.runsLk.Lock()
+	run, ok := r.runs[runID]
+	r.runsLk.Unlock()
+
+	if !ok {
+		ow.Infow("run is not in progress; nothing to terminate", "run_id", runID)
+		return nil
+	}
+
+	ow.Infow("terminating run", "run_id", runID)
+	run.kill()
+	return nil
+}
+
+// TerminateAll kills the processes of all runs in progress, and stops the
+// infrastructure/dependency containers.
+func (r *LocalExecutableRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWriter) error {
 	ow.Info("terminate local:exec requested")
 
+	r.runsLk.Lock()
+	for _, run := range r.runs {
+		run.kill()
+	}
+	r.runsLk.Unlock()
+
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return err
@@ -233,7 +691,7 @@ func (*LocalExecutableRunner) TerminateAll(ctx context.Context, ow *rpc.OutputWr
 		containers = append(containers, container.ID)
 	}
 
-	err = docker.DeleteContainers(cli, ow, containers)
+	err = docker.DeleteContainers(ctx, cli, ow, containers)
 	if err != nil {
 		return fmt.Errorf("failed to list testground containers: %w", err)
 	}
@@ -0,0 +1,127 @@
+//go:build linux
+// +build linux
+
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is the mount point of the cgroup v2 unified hierarchy.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cpuPeriod is the period of the CPU quota of instances, and minCPUQuota the
+// smallest quota the kernel accepts, in microseconds.
+const (
+	cpuPeriod   = 100000
+	minCPUQuota = 1000
+)
+
+// execCgroups manages the cgroups of the instances of a local:exec run. Every
+// instance gets its own cgroup, under <cgroupRoot>/<parent>/<run id>.
+type execCgroups struct {
+	dir string
+}
+
+// newExecCgroups creates the cgroup of a run under parent, relative to the
+// root of the unified hierarchy, and delegates the cpu, cpuset and memory
+// controllers to it. The daemon must be allowed to manage parent, i.e. run as
+// root or be delegated parent by the init system.
+func newExecCgroups(parent, runID string) (*execCgroups, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 unified hierarchy not found at %s: %w", cgroupRoot, err)
+	}
+
+	parentDir := filepath.Join(cgroupRoot, parent)
+	dir := filepath.Join(parentDir, runID)
+	for _, d := range []string{parentDir, dir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cgroup %s: %w", d, err)
+		}
+		if err := enableControllers(d, "cpu", "cpuset", "memory"); err != nil {
+			_ = os.Remove(dir)
+			return nil, err
+		}
+	}
+	return &execCgroups{dir: dir}, nil
+}
+
+// enableControllers enables controllers for the children of the cgroup at
+// dir.
+func enableControllers(dir string, controllers ...string) error {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("failed to read controllers of cgroup %s: %w", dir, err)
+	}
+	available := make(map[string]bool)
+	for _, c := range strings.Fields(string(b)) {
+		available[c] = true
+	}
+
+	for _, c := range controllers {
+		if !available[c] {
+			return fmt.Errorf("controller %s not available in cgroup %s", c, dir)
+		}
+		if err := writeCgroupFile(dir, "cgroup.subtree_control", "+"+c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add creates the cgroup of an instance, applies limits to it, and moves the
+// process pid into it.
+func (c *execCgroups) add(name string, limits instanceLimits, pid int) error {
+	dir := filepath.Join(c.dir, name)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create cgroup %s: %w", dir, err)
+	}
+
+	if limits.NanoCPUs > 0 {
+		quota := limits.NanoCPUs * cpuPeriod / 1e9
+		if quota < minCPUQuota {
+			quota = minCPUQuota
+		}
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			return err
+		}
+	}
+	if limits.Memory > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(limits.Memory, 10)); err != nil {
+			return err
+		}
+	}
+	if limits.CPUSet != "" {
+		if err := writeCgroupFile(dir, "cpuset.cpus", limits.CPUSet); err != nil {
+			return err
+		}
+	}
+	return writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// remove deletes the cgroups of the run. Instances must have exited.
+func (c *execCgroups) remove() error {
+	children, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, ch := range children {
+		if ch.IsDir() {
+			_ = os.Remove(filepath.Join(c.dir, ch.Name()))
+		}
+	}
+	return os.Remove(c.dir)
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	path := filepath.Join(dir, file)
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %q to %s: %w", value, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package runner
+
+import (
+	"errors"
+)
+
+// execCgroups manages the cgroups of the instances of a local:exec run; only
+// supported on Linux.
+type execCgroups struct{}
+
+func newExecCgroups(parent, runID string) (*execCgroups, error) {
+	return nil, errors.New("resource limits are only supported on Linux hosts by local:exec")
+}
+
+func (c *execCgroups) add(name string, limits instanceLimits, pid int) error {
+	return errors.New("resource limits are only supported on Linux hosts by local:exec")
+}
+
+func (c *execCgroups) remove() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/rpc"
+	"github.com/testground/testground/pkg/task"
+)
+
+func TestExecOutcomesFallBackToExitCodes(t *testing.T) {
+	input := &api.RunInput{
+		Groups: []*api.RunGroup{
+			{ID: "reported", Instances: 2},
+			{ID: "silent", Instances: 3},
+		},
+	}
+
+	outcomes := &execOutcomes{result: newResult(input), reported: make(map[string]int)}
+	outcomes.add("reported", task.OutcomeSuccess)
+	outcomes.add("reported", task.OutcomeSuccess)
+	outcomes.add("unknown", task.OutcomeSuccess)
+
+	// all instances of the silent group exited successfully, without
+	// reporting an outcome.
+	outcomes.finalize(map[string]int{"reported": 2, "silent": 3})
+	require.Equal(t, task.OutcomeSuccess, outcomes.result.Outcome)
+	require.Equal(t, 2, outcomes.result.Outcomes["reported"].Ok)
+	require.Equal(t, 3, outcomes.result.Outcomes["silent"].Ok)
+
+	// events arriving after finalization are ignored.
+	outcomes.add("silent", task.OutcomeSuccess)
+	require.Equal(t, 3, outcomes.result.Outcomes["silent"].Ok)
+}
+
+func TestExecOutcomesReportedFailuresPrevail(t *testing.T) {
+	input := &api.RunInput{
+		Groups: []*api.RunGroup{{ID: "single", Instances: 3}},
+	}
+
+	outcomes := &execOutcomes{result: newResult(input), reported: make(map[string]int)}
+	outcomes.add("single", task.OutcomeFailure)
+	outcomes.add("single", task.OutcomeSuccess)
+
+	// the reporting instances exited successfully, and the third one
+	// crashed silently.
+	outcomes.finalize(map[string]int{"single": 2})
+	require.Equal(t, task.OutcomeFailure, outcomes.result.Outcome)
+	require.Equal(t, 1, outcomes.result.Outcomes["single"].Ok)
+}
+
+func TestTerminateExecRun(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	require.NoError(t, cmd.Start())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &LocalExecutableRunner{runs: map[string]*execRun{"run": {cancel: cancel}}}
+	r.runs["run"].add(&execInstance{cmd: cmd})
+
+	// unknown runs are ignored.
+	require.NoError(t, r.TerminateRun(context.Background(), "other", rpc.Discard()))
+	require.NoError(t, ctx.Err())
+
+	require.NoError(t, r.TerminateRun(context.Background(), "run", rpc.Discard()))
+	require.Error(t, ctx.Err())
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		require.Error(t, err, "process should have been killed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("process still running")
+	}
+}
+
+func TestInspectExecRun(t *testing.T) {
+	sleep := exec.Command("sleep", "60")
+	require.NoError(t, sleep.Start())
+	defer func() { _ = sleep.Process.Kill(); _ = sleep.Wait() }()
+
+	done := exec.Command("true")
+	require.NoError(t, done.Start())
+	require.NoError(t, done.Wait())
+
+	run := &execRun{}
+	run.add(&execInstance{cmd: sleep, groupID: "b", idx: 0})
+	finished := &execInstance{cmd: done, groupID: "a", idx: 1}
+	run.add(finished)
+	run.reaped(finished, 3)
+
+	st := run.inspect()
+	require.Len(t, st, 2)
+	require.Equal(t, "a", st[0].GroupID)
+	require.Equal(t, api.InstanceStateExited, st[0].State)
+	require.Equal(t, 3, st[0].ExitCode)
+	require.Equal(t, "b", st[1].GroupID)
+	require.Equal(t, api.InstanceStateRunning, st[1].State)
+}
@@ -0,0 +1,151 @@
+//go:build linux
+// +build linux
+
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the number of clock ticks per second procfs reports CPU
+// times in (USER_HZ), which is 100 on all supported architectures.
+const clockTicks = 100
+
+// procUsageProbe samples the resource usage of a process from procfs. Only
+// the process itself is measured, not its children. Network usage is not
+// measured, as instances share the network stack of the host.
+func procUsageProbe(pid int) usageProbe {
+	var (
+		prevCPU  float64
+		prevTime time.Time
+	)
+
+	return func(ctx context.Context) (*UsageSample, error) {
+		now := time.Now()
+
+		cpu, err := procCPUTime(pid)
+		if err != nil {
+			return nil, err
+		}
+
+		s := &UsageSample{Time: now}
+		if !prevTime.IsZero() {
+			s.CPU = (cpu - prevCPU) / now.Sub(prevTime).Seconds()
+		}
+		prevCPU, prevTime = cpu, now
+
+		if s.Memory, err = procRSS(pid); err != nil {
+			return nil, err
+		}
+
+		// I/O accounting may be unavailable, e.g. when the kernel is built
+		// without it.
+		io, _ := procKeyValues(fmt.Sprintf("/proc/%d/io", pid))
+		s.DiskRead = io["read_bytes"]
+		s.DiskWrite = io["write_bytes"]
+
+		return s, nil
+	}
+}
+
+// procStat returns the fields of the stat file of a process that follow its
+// command name, starting with its state.
+func procStat(pid int) ([]string, error) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	// the command name is enclosed in parentheses, and may contain spaces.
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return nil, errors.New("malformed stat file")
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 13 {
+		return nil, errors.New("malformed stat file")
+	}
+	return fields, nil
+}
+
+// procExited returns whether a process has exited, i.e. it's a zombie
+// waiting to be reaped, or is gone.
+func procExited(pid int) bool {
+	fields, err := procStat(pid)
+	return err != nil || fields[0] == "Z" || fields[0] == "X"
+}
+
+// procCPUTime returns the CPU time consumed by a process, in seconds.
+func procCPUTime(pid int) (float64, error) {
+	fields, err := procStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	if fields[0] == "Z" || fields[0] == "X" {
+		return 0, errors.New("process exited")
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(utime+stime) / clockTicks, nil
+}
+
+// procRSS returns the resident set size of a process, in bytes.
+func procRSS(pid int) (uint64, error) {
+	status, err := procKeyValues(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	// VmRSS is expressed in kB.
+	return status["VmRSS"] * 1024, nil
+}
+
+// procKeyValues parses the numeric values of a procfs file made of
+// "key: value [unit]" lines. Lines with non-numeric values are skipped.
+func procKeyValues(path string) (map[string]uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			kv[strings.TrimSpace(parts[0])] = v
+		}
+	}
+	return kv, scanner.Err()
+}
+
+// hostMemory returns the total memory of the host, or -1 if unknown.
+func hostMemory() int64 {
+	meminfo, err := procKeyValues("/proc/meminfo")
+	if err != nil || meminfo["MemTotal"] == 0 {
+		return -1
+	}
+	// MemTotal is expressed in kB.
+	return int64(meminfo["MemTotal"]) * 1024
+}
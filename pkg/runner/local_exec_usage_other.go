@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package runner
+
+import (
+	"context"
+	"errors"
+)
+
+// procUsageProbe samples the resource usage of a process; only supported on
+// Linux.
+func procUsageProbe(pid int) usageProbe {
+	return func(ctx context.Context) (*UsageSample, error) {
+		return nil, errors.New("resource usage sampling is only supported on Linux hosts by local:exec")
+	}
+}
+
+// procExited returns whether a process has exited; it's only known once the
+// process has been reaped on hosts other than Linux.
+func procExited(pid int) bool {
+	return false
+}
+
+// hostMemory returns the total memory of the host; only known on Linux.
+func hostMemory() int64 {
+	return -1
+}
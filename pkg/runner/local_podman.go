@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/testground/testground/pkg/api"
+	"github.com/testground/testground/pkg/docker"
+)
+
+var (
+	_ api.Runner          = (*LocalPodmanRunner)(nil)
+	_ api.Healthchecker   = (*LocalPodmanRunner)(nil)
+	_ api.Terminatable    = (*LocalPodmanRunner)(nil)
+	_ api.RunTerminatable = (*LocalPodmanRunner)(nil)
+	_ api.Inspectable     = (*LocalPodmanRunner)(nil)
+	_ api.Attachable      = (*LocalPodmanRunner)(nil)
+	_ api.BuildConfigurer = (*LocalPodmanRunner)(nil)
+)
+
+// LocalPodmanRunner is the local:docker runner, running containers in podman
+// instead of docker, through the docker-compatible API of podman. It's meant
+// for workstations that can't run the docker daemon, and supports rootless
+// podman. The API socket is found as described in docker.PodmanHost; with
+// systemd, it's started with `systemctl --user start podman.socket`.
+//
+// Images are built in podman by the docker builders for runs of this runner
+// (see the engine option of the builders). Infrastructure images are referred
+// to by short names, which podman must resolve from docker.io, i.e. with
+// unqualified-search-registries = ["docker.io"] in registries.conf.
+//
+// The sidecar shapes the network of instances from within their network
+// namespaces. In rootless mode, it only holds capabilities in the user
+// namespace of podman, which restricts the network features available; they
+// are listed in env-example.toml, next to the configuration of the runner.
+type LocalPodmanRunner struct {
+	LocalDockerRunner
+}
+
+// NewLocalPodmanRunner returns a runner of containers in podman.
+func NewLocalPodmanRunner() *LocalPodmanRunner {
+	return &LocalPodmanRunner{LocalDockerRunner{engine: docker.EnginePodman}}
+}
+
+// BuildConfig builds the images of runs of this runner in podman, when
+// they're built by a docker builder.
+func (*LocalPodmanRunner) BuildConfig(builder string) map[string]interface{} {
+	if !strings.HasPrefix(builder, "docker:") {
+		return nil
+	}
+	return map[string]interface{}{"engine": docker.EnginePodman}
+}
@@ -11,7 +11,14 @@ import (
 	"github.com/docker/docker/client"
 )
 
-func (c *ClusterK8sRunner) pushToDockerRegistry(ctx context.Context, ow *rpc.OutputWriter, client *client.Client, in *api.RunInput, ipo types.ImagePushOptions, uri string) error {
+// remoteImageFunc returns the ID of the image pushed with a tag to a registry,
+// i.e. the digest of its config, or "" if there is none.
+type remoteImageFunc func(ctx context.Context, tag string) (string, error)
+
+// pushToDockerRegistry pushes the images of the groups to the repository at
+// uri, tagged by their short ID. Images in the registry already, as told by
+// remote if not nil, are not pushed again.
+func (c *ClusterK8sRunner) pushToDockerRegistry(ctx context.Context, ow *rpc.OutputWriter, client *client.Client, in *api.RunInput, ipo types.ImagePushOptions, uri string, remote remoteImageFunc) error {
 	for _, g := range in.Groups {
 		tag := uri + ":" + g.ArtifactPath
 
@@ -21,6 +28,22 @@ func (c *ClusterK8sRunner) pushToDockerRegistry(ctx context.Context, ow *rpc.Out
 			continue
 		}
 
+		if remote != nil {
+			local, _, err := client.ImageInspectWithRaw(ctx, g.ArtifactPath)
+			if err != nil {
+				return err
+			}
+			switch digest, err := remote(ctx, g.ArtifactPath); {
+			case err != nil:
+				ow.Warnw("failed to look up image in registry; pushing it", "group_id", g.ID, "tag", tag, "err", err)
+			case digest == local.ID:
+				ow.Infow("image already in registry", "group_id", g.ID, "tag", tag, "digest", digest)
+				c.imagesLRU.Add(tag, struct{}{})
+				g.ArtifactPath = tag
+				continue
+			}
+		}
+
 		ow.Infow("tagging image", "group_id", g.ID, "tag", tag)
 		if err := client.ImageTag(ctx, g.ArtifactPath, tag); err != nil {
 			return err
@@ -22,6 +22,7 @@ import (
 	"github.com/testground/sdk-go/sync"
 	"github.com/testground/testground/pkg/docker"
 	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/syncclient"
 )
 
 // PublicAddr points to an IP address in the public range. It helps us discover
@@ -34,12 +35,21 @@ import (
 // ports can be exposed to the Docker host.
 var PublicAddr = net.ParseIP("1.1.1.1")
 
+// SyncServiceLabel is the label of plan containers carrying the host of the
+// sync service provisioned for their run, if it's isolated from the shared
+// one.
+const SyncServiceLabel = "testground.sync_service"
+
 type DockerReactor struct {
 	client sync.Client
 	gosync.Mutex
 	servicesRoutes []net.IP
 	manager        *docker.Manager
 	runidsCache    *lru.Cache
+
+	// runClients are the clients of the isolated sync services of runs, by
+	// host, shared by the instances of their runs.
+	runClients *runClients
 }
 
 func NewDockerReactor() (Reactor, error) {
@@ -48,17 +58,25 @@ func NewDockerReactor() (Reactor, error) {
 		return nil, err
 	}
 
-	client, err := sync.NewGenericClient(context.Background(), logging.S())
+	client, err := syncclient.New(context.Background(), logging.S(), "", "")
 	if err != nil {
 		return nil, err
 	}
 
 	cache, _ := lru.New(32)
+	runClients := newRunClients(func(host string) (sync.Client, error) {
+		client, err := syncclient.New(context.Background(), logging.S(), host, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to sync service %s: %w", host, err)
+		}
+		return client, nil
+	})
 
 	r := &DockerReactor{
 		client:      client,
 		manager:     docker,
 		runidsCache: cache,
+		runClients:  runClients,
 	}
 
 	r.ResolveServices("constructor")
@@ -126,6 +144,7 @@ func (d *DockerReactor) Close() error {
 	var err *multierror.Error
 	err = multierror.Append(err, d.manager.Close())
 	err = multierror.Append(err, d.client.Close())
+	d.runClients.close()
 	return err.ErrorOrNil()
 }
 
@@ -157,9 +176,39 @@ func (d *DockerReactor) handleContainer(ctx context.Context, container *docker.C
 
 	logging.S().Debugw("handle container", "name", info.Name, "image", info.Image)
 
+	// Failures to manage the container from here on are reported to its run.
+	// The client of the sync service of the run is released along with the
+	// instance, or once the failure is reported.
+	var (
+		client  = d.client
+		release = func() {}
+	)
+	defer func() {
+		if err != nil {
+			reportInstance(ctx, client, params, info.Config.Hostname, InstanceFailed, err)
+			release()
+		}
+	}()
+
 	// Resolve allowed services, so that we update network routes
 	d.ResolveServices(params.TestRun)
 
+	// Use the sync service of the run, if it has its own, and allow
+	// traffic to it.
+	servicesRoutes := d.servicesRoutes
+	if host := info.Config.Labels[SyncServiceLabel]; host != "" {
+		runClient, runRelease, err := d.runClients.acquire(host)
+		if err != nil {
+			return nil, err
+		}
+		client, release = runClient, runRelease
+		ip, err := net.ResolveIPAddr("ip4", host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve sync service %s: %w", host, err)
+		}
+		servicesRoutes = append(append([]net.IP(nil), servicesRoutes...), ip.IP)
+	}
+
 	// Remove the TestOutputsPath. We can't store anything from the sidecar.
 	params.TestOutputsPath = ""
 	runenv := runtime.NewRunEnv(*params)
@@ -210,7 +259,7 @@ func (d *DockerReactor) handleContainer(ctx context.Context, container *docker.C
 	}
 
 	// Retrieve control routes.
-	controlRoutes, err := getControlRoutes(d.servicesRoutes, container.ID, netlinkHandle)
+	controlRoutes, err := getControlRoutes(servicesRoutes, container.ID, netlinkHandle)
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +315,11 @@ func (d *DockerReactor) handleContainer(ctx context.Context, container *docker.C
 		}
 	}
 
-	return NewInstance(d.client, runenv, info.Config.Hostname, network)
+	if inst, err = NewInstance(client, runenv, info.Config.Hostname, network); err != nil {
+		return nil, err
+	}
+	inst.release = release
+	return inst, nil
 }
 
 func getNetworkHandlers(pid int) (netns.NsHandle, *netlink.Handle, error) {
@@ -0,0 +1,198 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	gosync "sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/testground/sdk-go/runtime"
+	"github.com/testground/sdk-go/sync"
+
+	"github.com/testground/testground/pkg/logging"
+)
+
+// InstanceState is the state of a test instance, as managed by the sidecar.
+type InstanceState string
+
+const (
+	// InstanceManaging is an instance whose network is being configured.
+	InstanceManaging InstanceState = "managing"
+	// InstanceManaged is an instance whose network is configured; it can use
+	// the network API.
+	InstanceManaged InstanceState = "managed"
+	// InstanceFailed is an instance the sidecar failed to manage.
+	InstanceFailed InstanceState = "failed"
+)
+
+// InstanceEvent is published by the sidecar on the InstanceEventsTopic of a
+// run when it manages an instance of the run, or fails to.
+type InstanceEvent struct {
+	Hostname string        `json:"hostname"`
+	GroupID  string        `json:"group_id"`
+	State    InstanceState `json:"state"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// InstanceEventsTopic is the topic of a run the sidecar publishes the
+// InstanceEvents of its instances on.
+var InstanceEventsTopic = sync.NewTopic("sidecar:instances", &InstanceEvent{})
+
+// InstanceHealth is the state of an instance, as reported by the health
+// endpoints of the sidecar.
+type InstanceHealth struct {
+	InstanceEvent
+
+	RunID string    `json:"run_id"`
+	Since time.Time `json:"since"`
+}
+
+// maxTrackedInstances bounds the instances tracked by the sidecar; failed
+// instances are kept until evicted, for their failure to be reported.
+const maxTrackedInstances = 4096
+
+// healthRegistry tracks the states of the instances managed by the sidecar,
+// by run and hostname.
+type healthRegistry struct {
+	lk        gosync.Mutex
+	ready     bool
+	instances *lru.Cache
+}
+
+var health = newHealthRegistry()
+
+func newHealthRegistry() *healthRegistry {
+	instances, _ := lru.New(maxTrackedInstances)
+	return &healthRegistry{instances: instances}
+}
+
+// setReady marks the sidecar as ready to manage instances, or not.
+func (h *healthRegistry) setReady(ready bool) {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	h.ready = ready
+}
+
+func (h *healthRegistry) isReady() bool {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	return h.ready
+}
+
+func (h *healthRegistry) set(runID string, e *InstanceEvent) {
+	h.instances.Add(runID+"/"+e.Hostname, &InstanceHealth{
+		InstanceEvent: *e,
+		RunID:         runID,
+		Since:         time.Now(),
+	})
+}
+
+// forget stops tracking an instance, unless it failed.
+func (h *healthRegistry) forget(runID, hostname string) {
+	key := runID + "/" + hostname
+	if v, ok := h.instances.Peek(key); ok && v.(*InstanceHealth).State != InstanceFailed {
+		h.instances.Remove(key)
+	}
+}
+
+func (h *healthRegistry) get(runID, hostname string) (*InstanceHealth, bool) {
+	v, ok := h.instances.Peek(runID + "/" + hostname)
+	if !ok {
+		return nil, false
+	}
+	return v.(*InstanceHealth), true
+}
+
+// list returns the instances of a run, or of all runs if runID is empty.
+func (h *healthRegistry) list(runID string) []*InstanceHealth {
+	res := make([]*InstanceHealth, 0)
+	for _, k := range h.instances.Keys() {
+		v, ok := h.instances.Peek(k)
+		if !ok {
+			continue
+		}
+		if ih := v.(*InstanceHealth); runID == "" || ih.RunID == runID {
+			res = append(res, ih)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].RunID != res[j].RunID {
+			return res[i].RunID < res[j].RunID
+		}
+		return res[i].Hostname < res[j].Hostname
+	})
+	return res
+}
+
+// reportInstance records the state of an instance and publishes it on the
+// events topic of its run, with client if not nil.
+func reportInstance(ctx context.Context, client sync.Client, params *runtime.RunParams, hostname string, state InstanceState, err error) {
+	e := &InstanceEvent{
+		Hostname: hostname,
+		GroupID:  params.TestGroupID,
+		State:    state,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	health.set(params.TestRun, e)
+
+	if client == nil {
+		return
+	}
+	if _, err := client.Publish(sync.WithRunParams(ctx, params), InstanceEventsTopic, e); err != nil {
+		logging.S().Warnw("failed to publish instance event", "run_id", params.TestRun, "instance", hostname, "state", state, "err", err)
+	}
+}
+
+// RegisterHealthHandlers registers the health endpoints of the sidecar:
+//
+//   - /health, which succeeds once the sidecar watches for instances.
+//   - /instances, which lists the instances the sidecar manages, or failed
+//     to, optionally of the run given by the run_id query parameter.
+//   - /instances/<run_id>/<hostname>, which reports the state of an
+//     instance, and succeeds once it's managed.
+func RegisterHealthHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if !health.isReady() {
+			http.Error(w, "sidecar is not ready", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("/instances", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, health.list(r.URL.Query().Get("run_id")))
+	})
+
+	mux.HandleFunc("/instances/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/instances/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.Error(w, "expected /instances/<run_id>/<hostname>", http.StatusBadRequest)
+			return
+		}
+
+		ih, ok := health.get(parts[0], parts[1])
+		switch {
+		case !ok:
+			http.Error(w, "unknown instance", http.StatusNotFound)
+		case ih.State != InstanceManaged:
+			writeHealth(w, http.StatusServiceUnavailable, ih)
+		default:
+			writeHealth(w, http.StatusOK, ih)
+		}
+	})
+}
+
+func writeHealth(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -29,6 +29,10 @@ type Instance struct {
 	Client   sync.Client
 	RunEnv   *runtime.RunEnv
 	Network  Network
+
+	// release releases the resources the instance shares with the other
+	// instances of its run, if any.
+	release func()
 }
 
 // Network is a test instance's network, as seen by the sidecar.
@@ -56,5 +60,8 @@ func NewInstance(client sync.Client, runenv *runtime.RunEnv, hostname string, ne
 func (inst *Instance) Close() error {
 	var err *multierror.Error
 	err = multierror.Append(err, inst.Network.Close())
+	if inst.release != nil {
+		inst.release()
+	}
 	return err.ErrorOrNil()
 }
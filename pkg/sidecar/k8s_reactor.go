@@ -17,6 +17,7 @@ import (
 
 	"github.com/testground/testground/pkg/docker"
 	"github.com/testground/testground/pkg/logging"
+	"github.com/testground/testground/pkg/syncclient"
 
 	"github.com/containernetworking/cni/libcni"
 	"github.com/hashicorp/go-multierror"
@@ -52,7 +53,7 @@ func NewK8sReactor() (Reactor, error) {
 		return nil, err
 	}
 
-	client, err := sync.NewGenericClient(context.Background(), logging.S())
+	client, err := syncclient.New(context.Background(), logging.S(), "", "")
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +159,13 @@ func (d *K8sReactor) manageContainer(ctx context.Context, container *docker.Cont
 		return nil, nil
 	}
 
+	// Failures to manage the container from here on are reported to its run.
+	defer func() {
+		if err != nil {
+			reportInstance(ctx, d.client, params, info.Config.Hostname, InstanceFailed, err)
+		}
+	}()
+
 	podName, ok := info.Config.Labels["io.kubernetes.pod.name"]
 	if !ok {
 		return nil, fmt.Errorf("couldn't get pod name from container labels for: %s", container.ID)
@@ -0,0 +1,73 @@
+package sidecar
+
+import (
+	gosync "sync"
+
+	"github.com/testground/sdk-go/sync"
+)
+
+// runClients holds the clients of the isolated sync services of runs, by
+// host. Clients are reference counted by the instances using them, and
+// closed once the last one is released, i.e. once the instances of their run
+// are gone.
+type runClients struct {
+	connect func(host string) (sync.Client, error)
+
+	lk      gosync.Mutex
+	clients map[string]*runClient
+}
+
+type runClient struct {
+	client sync.Client
+	refs   int
+}
+
+func newRunClients(connect func(host string) (sync.Client, error)) *runClients {
+	return &runClients{connect: connect, clients: make(map[string]*runClient)}
+}
+
+// acquire returns the client of the sync service listening on host,
+// connecting to it if necessary, and a function releasing it. The client
+// must not be used once released.
+func (r *runClients) acquire(host string) (sync.Client, func(), error) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	c, ok := r.clients[host]
+	if !ok {
+		client, err := r.connect(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		c = &runClient{client: client}
+		r.clients[host] = c
+	}
+	c.refs++
+
+	var once gosync.Once
+	release := func() { once.Do(func() { r.release(host, c) }) }
+	return c.client, release, nil
+}
+
+func (r *runClients) release(host string, c *runClient) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	// clients closed along with the reactor are gone already.
+	if c.refs--; c.refs > 0 || r.clients[host] != c {
+		return
+	}
+	delete(r.clients, host)
+	_ = c.client.Close()
+}
+
+// close closes all clients, whether they're in use or not.
+func (r *runClients) close() {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	for host, c := range r.clients {
+		_ = c.client.Close()
+		delete(r.clients, host)
+	}
+}
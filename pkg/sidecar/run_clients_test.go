@@ -0,0 +1,66 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testground/sdk-go/sync"
+)
+
+// closeCounter is a sync client that counts how many times it's closed.
+type closeCounter struct {
+	sync.Client
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestRunClientsAreReferenceCounted(t *testing.T) {
+	connected := map[string]*closeCounter{}
+	clients := newRunClients(func(host string) (sync.Client, error) {
+		if host == "unreachable" {
+			return nil, errors.New("connection refused")
+		}
+		c := &closeCounter{Client: sync.NewInmemClient()}
+		connected[host] = c
+		return c, nil
+	})
+
+	a, releaseA, err := clients.acquire("tg-sync-service-a")
+	assert.NoError(t, err)
+	again, releaseAgain, err := clients.acquire("tg-sync-service-a")
+	assert.NoError(t, err)
+	assert.Same(t, a, again)
+	_, releaseB, err := clients.acquire("tg-sync-service-b")
+	assert.NoError(t, err)
+
+	_, _, err = clients.acquire("unreachable")
+	assert.Error(t, err)
+
+	// clients stay open as long as an instance uses them; releasing twice
+	// is harmless.
+	releaseA()
+	releaseA()
+	assert.Equal(t, 0, connected["tg-sync-service-a"].closed)
+	_, err = a.SignalEntry(context.Background(), "ready")
+	assert.NoError(t, err)
+
+	releaseAgain()
+	assert.Equal(t, 1, connected["tg-sync-service-a"].closed)
+
+	// a new instance of the run connects again.
+	_, releaseA, err = clients.acquire("tg-sync-service-a")
+	assert.NoError(t, err)
+	assert.Len(t, connected, 2)
+	releaseA()
+
+	clients.close()
+	assert.Equal(t, 1, connected["tg-sync-service-b"].closed)
+	releaseB()
+	assert.Equal(t, 1, connected["tg-sync-service-b"].closed)
+}
@@ -12,10 +12,19 @@ const (
 	defaultDataNetwork = "default"
 )
 
-func handler(ctx context.Context, instance *Instance) error {
+func handler(ctx context.Context, instance *Instance) (err error) {
 	instance.S().Debugw("managing instance", "instance", instance.Hostname)
 
+	params := &instance.RunEnv.RunParams
+	reportInstance(ctx, nil, params, instance.Hostname, InstanceManaging, nil)
+
 	defer func() {
+		// failures are reported to the run, for it to fail fast.
+		if err != nil {
+			reportInstance(ctx, instance.Client, params, instance.Hostname, InstanceFailed, err)
+		}
+		health.forget(params.TestRun, instance.Hostname)
+
 		instance.S().Debugw("closing instance", "instance", instance.Hostname)
 		if err := instance.Close(); err != nil {
 			instance.S().Warnf("failed to close instance: %s", err)
@@ -23,16 +32,18 @@ func handler(ctx context.Context, instance *Instance) error {
 	}()
 
 	// Network configuration loop.
-	err := instance.Network.ConfigureNetwork(ctx, &network.Config{
+	err = instance.Network.ConfigureNetwork(ctx, &network.Config{
 		Network: defaultDataNetwork,
 		Enable:  true,
 	})
 
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to configure network: %w", err)
 	}
 
-	ctx = sync.WithRunParams(ctx, &instance.RunEnv.RunParams)
+	reportInstance(ctx, instance.Client, params, instance.Hostname, InstanceManaged, nil)
+
+	ctx = sync.WithRunParams(ctx, params)
 
 	// Wait for all the sidecars to enter the "network-initialized" state.
 	instance.S().Infof("waiting for all networks to be ready")
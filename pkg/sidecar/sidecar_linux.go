@@ -53,6 +53,9 @@ func Run(runnerName string) error {
 
 	defer reactor.Close()
 
+	health.setReady(true)
+	defer health.setReady(false)
+
 	// this call blocks.
 	err = reactor.Handle(globalctx, handler)
 	return err
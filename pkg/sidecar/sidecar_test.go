@@ -2,13 +2,19 @@ package sidecar
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/testground/sdk-go/network"
+	"github.com/testground/sdk-go/runtime"
+	"github.com/testground/sdk-go/sync"
 )
 
 func init() {
@@ -91,3 +97,72 @@ func TestNetworkConfigured(t *testing.T) {
 	assert.Len(t, r.Network.Configured, 2, "the sidecar passes on configurations to the backing network")
 	assert.True(t, reflect.DeepEqual(*r.Network.Active["default"], cfg), "the sidecar shuold not edit the config")
 }
+
+// Test that the sidecar reports the instances it manages.
+func TestInstanceManagedReported(t *testing.T) {
+	reactor, err := NewMockReactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := reactor.(*MockReactor)
+
+	events := make(chan *InstanceEvent, 1)
+	if _, err := r.Client.Subscribe(sync.WithRunParams(ctx, r.RunParams), InstanceEventsTopic, events); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		if err := r.Handle(ctx, handler); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	e := <-events
+	assert.Equal(t, InstanceManaged, e.State, "the instance should be reported as managed")
+	assert.Equal(t, r.Hostname, e.Hostname)
+	assert.Equal(t, r.RunParams.TestGroupID, e.GroupID)
+
+	ih, ok := health.get(r.RunParams.TestRun, r.Hostname)
+	assert.True(t, ok, "the instance should be tracked")
+	assert.Equal(t, InstanceManaged, ih.State)
+}
+
+func TestHealthHandlers(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterHealthHandlers(mux)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	health.setReady(false)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code, "the sidecar isn't ready before watching instances")
+	health.setReady(true)
+	defer health.setReady(false)
+	assert.Equal(t, http.StatusOK, get("/health").Code)
+
+	params := &runtime.RunParams{TestRun: "health-run", TestGroupID: "a"}
+	reportInstance(context.Background(), nil, params, "managed-host", InstanceManaged, nil)
+	reportInstance(context.Background(), nil, params, "failed-host", InstanceFailed, errors.New("no netns"))
+
+	assert.Equal(t, http.StatusOK, get("/instances/health-run/managed-host").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/instances/health-run/failed-host").Code)
+	assert.Equal(t, http.StatusNotFound, get("/instances/health-run/unknown-host").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/instances/health-run").Code)
+
+	var list []*InstanceHealth
+	assert.NoError(t, json.NewDecoder(get("/instances?run_id=health-run").Body).Decode(&list))
+	assert.Len(t, list, 2)
+	assert.Equal(t, "failed-host", list[0].Hostname)
+	assert.Equal(t, "no netns", list[0].Error)
+
+	// failed instances are kept for their failure to be reported.
+	health.forget("health-run", "managed-host")
+	health.forget("health-run", "failed-host")
+	assert.Equal(t, http.StatusNotFound, get("/instances/health-run/managed-host").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/instances/health-run/failed-host").Code)
+}
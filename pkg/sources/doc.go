@@ -0,0 +1,8 @@
+// Package sources implements content-addressed transfer of test plan sources
+// between the Testground client and daemon.
+//
+// The client describes its source trees in a manifest of file hashes. The
+// daemon replies with the hashes it does not hold in its content store, and
+// only those files are uploaded. The daemon then reconstructs the source
+// trees for the build from the store.
+package sources
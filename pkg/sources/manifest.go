@@ -0,0 +1,149 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+// HashFile returns the hex-encoded sha256 digest of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Tree is the manifest of a local source tree, retaining where the contents
+// of each file can be read from.
+type Tree struct {
+	Files []api.SourceFile
+
+	// Local maps content hashes to the local path of a file with those
+	// contents.
+	Local map[string]string
+}
+
+// ManifestDirs walks the supplied directories and returns the tree of files
+// they contain.
+//
+// If toplevel is true, each directory is retained as the first path component
+// of its files, so that /abc and /def produce abc/* and def/*. Otherwise the
+// contents of every directory are placed at the root, with later directories
+// overwriting earlier ones. This mirrors how source directories are zipped
+// for legacy uploads.
+//
+// Only regular files are included; empty directories are not preserved.
+func ManifestDirs(toplevel bool, dirs ...string) (*Tree, error) {
+	var (
+		tree  = &Tree{Local: make(map[string]string)}
+		index = make(map[string]int)
+	)
+
+	for _, dir := range dirs {
+		if fi, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if !fi.IsDir() {
+			return nil, fmt.Errorf("file %s is not a directory", dir)
+		}
+
+		prefix := ""
+		if toplevel {
+			prefix = filepath.Base(dir)
+		}
+
+		err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+
+			hash, err := HashFile(p)
+			if err != nil {
+				return err
+			}
+
+			f := api.SourceFile{
+				Path: path.Join(prefix, filepath.ToSlash(rel)),
+				Hash: hash,
+				Mode: uint32(fi.Mode().Perm()),
+			}
+
+			tree.Local[hash] = p
+
+			if i, ok := index[f.Path]; ok {
+				tree.Files[i] = f
+				return nil
+			}
+			index[f.Path] = len(tree.Files)
+			tree.Files = append(tree.Files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tree, nil
+}
+
+// Hashes returns the set of unique content hashes referenced by a manifest.
+func Hashes(m api.SourceManifest) []string {
+	var (
+		seen   = make(map[string]struct{})
+		hashes []string
+	)
+	for _, files := range m {
+		for _, f := range files {
+			if _, ok := seen[f.Hash]; ok {
+				continue
+			}
+			seen[f.Hash] = struct{}{}
+			hashes = append(hashes, f.Hash)
+		}
+	}
+	return hashes
+}
+
+// validatePath verifies that a manifest path is relative and stays within its
+// source tree.
+func validatePath(p string) error {
+	if p == "" || path.IsAbs(p) || strings.HasPrefix(p, "/") {
+		return fmt.Errorf("invalid source path: %q", p)
+	}
+	if clean := path.Clean(p); clean != p || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("invalid source path: %q", p)
+	}
+	return nil
+}
+
+// validateHash verifies that a hash is a hex-encoded sha256 digest, so that it
+// is safe to use as a file name.
+func validateHash(hash string) error {
+	b, err := hex.DecodeString(hash)
+	if err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid content hash: %q", hash)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+// Store is a content-addressed store of source files, keyed by the sha256
+// digest of their contents. It is safe for concurrent use, as blobs are
+// written to a temporary file and atomically renamed into place.
+type Store struct {
+	root string
+}
+
+// NewStore returns a Store rooted at the supplied directory, creating it if
+// necessary.
+func NewStore(root string) (*Store, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create content store directory: %w", err)
+	}
+	return &Store{root: root}, nil
+}
+
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.root, hash[:2], hash)
+}
+
+// Has returns whether the store holds the blob with the supplied hash.
+func (s *Store) Has(hash string) bool {
+	if validateHash(hash) != nil {
+		return false
+	}
+	_, err := os.Stat(s.path(hash))
+	return err == nil
+}
+
+// Missing returns the hashes referenced by the manifest that are not present
+// in the store.
+func (s *Store) Missing(m api.SourceManifest) ([]string, error) {
+	missing := make([]string, 0)
+	for _, hash := range Hashes(m) {
+		if err := validateHash(hash); err != nil {
+			return nil, err
+		}
+		if !s.Has(hash) {
+			missing = append(missing, hash)
+		}
+	}
+	return missing, nil
+}
+
+// Put stores the contents read from r under the supplied hash, verifying that
+// the contents match it.
+func (s *Store) Put(hash string, r io.Reader) error {
+	if err := validateHash(hash); err != nil {
+		return err
+	}
+
+	dst := s.path(hash)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", hash, err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != hash {
+		return fmt.Errorf("content hash mismatch; expected: %s, got: %s", hash, actual)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Materialize reconstructs the source trees described by the manifest under
+// dir, and returns the resulting UnpackedSources. Files are copied out of the
+// store rather than linked, as builders are free to modify their sources.
+func (s *Store) Materialize(m api.SourceManifest, dir string) (*api.UnpackedSources, error) {
+	unpacked := &api.UnpackedSources{BaseDir: dir}
+
+	for kind, files := range m {
+		destdir := filepath.Join(dir, kind)
+
+		switch kind {
+		case api.SourceKindPlan:
+			unpacked.PlanDir = destdir
+		case api.SourceKindSDK:
+			unpacked.SDKDir = destdir
+		case api.SourceKindExtra:
+			unpacked.ExtraDir = destdir
+		default:
+			return nil, fmt.Errorf("unknown source kind: %s", kind)
+		}
+
+		if err := os.MkdirAll(destdir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", kind, err)
+		}
+
+		for _, f := range files {
+			if err := validatePath(f.Path); err != nil {
+				return nil, err
+			}
+			if err := validateHash(f.Hash); err != nil {
+				return nil, err
+			}
+			if err := s.copyOut(f, filepath.Join(destdir, filepath.FromSlash(f.Path))); err != nil {
+				return nil, fmt.Errorf("failed to materialize %s/%s: %w", kind, f.Path, err)
+			}
+		}
+	}
+
+	return unpacked, nil
+}
+
+func (s *Store) copyOut(f api.SourceFile, dst string) error {
+	src, err := os.Open(s.path(f.Hash))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(f.Mode).Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
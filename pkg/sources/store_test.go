@@ -0,0 +1,107 @@
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/testground/testground/pkg/api"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for p, content := range files {
+		p = filepath.Join(dir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func TestManifestAndMaterialize(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"main.go":      "package main",
+		"go.mod":       "module plan",
+		"sub/dup.txt":  "same",
+		"sub/dup2.txt": "same",
+	})
+
+	tree, err := ManifestDirs(false, src)
+	require.NoError(t, err)
+	require.Len(t, tree.Files, 4)
+	// identical contents share a single blob.
+	require.Len(t, tree.Local, 3)
+
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	manifest := api.SourceManifest{api.SourceKindPlan: tree.Files}
+
+	missing, err := store.Missing(manifest)
+	require.NoError(t, err)
+	require.Len(t, missing, 3)
+
+	for _, hash := range missing {
+		f, err := os.Open(tree.Local[hash])
+		require.NoError(t, err)
+		require.NoError(t, store.Put(hash, f))
+		f.Close()
+	}
+
+	missing, err = store.Missing(manifest)
+	require.NoError(t, err)
+	require.Empty(t, missing)
+
+	dst := t.TempDir()
+	unpacked, err := store.Materialize(manifest, dst)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dst, "plan"), unpacked.PlanDir)
+	require.Empty(t, unpacked.SDKDir)
+
+	b, err := ioutil.ReadFile(filepath.Join(unpacked.PlanDir, "sub", "dup2.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "same", string(b))
+}
+
+func TestManifestToplevel(t *testing.T) {
+	base := t.TempDir()
+	writeFiles(t, base, map[string]string{
+		"abc/a.txt": "a",
+		"def/d.txt": "d",
+	})
+
+	tree, err := ManifestDirs(true, filepath.Join(base, "abc"), filepath.Join(base, "def"))
+	require.NoError(t, err)
+
+	var paths []string
+	for _, f := range tree.Files {
+		paths = append(paths, f.Path)
+	}
+	require.ElementsMatch(t, []string{"abc/a.txt", "def/d.txt"}, paths)
+}
+
+func TestPutVerifiesHash(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	hash := strings.Repeat("0", 64)
+	require.Error(t, store.Put(hash, strings.NewReader("not matching")))
+	require.False(t, store.Has(hash))
+
+	require.Error(t, store.Put("../../etc/passwd", strings.NewReader("")))
+}
+
+func TestMaterializeRejectsEscapingPaths(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, p := range []string{"../evil", "/abs", "a/../../b", ""} {
+		manifest := api.SourceManifest{api.SourceKindPlan: {{Path: p, Hash: strings.Repeat("0", 64)}}}
+		_, err := store.Materialize(manifest, t.TempDir())
+		require.Error(t, err, p)
+	}
+}
@@ -0,0 +1,58 @@
+// Package syncclient creates clients of sync services.
+//
+// The sdk reads the address of the sync service from the SYNC_SERVICE_HOST
+// and SYNC_SERVICE_PORT environment variables. Processes that connect to
+// several sync services create their clients through this package, which
+// sets those variables for the duration of the connection only, and
+// serializes connections so that they don't observe each other's address.
+package syncclient
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	ss "github.com/testground/sdk-go/sync"
+	"go.uber.org/zap"
+)
+
+var lk sync.Mutex
+
+// New returns a generic sync client connected to the sync service listening
+// on host:port. An empty host or port is read from the environment, as the
+// sdk does.
+func New(ctx context.Context, log *zap.SugaredLogger, host, port string) (*ss.DefaultClient, error) {
+	lk.Lock()
+	defer lk.Unlock()
+
+	for _, v := range []struct{ key, value string }{
+		{ss.EnvServiceHost, host},
+		{ss.EnvServicePort, port},
+	} {
+		if v.value == "" {
+			continue
+		}
+		restore, err := setenv(v.key, v.value)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+	}
+	return ss.NewGenericClient(ctx, log)
+}
+
+// setenv sets an environment variable, and returns a function restoring its
+// previous value.
+func setenv(key, value string) (func(), error) {
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		return nil, err
+	}
+	return func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	}, nil
+}
@@ -0,0 +1,53 @@
+package syncclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ss "github.com/testground/sdk-go/sync"
+	"go.uber.org/zap"
+)
+
+func TestNewRestoresEnvironment(t *testing.T) {
+	hosts := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts <- r.Host
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	prevHost, hadHost := os.LookupEnv(ss.EnvServiceHost)
+	prevPort, hadPort := os.LookupEnv(ss.EnvServicePort)
+	defer func() {
+		if hadHost {
+			os.Setenv(ss.EnvServiceHost, prevHost)
+		} else {
+			os.Unsetenv(ss.EnvServiceHost)
+		}
+		if hadPort {
+			os.Setenv(ss.EnvServicePort, prevPort)
+		} else {
+			os.Unsetenv(ss.EnvServicePort)
+		}
+	}()
+	os.Setenv(ss.EnvServiceHost, "testground-sync-service")
+	os.Unsetenv(ss.EnvServicePort)
+
+	// the server refuses the websocket upgrade, after the client has dialed
+	// the address it was given.
+	_, err = New(context.Background(), zap.NewNop().Sugar(), host, port)
+	require.Error(t, err)
+	require.Equal(t, srv.Listener.Addr().String(), <-hosts)
+
+	require.Equal(t, "testground-sync-service", os.Getenv(ss.EnvServiceHost))
+	_, ok := os.LookupEnv(ss.EnvServicePort)
+	require.False(t, ok)
+}